@@ -1,7 +1,8 @@
 // Code generated by github.com/actgardner/gogen-avro. DO NOT EDIT.
 /*
- * SOURCE:
+ * SOURCES:
  *     application_version_available.avsc
+ *     application_version_deployed.avsc
  */
 
 package avro
@@ -11,8 +12,24 @@ import (
 )
 
 type ApplicationVersionAvailable struct {
-	App     string
-	Version string
+	App            string
+	Version        string
+	Parsed         bool
+	Major          int32
+	Minor          int32
+	Patch          int32
+	Prerelease     string
+	Build          string
+	Stable         bool
+	PublishedAt    int64
+	Url            string
+	AppVersion     string
+	Channel        string
+	Ecosystem      string
+	Digest         string
+	MediaType      string
+	Platforms      []string
+	PreviousDigest string
 }
 
 func DeserializeApplicationVersionAvailable(r io.Reader) (*ApplicationVersionAvailable, error) {
@@ -21,12 +38,28 @@ func DeserializeApplicationVersionAvailable(r io.Reader) (*ApplicationVersionAva
 
 func NewApplicationVersionAvailable() *ApplicationVersionAvailable {
 	v := &ApplicationVersionAvailable{}
+	v.Parsed = false
+	v.Major = 0
+	v.Minor = 0
+	v.Patch = 0
+	v.Prerelease = ""
+	v.Build = ""
+	v.Stable = false
+	v.PublishedAt = 0
+	v.Url = ""
+	v.AppVersion = ""
+	v.Channel = ""
+	v.Ecosystem = ""
+	v.Digest = ""
+	v.MediaType = ""
+	v.Platforms = make([]string, 0)
+	v.PreviousDigest = ""
 
 	return v
 }
 
 func (r *ApplicationVersionAvailable) Schema() string {
-	return "{\"fields\":[{\"name\":\"App\",\"type\":\"string\"},{\"name\":\"Version\",\"type\":\"string\"}],\"name\":\"ApplicationVersionAvailable\",\"type\":\"record\"}"
+	return "{\"fields\":[{\"name\":\"App\",\"type\":\"string\"},{\"name\":\"Version\",\"type\":\"string\"},{\"default\":false,\"name\":\"Parsed\",\"type\":\"boolean\"},{\"default\":0,\"name\":\"Major\",\"type\":\"int\"},{\"default\":0,\"name\":\"Minor\",\"type\":\"int\"},{\"default\":0,\"name\":\"Patch\",\"type\":\"int\"},{\"default\":\"\",\"name\":\"Prerelease\",\"type\":\"string\"},{\"default\":\"\",\"name\":\"Build\",\"type\":\"string\"},{\"default\":false,\"name\":\"Stable\",\"type\":\"boolean\"},{\"default\":0,\"name\":\"PublishedAt\",\"type\":\"long\"},{\"default\":\"\",\"name\":\"Url\",\"type\":\"string\"},{\"default\":\"\",\"name\":\"AppVersion\",\"type\":\"string\"},{\"default\":\"\",\"name\":\"Channel\",\"type\":\"string\"},{\"default\":\"\",\"name\":\"Ecosystem\",\"type\":\"string\"},{\"default\":\"\",\"name\":\"Digest\",\"type\":\"string\"},{\"default\":\"\",\"name\":\"MediaType\",\"type\":\"string\"},{\"default\":[],\"name\":\"Platforms\",\"type\":{\"items\":\"string\",\"type\":\"array\"}},{\"default\":\"\",\"name\":\"PreviousDigest\",\"type\":\"string\"}],\"name\":\"ApplicationVersionAvailable\",\"type\":\"record\"}"
 }
 
 func (r *ApplicationVersionAvailable) Serialize(w io.Writer) error {
@@ -1,7 +1,8 @@
 // Code generated by github.com/actgardner/gogen-avro. DO NOT EDIT.
 /*
- * SOURCE:
+ * SOURCES:
  *     application_version_available.avsc
+ *     application_version_deployed.avsc
  */
 
 package avro
@@ -78,10 +79,181 @@ func readApplicationVersionAvailable(r io.Reader) (*ApplicationVersionAvailable,
 	if err != nil {
 		return nil, err
 	}
+	str.Parsed, err = readBool(r)
+	if err != nil {
+		return nil, err
+	}
+	str.Major, err = readInt(r)
+	if err != nil {
+		return nil, err
+	}
+	str.Minor, err = readInt(r)
+	if err != nil {
+		return nil, err
+	}
+	str.Patch, err = readInt(r)
+	if err != nil {
+		return nil, err
+	}
+	str.Prerelease, err = readString(r)
+	if err != nil {
+		return nil, err
+	}
+	str.Build, err = readString(r)
+	if err != nil {
+		return nil, err
+	}
+	str.Stable, err = readBool(r)
+	if err != nil {
+		return nil, err
+	}
+	str.PublishedAt, err = readLong(r)
+	if err != nil {
+		return nil, err
+	}
+	str.Url, err = readString(r)
+	if err != nil {
+		return nil, err
+	}
+	str.AppVersion, err = readString(r)
+	if err != nil {
+		return nil, err
+	}
+	str.Channel, err = readString(r)
+	if err != nil {
+		return nil, err
+	}
+	str.Ecosystem, err = readString(r)
+	if err != nil {
+		return nil, err
+	}
+	str.Digest, err = readString(r)
+	if err != nil {
+		return nil, err
+	}
+	str.MediaType, err = readString(r)
+	if err != nil {
+		return nil, err
+	}
+	str.Platforms, err = readArrayString(r)
+	if err != nil {
+		return nil, err
+	}
+	str.PreviousDigest, err = readString(r)
+	if err != nil {
+		return nil, err
+	}
 
 	return str, nil
 }
 
+func readApplicationVersionDeployed(r io.Reader) (*ApplicationVersionDeployed, error) {
+	var str = &ApplicationVersionDeployed{}
+	var err error
+	str.Cluster, err = readString(r)
+	if err != nil {
+		return nil, err
+	}
+	str.Kind, err = readString(r)
+	if err != nil {
+		return nil, err
+	}
+	str.Namespace, err = readString(r)
+	if err != nil {
+		return nil, err
+	}
+	str.Workload, err = readString(r)
+	if err != nil {
+		return nil, err
+	}
+	str.Container, err = readString(r)
+	if err != nil {
+		return nil, err
+	}
+	str.Image, err = readString(r)
+	if err != nil {
+		return nil, err
+	}
+	str.Repository, err = readString(r)
+	if err != nil {
+		return nil, err
+	}
+	str.Tag, err = readString(r)
+	if err != nil {
+		return nil, err
+	}
+	str.Digest, err = readString(r)
+	if err != nil {
+		return nil, err
+	}
+	str.KubeletVersion, err = readString(r)
+	if err != nil {
+		return nil, err
+	}
+	str.App, err = readString(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return str, nil
+}
+
+func readArrayString(r io.Reader) ([]string, error) {
+	var err error
+	var blkSize int64
+	var arr = make([]string, 0)
+	for {
+		blkSize, err = readLong(r)
+		if err != nil {
+			return nil, err
+		}
+		if blkSize == 0 {
+			break
+		}
+		if blkSize < 0 {
+			blkSize = -blkSize
+			_, err = readLong(r)
+			if err != nil {
+				return nil, err
+			}
+		}
+		for i := int64(0); i < blkSize; i++ {
+			elem, err := readString(r)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, elem)
+		}
+	}
+	return arr, nil
+}
+
+func readBool(r io.Reader) (bool, error) {
+	b := make([]byte, 1)
+	_, err := io.ReadFull(r, b)
+	if err != nil {
+		return false, err
+	}
+	return b[0] == 1, nil
+}
+
+func readInt(r io.Reader) (int32, error) {
+	var v int
+	buf := make([]byte, 1)
+	for shift := uint(0); ; shift += 7 {
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return 0, err
+		}
+		b := buf[0]
+		v |= int(b&127) << shift
+		if b&128 == 0 {
+			break
+		}
+	}
+	datum := (int32(v>>1) ^ -int32(v&1))
+	return datum, nil
+}
+
 func readLong(r io.Reader) (int64, error) {
 	var v uint64
 	buf := make([]byte, 1)
@@ -133,10 +305,165 @@ func writeApplicationVersionAvailable(r *ApplicationVersionAvailable, w io.Write
 	if err != nil {
 		return err
 	}
+	err = writeBool(r.Parsed, w)
+	if err != nil {
+		return err
+	}
+	err = writeInt(r.Major, w)
+	if err != nil {
+		return err
+	}
+	err = writeInt(r.Minor, w)
+	if err != nil {
+		return err
+	}
+	err = writeInt(r.Patch, w)
+	if err != nil {
+		return err
+	}
+	err = writeString(r.Prerelease, w)
+	if err != nil {
+		return err
+	}
+	err = writeString(r.Build, w)
+	if err != nil {
+		return err
+	}
+	err = writeBool(r.Stable, w)
+	if err != nil {
+		return err
+	}
+	err = writeLong(r.PublishedAt, w)
+	if err != nil {
+		return err
+	}
+	err = writeString(r.Url, w)
+	if err != nil {
+		return err
+	}
+	err = writeString(r.AppVersion, w)
+	if err != nil {
+		return err
+	}
+	err = writeString(r.Channel, w)
+	if err != nil {
+		return err
+	}
+	err = writeString(r.Ecosystem, w)
+	if err != nil {
+		return err
+	}
+	err = writeString(r.Digest, w)
+	if err != nil {
+		return err
+	}
+	err = writeString(r.MediaType, w)
+	if err != nil {
+		return err
+	}
+	err = writeArrayString(r.Platforms, w)
+	if err != nil {
+		return err
+	}
+	err = writeString(r.PreviousDigest, w)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func writeApplicationVersionDeployed(r *ApplicationVersionDeployed, w io.Writer) error {
+	var err error
+	err = writeString(r.Cluster, w)
+	if err != nil {
+		return err
+	}
+	err = writeString(r.Kind, w)
+	if err != nil {
+		return err
+	}
+	err = writeString(r.Namespace, w)
+	if err != nil {
+		return err
+	}
+	err = writeString(r.Workload, w)
+	if err != nil {
+		return err
+	}
+	err = writeString(r.Container, w)
+	if err != nil {
+		return err
+	}
+	err = writeString(r.Image, w)
+	if err != nil {
+		return err
+	}
+	err = writeString(r.Repository, w)
+	if err != nil {
+		return err
+	}
+	err = writeString(r.Tag, w)
+	if err != nil {
+		return err
+	}
+	err = writeString(r.Digest, w)
+	if err != nil {
+		return err
+	}
+	err = writeString(r.KubeletVersion, w)
+	if err != nil {
+		return err
+	}
+	err = writeString(r.App, w)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func writeArrayString(r []string, w io.Writer) error {
+	err := writeLong(int64(len(r)), w)
+	if err != nil || len(r) == 0 {
+		return err
+	}
+	for _, e := range r {
+		err = writeString(e, w)
+		if err != nil {
+			return err
+		}
+	}
+	return writeLong(0, w)
+}
 
+func writeBool(r bool, w io.Writer) error {
+	var b byte
+	if r {
+		b = byte(1)
+	}
+
+	var err error
+	if bw, ok := w.(ByteWriter); ok {
+		err = bw.WriteByte(b)
+	} else {
+		bb := make([]byte, 1)
+		bb[0] = b
+		_, err = w.Write(bb)
+	}
+	if err != nil {
+		return err
+	}
 	return nil
 }
 
+func writeInt(r int32, w io.Writer) error {
+	downShift := uint32(31)
+	encoded := uint64((uint32(r) << 1) ^ uint32(r>>downShift))
+	const maxByteSize = 5
+	return encodeInt(w, maxByteSize, encoded)
+}
+
 func writeLong(r int64, w io.Writer) error {
 	downShift := uint64(63)
 	encoded := uint64((r << 1) ^ (r >> downShift))
@@ -14,6 +14,8 @@ require (
 	github.com/onsi/gomega v1.4.3
 	github.com/pkg/errors v0.8.1
 	github.com/prometheus/client_golang v0.9.2
-	github.com/robfig/cron v0.0.0-20180505203441-b41be1df6967 // indirect
+	github.com/robfig/cron v0.0.0-20180505203441-b41be1df6967
 	github.com/seibert-media/go-kafka v0.0.0-20190226200402-b82e33ffb705
+	go.etcd.io/bbolt v1.3.6
+	gopkg.in/yaml.v2 v2.2.2
 )
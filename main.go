@@ -18,7 +18,6 @@ import (
 
 	"github.com/Shopify/sarama"
 	"github.com/bborbe/argument"
-	"github.com/bborbe/cron"
 	flag "github.com/bborbe/flagenv"
 	"github.com/bborbe/kafka-k8s-version-collector/version"
 	"github.com/bborbe/run"
@@ -26,6 +25,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/seibert-media/go-kafka/schema"
+	bolt "go.etcd.io/bbolt"
 )
 
 func main() {
@@ -64,29 +64,60 @@ func contextWithSig(ctx context.Context) context.Context {
 }
 
 type application struct {
-	Wait              time.Duration `required:"true" arg:"wait" env:"WAIT" default:"1h" usage:"time to wait before next version collect"`
-	Port              int           `required:"true" arg:"port" env:"PORT" default:"9003" usage:"port to listen"`
-	KafkaBrokers      string        `required:"true" arg:"kafka-brokers" env:"KAFKA_BROKERS" usage:"kafka brokers"`
-	KafkaTopic        string        `required:"true" arg:"kafka-topic" env:"KAFKA_TOPIC" usage:"kafka topic"`
-	SchemaRegistryUrl string        `required:"true" arg:"kafka-schema-registry-url" env:"KAFKA_SCHEMA_REGISTRY_URL" usage:"kafka schema registry url"`
+	Config              string        `required:"false" arg:"config" env:"CONFIG" usage:"yaml file describing sources and sink, replaces images, filter and registry arguments"`
+	Wait                time.Duration `required:"true" arg:"wait" env:"WAIT" default:"1h" usage:"time to wait before next version collect"`
+	Images              string        `required:"true" arg:"images" env:"IMAGES" default:"Kubernetes=https://gcr.io/google_containers/hyperkube-amd64" usage:"comma separated list of images to collect tags from (app=registry/repository)"`
+	Port                int           `required:"true" arg:"port" env:"PORT" default:"9003" usage:"port to listen"`
+	PageSize            int           `required:"false" arg:"page-size" env:"PAGE_SIZE" default:"100" usage:"number of tags requested per page"`
+	MaxPages            int           `required:"false" arg:"max-pages" env:"MAX_PAGES" default:"100" usage:"max number of pages fetched per image"`
+	Include             string        `required:"false" arg:"include" env:"INCLUDE" usage:"regular expression versions must match"`
+	Exclude             string        `required:"false" arg:"exclude" env:"EXCLUDE" usage:"regular expression versions must not match"`
+	Constraint          string        `required:"false" arg:"constraint" env:"CONSTRAINT" usage:"semantic version constraint versions must match (example: >=1.10 <2.0)"`
+	StableOnly          bool          `required:"false" arg:"stable-only" env:"STABLE_ONLY" default:"false" usage:"publish only stable semantic versions"`
+	KeepNewest          int           `required:"false" arg:"keep-newest" env:"KEEP_NEWEST" default:"0" usage:"publish only the newest n semantic versions per app (0 = all)"`
+	ResolveDigests      bool          `required:"false" arg:"resolve-digests" env:"RESOLVE_DIGESTS" default:"false" usage:"publish tags with the digest of their manifest and publish retagged tags again"`
+	RegistryUsername    string        `required:"false" arg:"registry-username" env:"REGISTRY_USERNAME" usage:"username used to get tokens from the registry"`
+	RegistryPassword    string        `required:"false" arg:"registry-password" env:"REGISTRY_PASSWORD" usage:"password used to get tokens from the registry" display:"length"`
+	StateFile           string        `required:"true" arg:"state-file" env:"STATE_FILE" default:"state.db" usage:"file to store already published versions"`
+	Force               bool          `required:"false" arg:"force" env:"FORCE" default:"false" usage:"publish all versions, even if already published"`
+	KafkaBrokers        string        `required:"false" arg:"kafka-brokers" env:"KAFKA_BROKERS" usage:"kafka brokers, overrides sink of config"`
+	KafkaTopic          string        `required:"false" arg:"kafka-topic" env:"KAFKA_TOPIC" usage:"kafka topic, overrides sink of config"`
+	KafkaDeployedTopic  string        `required:"false" arg:"kafka-deployed-topic" env:"KAFKA_DEPLOYED_TOPIC" usage:"kafka topic of deployed versions, overrides sink of config"`
+	SchemaRegistryUrl   string        `required:"false" arg:"kafka-schema-registry-url" env:"KAFKA_SCHEMA_REGISTRY_URL" usage:"kafka schema registry url, overrides sink of config"`
+	RetryMaxAttempts    int           `required:"false" arg:"retry-max-attempts" env:"RETRY_MAX_ATTEMPTS" default:"5" usage:"max number of attempts of registry, schema registry and kafka calls (1 = no retry)"`
+	RetryInitialDelay   time.Duration `required:"false" arg:"retry-initial-delay" env:"RETRY_INITIAL_DELAY" default:"1s" usage:"wait before the first retry, doubled for each further retry"`
+	RetryMaxDelay       time.Duration `required:"false" arg:"retry-max-delay" env:"RETRY_MAX_DELAY" default:"30s" usage:"max wait between two attempts"`
+	RetryJitter         float64       `required:"false" arg:"retry-jitter" env:"RETRY_JITTER" default:"0.2" usage:"randomize the wait between attempts by this fraction"`
+	ReadinessSyncWindow time.Duration `required:"false" arg:"readiness-sync-window" env:"READINESS_SYNC_WINDOW" default:"0" usage:"not ready if no sync succeeded within this window (0 = three times the longest source interval, negative = disabled)"`
+	HistorySize         int           `required:"false" arg:"history-size" env:"HISTORY_SIZE" default:"100" usage:"number of recent sync runs shown on the status page"`
+	RateLimitMaxWait    time.Duration `required:"false" arg:"rate-limit-max-wait" env:"RATE_LIMIT_MAX_WAIT" default:"1m" usage:"max wait for a registry with exhausted rate limit before the source fails"`
 }
 
 func (a *application) Run(ctx context.Context) error {
-	return run.CancelOnFirstFinish(
-		ctx,
-		a.runCron,
-		a.runHttpServer,
-	)
-}
+	config, err := a.readConfig()
+	if err != nil {
+		return errors.Wrap(err, "read config failed")
+	}
 
-func (a *application) runCron(ctx context.Context) error {
-	config := sarama.NewConfig()
-	config.Version = sarama.V2_0_0_0
-	config.Producer.RequiredAcks = sarama.WaitForAll
-	config.Producer.Retry.Max = 10
-	config.Producer.Return.Successes = true
+	retryPolicy := a.retryPolicy()
+	if err := retryPolicy.Validate(); err != nil {
+		return errors.Wrap(err, "validate retry policy failed")
+	}
 
-	client, err := sarama.NewClient(strings.Split(a.KafkaBrokers, ","), config)
+	db, err := bolt.Open(a.StateFile, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return errors.Wrapf(err, "open state file %s failed", a.StateFile)
+	}
+	defer db.Close()
+
+	saramaConfig := sarama.NewConfig()
+	saramaConfig.Version = sarama.V2_0_0_0
+	saramaConfig.Producer.RequiredAcks = sarama.WaitForAll
+	// retried by NewRetrySyncProducer
+	saramaConfig.Producer.Retry.Max = 0
+	saramaConfig.Producer.Return.Successes = true
+
+	client, err := sarama.NewClient(strings.Split(config.Sink.KafkaBrokers, ","), saramaConfig)
 	if err != nil {
 		return errors.Wrap(err, "create client failed")
 	}
@@ -98,43 +129,228 @@ func (a *application) runCron(ctx context.Context) error {
 	}
 	defer producer.Close()
 
-	httpClient := http.DefaultClient
-	syncer := version.NewSyncer(
-		version.NewFetcher(httpClient, "https://gcr.io"),
-		version.NewSender(
-			producer,
-			schema.NewRegistry(
-				httpClient,
-				a.SchemaRegistryUrl,
-			),
-			a.KafkaTopic,
-		),
+	store := version.NewStore(db)
+	schemaRegistry := schema.NewRegistry(
+		&http.Client{Transport: version.NewRetryRoundTripper(http.DefaultTransport, retryPolicy)},
+		config.Sink.SchemaRegistryUrl,
+	)
+	sender := version.NewSender(
+		version.NewRetrySyncProducer(producer, retryPolicy),
+		schemaRegistry,
+		config.Sink.KafkaTopic,
+		store,
+		a.Force,
+	)
+	deploymentSender := version.NewDeploymentSender(
+		version.NewRetrySyncProducer(producer, retryPolicy),
+		schemaRegistry,
+		config.Sink.DeployedTopic,
+		a.Force,
 	)
 
-	cronJob := cron.NewWaitCron(
-		a.Wait,
-		syncer.Sync,
+	// each retry passes the rate limit, a retry of a host with exhausted limit waits or fails without request
+	transport := version.NewRetryRoundTripper(
+		version.NewRateLimitRoundTripper(http.DefaultTransport, a.RateLimitMaxWait),
+		retryPolicy,
+	)
+	sources, err := a.sources(config, transport, store, deploymentSender)
+	if err != nil {
+		return errors.Wrap(err, "create sources failed")
+	}
+	lastSuccessfulSync := version.NewLastSuccessfulSync(time.Now())
+	history := version.NewHistory(a.HistorySize)
+	runner := history.Runner(lastSuccessfulSync.Runner(version.NewRunner(sender, sources...)))
+
+	checks := []version.Check{
+		version.NewKafkaCheck(client),
+		version.NewSchemaRegistryCheck(http.DefaultClient, config.Sink.SchemaRegistryUrl),
+	}
+	readinessSyncWindow := a.ReadinessSyncWindow
+	if readinessSyncWindow == 0 {
+		readinessSyncWindow = version.ReadinessSyncWindow(config.Sources, time.Now())
+	}
+	if readinessSyncWindow > 0 {
+		glog.V(1).Infof("not ready if no sync succeeded within %v", readinessSyncWindow)
+		checks = append(checks, lastSuccessfulSync.Check(readinessSyncWindow))
+	}
+
+	webhooks, err := version.NewWebhookHandlers(ctx, config, sender)
+	if err != nil {
+		return errors.Wrap(err, "create webhooks failed")
+	}
+
+	router := http.NewServeMux()
+	router.Handle("/healthz", version.NewHealthzHandler())
+	router.Handle("/readiness", version.NewReadinessHandler(5*time.Second, checks...))
+	router.Handle("/metrics", promhttp.Handler())
+	router.Handle("/sync", version.NewSyncHandler(ctx, runner))
+	router.Handle("/status", version.NewStatusHandler(history, config.Sources))
+	router.Handle("/apps", version.NewAppsHandler(store))
+	router.Handle("/apps/", version.NewAppsHandler(store))
+	for name, handler := range webhooks {
+		router.Handle(version.WebhookPath(name), handler)
+	}
+	router.Handle("/", version.NewStatusPageHandler(history, config.Sources))
+
+	return run.CancelOnFirstFinish(
+		ctx,
+		func(ctx context.Context) error {
+			return run.CancelOnFirstError(ctx, a.crons(config, runner)...)
+		},
+		a.runHttpServer(router),
 	)
-	return cronJob.Run(ctx)
 }
 
-func (a *application) runHttpServer(ctx context.Context) error {
-	server := &http.Server{
-		Addr:    fmt.Sprintf(":%d", a.Port),
-		Handler: promhttp.Handler(),
+// sources returns fetcher and filter of each configured source.
+// Cluster sources publish deployed versions with the deployment sender.
+// Digests are resolved after the filter, only for tags new or published with digest according to the store.
+func (a *application) sources(config *version.Config, transport http.RoundTripper, store version.Store, deploymentSender version.DeploymentSender) ([]version.Source, error) {
+	var result []version.Source
+	for _, source := range config.Sources {
+		var fetcher version.Fetcher
+		var err error
+		if source.Type == version.SourceTypeCluster {
+			fetcher, err = version.NewClusterSourceFetcher(config, source, deploymentSender)
+		} else {
+			fetcher, err = version.NewSourceFetcher(transport, config, source)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "create fetcher for source %s failed", source.Name)
+		}
+		filter, err := version.NewSourceFilter(transport, config, source, store)
+		if err != nil {
+			return nil, errors.Wrapf(err, "create filter for source %s failed", source.Name)
+		}
+		result = append(result, version.Source{
+			Name:    source.Name,
+			Fetcher: fetcher,
+			Filter:  filter,
+		})
 	}
-	go func() {
-		select {
-		case <-ctx.Done():
-			if err := server.Shutdown(ctx); err != nil {
-				glog.Warningf("shutdown failed: %v", err)
+	return result, nil
+}
+
+// crons returns a cron per source, so a slow source does not delay others with the same schedule.
+func (a *application) crons(config *version.Config, runner version.Runner) []run.Func {
+	var result []run.Func
+	for _, source := range config.Sources {
+		result = append(result, version.NewCron(
+			source.Schedule,
+			version.NewRunnerSyncer(runner, version.SyncTriggerSchedule, source.Name).Sync,
+		).Run)
+	}
+	return result
+}
+
+// readConfig returns the config file or builds the config from the arguments if no file is given.
+func (a *application) readConfig() (*version.Config, error) {
+	var config *version.Config
+	if a.Config != "" {
+		var err error
+		config, err = version.ReadConfig(a.Config)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		config, err = a.configFromArguments()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if a.KafkaBrokers != "" {
+		config.Sink.KafkaBrokers = a.KafkaBrokers
+	}
+	if a.KafkaTopic != "" {
+		config.Sink.KafkaTopic = a.KafkaTopic
+	}
+	if a.KafkaDeployedTopic != "" {
+		config.Sink.DeployedTopic = a.KafkaDeployedTopic
+	}
+	if a.SchemaRegistryUrl != "" {
+		config.Sink.SchemaRegistryUrl = a.SchemaRegistryUrl
+	}
+	config.ApplyDefaults(a.Wait, a.PageSize, a.MaxPages)
+	if err := config.Validate(); err != nil {
+		return nil, errors.Wrap(err, "validate config failed")
+	}
+	return config, nil
+}
+
+func (a *application) configFromArguments() (*version.Config, error) {
+	images, err := version.ParseImages(a.Images)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse images failed")
+	}
+	config := &version.Config{}
+	var credentials string
+	if a.RegistryUsername != "" {
+		credentials = "registry"
+		config.Credentials = map[string]version.CredentialsConfig{
+			credentials: {
+				Username: a.RegistryUsername,
+				Password: a.RegistryPassword,
+			},
+		}
+	}
+	for _, image := range images {
+		config.Sources = append(config.Sources, version.SourceConfig{
+			Name:           image.App,
+			Type:           version.SourceTypeRegistry,
+			Registry:       image.Registry,
+			Repository:     image.Repository,
+			App:            image.App,
+			Credentials:    credentials,
+			ResolveDigests: a.ResolveDigests,
+			Filter:         a.filterConfig(),
+		})
+	}
+	return config, nil
+}
+
+func (a *application) retryPolicy() version.RetryPolicy {
+	return version.RetryPolicy{
+		MaxAttempts:  a.RetryMaxAttempts,
+		InitialDelay: a.RetryInitialDelay,
+		MaxDelay:     a.RetryMaxDelay,
+		Jitter:       a.RetryJitter,
+	}
+}
+
+func (a *application) filterConfig() version.FilterConfig {
+	config := version.FilterConfig{
+		Constraint: a.Constraint,
+		StableOnly: a.StableOnly,
+		KeepNewest: a.KeepNewest,
+	}
+	if a.Include != "" {
+		config.Include = []string{a.Include}
+	}
+	if a.Exclude != "" {
+		config.Exclude = []string{a.Exclude}
+	}
+	return config
+}
+
+func (a *application) runHttpServer(handler http.Handler) run.Func {
+	return func(ctx context.Context) error {
+		server := &http.Server{
+			Addr:    fmt.Sprintf(":%d", a.Port),
+			Handler: handler,
+		}
+		go func() {
+			select {
+			case <-ctx.Done():
+				if err := server.Shutdown(ctx); err != nil {
+					glog.Warningf("shutdown failed: %v", err)
+				}
 			}
+		}()
+		err := server.ListenAndServe()
+		if err == http.ErrServerClosed {
+			glog.V(0).Info(err)
+			return nil
 		}
-	}()
-	err := server.ListenAndServe()
-	if err == http.ErrServerClosed {
-		glog.V(0).Info(err)
-		return nil
+		return errors.Wrap(err, "httpServer failed")
 	}
-	return errors.Wrap(err, "httpServer failed")
 }
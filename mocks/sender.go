@@ -10,23 +10,25 @@ import (
 )
 
 type Sender struct {
-	SendStub        func(context.Context, <-chan avro.ApplicationVersionAvailable) error
+	SendStub        func(context.Context, <-chan avro.ApplicationVersionAvailable) (int, error)
 	sendMutex       sync.RWMutex
 	sendArgsForCall []struct {
 		arg1 context.Context
 		arg2 <-chan avro.ApplicationVersionAvailable
 	}
 	sendReturns struct {
-		result1 error
+		result1 int
+		result2 error
 	}
 	sendReturnsOnCall map[int]struct {
-		result1 error
+		result1 int
+		result2 error
 	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *Sender) Send(arg1 context.Context, arg2 <-chan avro.ApplicationVersionAvailable) error {
+func (fake *Sender) Send(arg1 context.Context, arg2 <-chan avro.ApplicationVersionAvailable) (int, error) {
 	fake.sendMutex.Lock()
 	ret, specificReturn := fake.sendReturnsOnCall[len(fake.sendArgsForCall)]
 	fake.sendArgsForCall = append(fake.sendArgsForCall, struct {
@@ -39,10 +41,10 @@ func (fake *Sender) Send(arg1 context.Context, arg2 <-chan avro.ApplicationVersi
 		return fake.SendStub(arg1, arg2)
 	}
 	if specificReturn {
-		return ret.result1
+		return ret.result1, ret.result2
 	}
 	fakeReturns := fake.sendReturns
-	return fakeReturns.result1
+	return fakeReturns.result1, fakeReturns.result2
 }
 
 func (fake *Sender) SendCallCount() int {
@@ -51,7 +53,7 @@ func (fake *Sender) SendCallCount() int {
 	return len(fake.sendArgsForCall)
 }
 
-func (fake *Sender) SendCalls(stub func(context.Context, <-chan avro.ApplicationVersionAvailable) error) {
+func (fake *Sender) SendCalls(stub func(context.Context, <-chan avro.ApplicationVersionAvailable) (int, error)) {
 	fake.sendMutex.Lock()
 	defer fake.sendMutex.Unlock()
 	fake.SendStub = stub
@@ -64,27 +66,30 @@ func (fake *Sender) SendArgsForCall(i int) (context.Context, <-chan avro.Applica
 	return argsForCall.arg1, argsForCall.arg2
 }
 
-func (fake *Sender) SendReturns(result1 error) {
+func (fake *Sender) SendReturns(result1 int, result2 error) {
 	fake.sendMutex.Lock()
 	defer fake.sendMutex.Unlock()
 	fake.SendStub = nil
 	fake.sendReturns = struct {
-		result1 error
-	}{result1}
+		result1 int
+		result2 error
+	}{result1, result2}
 }
 
-func (fake *Sender) SendReturnsOnCall(i int, result1 error) {
+func (fake *Sender) SendReturnsOnCall(i int, result1 int, result2 error) {
 	fake.sendMutex.Lock()
 	defer fake.sendMutex.Unlock()
 	fake.SendStub = nil
 	if fake.sendReturnsOnCall == nil {
 		fake.sendReturnsOnCall = make(map[int]struct {
-			result1 error
+			result1 int
+			result2 error
 		})
 	}
 	fake.sendReturnsOnCall[i] = struct {
-		result1 error
-	}{result1}
+		result1 int
+		result2 error
+	}{result1, result2}
 }
 
 func (fake *Sender) Invocations() map[string][][]interface{} {
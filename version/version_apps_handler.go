@@ -0,0 +1,197 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bborbe/kafka-k8s-version-collector/avro"
+	"github.com/golang/glog"
+)
+
+// AppVersion is the json representation of a published version.
+type AppVersion struct {
+	App        string `json:"app"`
+	Version    string `json:"version"`
+	Parsed     bool   `json:"parsed"`
+	Major      int    `json:"major"`
+	Minor      int    `json:"minor"`
+	Patch      int    `json:"patch"`
+	Prerelease string `json:"prerelease,omitempty"`
+	Build      string `json:"build,omitempty"`
+	Stable     bool   `json:"stable"`
+	// PublishedAt is the publish time of the release, if known.
+	PublishedAt *time.Time `json:"publishedAt,omitempty"`
+	URL         string     `json:"url,omitempty"`
+	// AppVersion is the version of the app packaged by a Helm chart.
+	AppVersion string `json:"appVersion,omitempty"`
+	// Channel lists the release channels pointing to the version, like stable,stable-1.13.
+	Channel string `json:"channel,omitempty"`
+	// Ecosystem is the package ecosystem of library versions, like Go or npm.
+	Ecosystem string `json:"ecosystem,omitempty"`
+	// Digest of the manifest the tag pointed to when published.
+	Digest string `json:"digest,omitempty"`
+	// Platforms of the manifest list the tag pointed to, like linux/amd64.
+	Platforms []string `json:"platforms,omitempty"`
+}
+
+// NewAppVersion returns the AppVersion of the given record.
+func NewAppVersion(version avro.ApplicationVersionAvailable) AppVersion {
+	result := AppVersion{
+		App:        version.App,
+		Version:    version.Version,
+		Parsed:     version.Parsed,
+		Major:      int(version.Major),
+		Minor:      int(version.Minor),
+		Patch:      int(version.Patch),
+		Prerelease: version.Prerelease,
+		Build:      version.Build,
+		Stable:     version.Stable,
+		URL:        version.Url,
+		AppVersion: version.AppVersion,
+		Channel:    version.Channel,
+		Ecosystem:  version.Ecosystem,
+		Digest:     version.Digest,
+		Platforms:  version.Platforms,
+	}
+	if version.PublishedAt > 0 {
+		publishedAt := time.Unix(0, version.PublishedAt*int64(time.Millisecond)).UTC()
+		result.PublishedAt = &publishedAt
+	}
+	return result
+}
+
+// NewAppsHandler returns a http.Handler answering queries about the published versions in the store:
+//
+//   GET /apps                    names of all apps
+//   GET /apps/{app}/versions     versions of the app, newest first (?stable=true or ?prerelease=true to filter)
+//   GET /apps/{app}/latest       newest stable version of the app (?prerelease=true to include prereleases)
+func NewAppsHandler(store Store) http.Handler {
+	return &appsHandler{
+		store: store,
+	}
+}
+
+type appsHandler struct {
+	store Store
+}
+
+func (a *appsHandler) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		resp.Header().Set("Allow", http.MethodGet)
+		http.Error(resp, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	path := strings.TrimSuffix(req.URL.Path, "/")
+	switch {
+	case path == "/apps":
+		a.serveApps(resp)
+	case strings.HasPrefix(path, "/apps/") && strings.HasSuffix(path, "/versions"):
+		a.serveVersions(resp, req, strings.TrimSuffix(strings.TrimPrefix(path, "/apps/"), "/versions"))
+	case strings.HasPrefix(path, "/apps/") && strings.HasSuffix(path, "/latest"):
+		a.serveLatest(resp, req, strings.TrimSuffix(strings.TrimPrefix(path, "/apps/"), "/latest"))
+	default:
+		http.NotFound(resp, req)
+	}
+}
+
+func (a *appsHandler) serveApps(resp http.ResponseWriter) {
+	apps, err := a.store.Apps()
+	if err != nil {
+		glog.Warningf("list apps failed: %v", err)
+		http.Error(resp, "list apps failed", http.StatusInternalServerError)
+		return
+	}
+	sort.Strings(apps)
+	if apps == nil {
+		apps = []string{}
+	}
+	writeJSON(resp, apps)
+}
+
+func (a *appsHandler) serveVersions(resp http.ResponseWriter, req *http.Request, app string) {
+	stable := req.URL.Query().Get("stable") == "true"
+	prerelease := req.URL.Query().Get("prerelease") == "true"
+	if stable && prerelease {
+		http.Error(resp, "stable and prerelease are exclusive", http.StatusBadRequest)
+		return
+	}
+	versions, ok := a.versions(resp, app)
+	if !ok {
+		return
+	}
+	result := []AppVersion{}
+	for _, version := range versions {
+		if stable && !version.Stable {
+			continue
+		}
+		if prerelease && (!version.Parsed || version.Stable) {
+			continue
+		}
+		result = append(result, NewAppVersion(version))
+	}
+	writeJSON(resp, result)
+}
+
+func (a *appsHandler) serveLatest(resp http.ResponseWriter, req *http.Request, app string) {
+	prerelease := req.URL.Query().Get("prerelease") == "true"
+	versions, ok := a.versions(resp, app)
+	if !ok {
+		return
+	}
+	for _, version := range versions {
+		if version.Stable || (prerelease && version.Parsed) {
+			writeJSON(resp, NewAppVersion(version))
+			return
+		}
+	}
+	http.Error(resp, "no semantic version of app "+app+" found", http.StatusNotFound)
+}
+
+// versions returns the versions of the app sorted newest first or writes an error response.
+func (a *appsHandler) versions(resp http.ResponseWriter, app string) ([]avro.ApplicationVersionAvailable, bool) {
+	versions, err := a.store.Versions(app)
+	if err != nil {
+		glog.Warningf("list versions of %s failed: %v", app, err)
+		http.Error(resp, "list versions failed", http.StatusInternalServerError)
+		return nil, false
+	}
+	if len(versions) == 0 {
+		http.Error(resp, "app "+app+" not found", http.StatusNotFound)
+		return nil, false
+	}
+	SortVersions(versions)
+	return versions, true
+}
+
+// SortVersions sorts semantic versions newest first, followed by all other versions in alphabetical order.
+// Versions of distribution packages are sorted newest first by the rules of their ecosystem.
+func SortVersions(versions []avro.ApplicationVersionAvailable) {
+	sort.SliceStable(versions, func(i, j int) bool {
+		if versions[i].Ecosystem == versions[j].Ecosystem {
+			if compare, ok := distroVersionCompare[versions[i].Ecosystem]; ok {
+				return compare(versions[i].Version, versions[j].Version) > 0
+			}
+		}
+		if versions[i].Parsed != versions[j].Parsed {
+			return versions[i].Parsed
+		}
+		if !versions[i].Parsed {
+			return versions[i].Version < versions[j].Version
+		}
+		return semVerOf(versions[i]).Compare(semVerOf(versions[j])) > 0
+	})
+}
+
+func writeJSON(resp http.ResponseWriter, value interface{}) {
+	resp.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(resp).Encode(value); err != nil {
+		glog.Warningf("encode json failed: %v", err)
+	}
+}
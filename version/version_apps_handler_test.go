@@ -0,0 +1,138 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/bborbe/kafka-k8s-version-collector/avro"
+	"github.com/bborbe/kafka-k8s-version-collector/mocks"
+	"github.com/bborbe/kafka-k8s-version-collector/version"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Apps Handler", func() {
+	var handler http.Handler
+	var store *mocks.Store
+	var recorder *httptest.ResponseRecorder
+	BeforeEach(func() {
+		store = &mocks.Store{}
+		store.AppsReturns([]string{"Kubernetes", "Grafana"}, nil)
+		store.VersionsReturns([]avro.ApplicationVersionAvailable{
+			version.NewApplicationVersionAvailable("Kubernetes", "latest"),
+			version.NewApplicationVersionAvailable("Kubernetes", "v1.13.4"),
+			version.NewApplicationVersionAvailable("Kubernetes", "v1.14.0-beta.0"),
+			version.NewApplicationVersionAvailable("Kubernetes", "v1.12.7"),
+		}, nil)
+		handler = version.NewAppsHandler(store)
+		recorder = httptest.NewRecorder()
+	})
+	get := func(url string) {
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, url, nil))
+	}
+	versionsOf := func(body []version.AppVersion) []string {
+		var result []string
+		for _, v := range body {
+			result = append(result, v.Version)
+		}
+		return result
+	}
+	It("returns sorted apps", func() {
+		get("/apps")
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		var apps []string
+		Expect(json.NewDecoder(recorder.Body).Decode(&apps)).To(BeNil())
+		Expect(apps).To(Equal([]string{"Grafana", "Kubernetes"}))
+	})
+	It("returns versions newest first", func() {
+		get("/apps/Kubernetes/versions")
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(store.VersionsArgsForCall(0)).To(Equal("Kubernetes"))
+		var versions []version.AppVersion
+		Expect(json.NewDecoder(recorder.Body).Decode(&versions)).To(BeNil())
+		Expect(versionsOf(versions)).To(Equal([]string{"v1.14.0-beta.0", "v1.13.4", "v1.12.7", "latest"}))
+		Expect(versions[1].Major).To(Equal(1))
+		Expect(versions[1].Minor).To(Equal(13))
+		Expect(versions[1].Stable).To(BeTrue())
+	})
+	It("returns only stable versions", func() {
+		get("/apps/Kubernetes/versions?stable=true")
+		var versions []version.AppVersion
+		Expect(json.NewDecoder(recorder.Body).Decode(&versions)).To(BeNil())
+		Expect(versionsOf(versions)).To(Equal([]string{"v1.13.4", "v1.12.7"}))
+	})
+	It("returns only prereleases", func() {
+		get("/apps/Kubernetes/versions?prerelease=true")
+		var versions []version.AppVersion
+		Expect(json.NewDecoder(recorder.Body).Decode(&versions)).To(BeNil())
+		Expect(versionsOf(versions)).To(Equal([]string{"v1.14.0-beta.0"}))
+	})
+	It("returns releases marked as prerelease", func() {
+		store.VersionsReturns([]avro.ApplicationVersionAvailable{
+			version.NewGitHubReleaseVersion("Grafana", version.GitHubRelease{TagName: "v6.0.0", Prerelease: true}),
+			version.NewGitHubReleaseVersion("Grafana", version.GitHubRelease{TagName: "v5.4.0"}),
+		}, nil)
+		get("/apps/Grafana/versions?prerelease=true")
+		var versions []version.AppVersion
+		Expect(json.NewDecoder(recorder.Body).Decode(&versions)).To(BeNil())
+		Expect(versionsOf(versions)).To(Equal([]string{"v6.0.0"}))
+		Expect(versions[0].Channel).To(Equal(version.GitHubChannelPrerelease))
+	})
+	It("returns bad request if stable and prerelease", func() {
+		get("/apps/Kubernetes/versions?stable=true&prerelease=true")
+		Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+	})
+	It("returns latest stable version", func() {
+		get("/apps/Kubernetes/latest")
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		var latest version.AppVersion
+		Expect(json.NewDecoder(recorder.Body).Decode(&latest)).To(BeNil())
+		Expect(latest.Version).To(Equal("v1.13.4"))
+	})
+	It("returns latest version including prereleases", func() {
+		get("/apps/Kubernetes/latest?prerelease=true")
+		var latest version.AppVersion
+		Expect(json.NewDecoder(recorder.Body).Decode(&latest)).To(BeNil())
+		Expect(latest.Version).To(Equal("v1.14.0-beta.0"))
+	})
+	It("returns not found for unknown app", func() {
+		store.VersionsReturns(nil, nil)
+		get("/apps/banana/versions")
+		Expect(recorder.Code).To(Equal(http.StatusNotFound))
+	})
+	It("returns not found for unknown path", func() {
+		get("/apps/Kubernetes/banana")
+		Expect(recorder.Code).To(Equal(http.StatusNotFound))
+	})
+	It("returns internal server error if store fails", func() {
+		store.AppsReturns(nil, errors.New("banana"))
+		get("/apps")
+		Expect(recorder.Code).To(Equal(http.StatusInternalServerError))
+	})
+	It("returns method not allowed for post", func() {
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/apps", nil))
+		Expect(recorder.Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+	It("returns publish time and url of release", func() {
+		release := version.NewApplicationVersionAvailable("Grafana", "v6.0.0")
+		release.PublishedAt = time.Date(2019, 2, 25, 12, 0, 0, 0, time.UTC).UnixNano() / int64(time.Millisecond)
+		release.Url = "https://github.com/grafana/grafana/releases/tag/v6.0.0"
+		appVersion := version.NewAppVersion(release)
+		Expect(appVersion.PublishedAt).NotTo(BeNil())
+		Expect(*appVersion.PublishedAt).To(Equal(time.Date(2019, 2, 25, 12, 0, 0, 0, time.UTC)))
+		Expect(appVersion.URL).To(Equal(release.Url))
+		Expect(version.NewAppVersion(version.NewApplicationVersionAvailable("Grafana", "v6.0.0")).PublishedAt).To(BeNil())
+	})
+	It("returns ecosystem of package version", func() {
+		library := version.NewApplicationVersionAvailable("React", "16.8.4")
+		library.Ecosystem = version.EcosystemNpm
+		Expect(version.NewAppVersion(library).Ecosystem).To(Equal("npm"))
+	})
+})
@@ -0,0 +1,35 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"math"
+
+	"github.com/bborbe/kafka-k8s-version-collector/avro"
+)
+
+// NewApplicationVersionAvailable returns the record for the given app and version.
+// The semantic version fields are filled if the version could be parsed.
+// Versions with components exceeding the int of the schema, like timestamps, are not parsed.
+func NewApplicationVersionAvailable(app string, version string) avro.ApplicationVersionAvailable {
+	result := *avro.NewApplicationVersionAvailable()
+	result.App = app
+	result.Version = version
+	semVer, err := ParseSemVer(version)
+	if err != nil {
+		return result
+	}
+	if semVer.Major > math.MaxInt32 || semVer.Minor > math.MaxInt32 || semVer.Patch > math.MaxInt32 {
+		return result
+	}
+	result.Parsed = true
+	result.Major = int32(semVer.Major)
+	result.Minor = int32(semVer.Minor)
+	result.Patch = int32(semVer.Patch)
+	result.Prerelease = semVer.Prerelease
+	result.Build = semVer.Build
+	result.Stable = semVer.Stable()
+	return result
+}
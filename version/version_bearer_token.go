@@ -0,0 +1,294 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+// defaultTokenExpiresIn is used if the token service returns no expires_in. (see https://docs.docker.com/registry/spec/auth/token/)
+const defaultTokenExpiresIn = 60 * time.Second
+
+// tokenExpiryMargin let tokens expire a bit earlier to avoid using them in the last moment.
+const tokenExpiryMargin = 5 * time.Second
+
+var repositoryPathRegexp = regexp.MustCompile(`^/v2/(.+)/(tags|manifests|blobs)/`)
+
+// NewBearerTokenRoundTripper returns a http.RoundTripper that handles the token authentication of the given registry.
+// If the registry answers with a Bearer challenge, a token is requested from the realm and the request is retried.
+// Username and password are optional and sent as basic auth to the token service.
+// Challenges of other hosts, like redirect targets, are not answered and credentials are only sent to the registry
+// and to realms its challenges name, realms without https only on the registry host.
+func NewBearerTokenRoundTripper(
+	roundTripper http.RoundTripper,
+	registry string,
+	username string,
+	password string,
+) http.RoundTripper {
+	var host string
+	if u, err := url.Parse(registry); err == nil {
+		host = u.Host
+	}
+	return &bearerTokenRoundTripper{
+		roundTripper: roundTripper,
+		host:         host,
+		username:     username,
+		password:     password,
+		challenges:   make(map[string]Challenge),
+		tokens:       make(map[Challenge]token),
+	}
+}
+
+type bearerTokenRoundTripper struct {
+	roundTripper http.RoundTripper
+	host         string
+	username     string
+	password     string
+
+	mux        sync.Mutex
+	challenges map[string]Challenge
+	tokens     map[Challenge]token
+}
+
+type token struct {
+	value   string
+	expires time.Time
+}
+
+// Challenge contains the parameters of a WWW-Authenticate header.
+type Challenge struct {
+	Scheme  string
+	Realm   string
+	Service string
+	Scope   string
+}
+
+func (b *bearerTokenRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("Authorization") != "" || req.URL.Host != b.host {
+		return b.roundTripper.RoundTrip(req)
+	}
+	if challenge, ok := b.knownChallenge(req); ok {
+		if value, ok := b.cachedToken(challenge); ok {
+			return b.roundTripper.RoundTrip(withAuthorization(req, "Bearer "+value))
+		}
+	}
+	resp, err := b.roundTripper.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusUnauthorized {
+		return resp, nil
+	}
+	challenge, err := ParseChallenge(resp.Header.Get("WWW-Authenticate"))
+	if err != nil {
+		glog.V(2).Infof("%s %s unauthorized without parsable challenge: %v", req.Method, req.URL.String(), err)
+		return resp, nil
+	}
+	switch challenge.Scheme {
+	case "basic":
+		if b.username == "" {
+			return resp, nil
+		}
+		resp.Body.Close()
+		authReq := withAuthorization(req, "")
+		authReq.SetBasicAuth(b.username, b.password)
+		return b.roundTripper.RoundTrip(authReq)
+	case "bearer":
+		resp.Body.Close()
+		if challenge.Scope == "" {
+			challenge.Scope = scopeForRequest(req)
+		}
+		b.rememberChallenge(req, challenge)
+		value, err := b.token(req, challenge)
+		if err != nil {
+			return nil, errors.Wrap(err, "get token failed")
+		}
+		return b.roundTripper.RoundTrip(withAuthorization(req, "Bearer "+value))
+	default:
+		return resp, nil
+	}
+}
+
+func (b *bearerTokenRoundTripper) knownChallenge(req *http.Request) (Challenge, bool) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	challenge, ok := b.challenges[req.URL.Host]
+	if !ok {
+		return challenge, false
+	}
+	challenge.Scope = scopeForRequest(req)
+	return challenge, challenge.Scope != ""
+}
+
+func (b *bearerTokenRoundTripper) rememberChallenge(req *http.Request, challenge Challenge) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	b.challenges[req.URL.Host] = challenge
+}
+
+func (b *bearerTokenRoundTripper) cachedToken(challenge Challenge) (string, bool) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	t, ok := b.tokens[challenge]
+	if !ok || !time.Now().Before(t.expires) {
+		return "", false
+	}
+	return t.value, true
+}
+
+func (b *bearerTokenRoundTripper) token(req *http.Request, challenge Challenge) (string, error) {
+	if value, ok := b.cachedToken(challenge); ok {
+		return value, nil
+	}
+	u, err := url.Parse(challenge.Realm)
+	if err != nil {
+		return "", errors.Wrapf(err, "parse realm %s failed", challenge.Realm)
+	}
+	values := u.Query()
+	if challenge.Service != "" {
+		values.Set("service", challenge.Service)
+	}
+	if challenge.Scope != "" {
+		values.Set("scope", challenge.Scope)
+	}
+	u.RawQuery = values.Encode()
+	tokenReq, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", errors.Wrap(err, "build request failed")
+	}
+	tokenReq = tokenReq.WithContext(req.Context())
+	if b.username != "" && (u.Scheme == "https" || u.Host == b.host) {
+		tokenReq.SetBasicAuth(b.username, b.password)
+	}
+	glog.V(1).Infof("%s %s", tokenReq.Method, tokenReq.URL.String())
+	resp, err := b.roundTripper.RoundTrip(tokenReq)
+	if err != nil {
+		return "", errors.Wrap(err, "request failed")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return "", errors.Errorf("token request status code %d != 2xx", resp.StatusCode)
+	}
+	var data struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return "", errors.Wrap(err, "decode json failed")
+	}
+	value := data.Token
+	if value == "" {
+		value = data.AccessToken
+	}
+	if value == "" {
+		return "", errors.New("token response contains no token")
+	}
+	expiresIn := defaultTokenExpiresIn
+	if data.ExpiresIn > 0 {
+		expiresIn = time.Duration(data.ExpiresIn) * time.Second
+	}
+	if expiresIn > tokenExpiryMargin {
+		expiresIn -= tokenExpiryMargin
+	}
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	b.tokens[challenge] = token{
+		value:   value,
+		expires: time.Now().Add(expiresIn),
+	}
+	return value, nil
+}
+
+// scopeForRequest returns the pull scope for the repository of the request.
+func scopeForRequest(req *http.Request) string {
+	matches := repositoryPathRegexp.FindStringSubmatch(req.URL.Path)
+	if len(matches) < 2 {
+		return ""
+	}
+	return fmt.Sprintf("repository:%s:pull", matches[1])
+}
+
+// withAuthorization returns a copy of the request with the given Authorization header.
+func withAuthorization(req *http.Request, authorization string) *http.Request {
+	result := new(http.Request)
+	*result = *req
+	result.Header = make(http.Header, len(req.Header))
+	for k, v := range req.Header {
+		result.Header[k] = append([]string(nil), v...)
+	}
+	if authorization != "" {
+		result.Header.Set("Authorization", authorization)
+	}
+	return result
+}
+
+// ParseChallenge parses a WWW-Authenticate header like
+// Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/nginx:pull"
+func ParseChallenge(header string) (Challenge, error) {
+	header = strings.TrimSpace(header)
+	pos := strings.Index(header, " ")
+	if pos == -1 {
+		if header == "" {
+			return Challenge{}, errors.New("challenge is empty")
+		}
+		return Challenge{Scheme: strings.ToLower(header)}, nil
+	}
+	challenge := Challenge{
+		Scheme: strings.ToLower(header[:pos]),
+	}
+	params, err := parseChallengeParams(header[pos+1:])
+	if err != nil {
+		return Challenge{}, err
+	}
+	challenge.Realm = params["realm"]
+	challenge.Service = params["service"]
+	challenge.Scope = params["scope"]
+	if challenge.Scheme == "bearer" && challenge.Realm == "" {
+		return Challenge{}, errors.Errorf("bearer challenge without realm: %s", header)
+	}
+	return challenge, nil
+}
+
+func parseChallengeParams(value string) (map[string]string, error) {
+	result := make(map[string]string)
+	for {
+		value = strings.TrimLeft(value, " ,")
+		if value == "" {
+			return result, nil
+		}
+		pos := strings.Index(value, "=")
+		if pos == -1 {
+			return nil, errors.Errorf("invalid challenge param: %s", value)
+		}
+		key := strings.ToLower(strings.TrimSpace(value[:pos]))
+		value = value[pos+1:]
+		if strings.HasPrefix(value, `"`) {
+			end := strings.Index(value[1:], `"`)
+			if end == -1 {
+				return nil, errors.Errorf("unterminated quote in challenge param %s", key)
+			}
+			result[key] = value[1 : end+1]
+			value = value[end+2:]
+		} else {
+			end := strings.Index(value, ",")
+			if end == -1 {
+				end = len(value)
+			}
+			result[key] = strings.TrimSpace(value[:end])
+			value = value[end:]
+		}
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version_test
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/bborbe/kafka-k8s-version-collector/version"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+)
+
+var _ = Describe("Bearer Token RoundTripper", func() {
+	var server *ghttp.Server
+	var httpClient *http.Client
+	var tokenRequests int
+	BeforeEach(func() {
+		tokenRequests = 0
+		server = ghttp.NewServer()
+		httpClient = &http.Client{
+			Transport: version.NewBearerTokenRoundTripper(http.DefaultTransport, server.URL(), "", ""),
+		}
+		server.RouteToHandler(http.MethodGet, "/token", func(resp http.ResponseWriter, req *http.Request) {
+			tokenRequests++
+			Expect(req.URL.Query().Get("service")).To(Equal("registry.example.com"))
+			Expect(req.URL.Query().Get("scope")).To(Equal("repository:library/nginx:pull"))
+			fmt.Fprint(resp, `{"token":"secret","expires_in":300}`)
+		})
+		server.RouteToHandler(http.MethodGet, "/v2/library/nginx/tags/list", func(resp http.ResponseWriter, req *http.Request) {
+			if req.Header.Get("Authorization") != "Bearer secret" {
+				resp.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="registry.example.com",scope="repository:library/nginx:pull"`, server.URL()))
+				resp.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(resp, `{"tags":["1.15"]}`)
+		})
+	})
+	AfterEach(func() {
+		server.Close()
+	})
+	It("gets token and retries request", func() {
+		resp, err := httpClient.Get(server.URL() + "/v2/library/nginx/tags/list")
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(tokenRequests).To(Equal(1))
+	})
+	It("reuses cached token", func() {
+		for i := 0; i < 3; i++ {
+			resp, err := httpClient.Get(server.URL() + "/v2/library/nginx/tags/list")
+			Expect(err).NotTo(HaveOccurred())
+			resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		}
+		Expect(tokenRequests).To(Equal(1))
+	})
+	It("sends credentials to token service", func() {
+		httpClient = &http.Client{
+			Transport: version.NewBearerTokenRoundTripper(http.DefaultTransport, server.URL(), "user", "pass"),
+		}
+		server.RouteToHandler(http.MethodGet, "/token", func(resp http.ResponseWriter, req *http.Request) {
+			username, password, ok := req.BasicAuth()
+			Expect(ok).To(BeTrue())
+			Expect(username).To(Equal("user"))
+			Expect(password).To(Equal("pass"))
+			fmt.Fprint(resp, `{"access_token":"secret"}`)
+		})
+		resp, err := httpClient.Get(server.URL() + "/v2/library/nginx/tags/list")
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	})
+	It("returns error if token service fails", func() {
+		server.RouteToHandler(http.MethodGet, "/token", func(resp http.ResponseWriter, req *http.Request) {
+			resp.WriteHeader(http.StatusForbidden)
+		})
+		_, err := httpClient.Get(server.URL() + "/v2/library/nginx/tags/list")
+		Expect(err).To(HaveOccurred())
+	})
+	It("retries with basic auth on basic challenge", func() {
+		httpClient = &http.Client{
+			Transport: version.NewBearerTokenRoundTripper(http.DefaultTransport, server.URL(), "user", "pass"),
+		}
+		server.RouteToHandler(http.MethodGet, "/v2/private/app/tags/list", func(resp http.ResponseWriter, req *http.Request) {
+			if _, _, ok := req.BasicAuth(); !ok {
+				resp.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+				resp.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(resp, `{"tags":[]}`)
+		})
+		resp, err := httpClient.Get(server.URL() + "/v2/private/app/tags/list")
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	})
+	Context("with other host", func() {
+		var other *ghttp.Server
+		var authorized bool
+		BeforeEach(func() {
+			authorized = false
+			other = ghttp.NewServer()
+			httpClient = &http.Client{
+				Transport: version.NewBearerTokenRoundTripper(http.DefaultTransport, server.URL(), "user", "pass"),
+			}
+			other.RouteToHandler(http.MethodGet, "/token", func(resp http.ResponseWriter, req *http.Request) {
+				_, _, authorized = req.BasicAuth()
+				fmt.Fprint(resp, `{"token":"secret"}`)
+			})
+			other.RouteToHandler(http.MethodGet, "/v2/library/nginx/tags/list", func(resp http.ResponseWriter, req *http.Request) {
+				if _, _, ok := req.BasicAuth(); ok {
+					authorized = true
+				}
+				resp.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="other"`, other.URL()))
+				resp.WriteHeader(http.StatusUnauthorized)
+			})
+			other.RouteToHandler(http.MethodGet, "/v2/private/app/tags/list", func(resp http.ResponseWriter, req *http.Request) {
+				if _, _, ok := req.BasicAuth(); ok {
+					authorized = true
+				}
+				resp.Header().Set("WWW-Authenticate", `Basic realm="other"`)
+				resp.WriteHeader(http.StatusUnauthorized)
+			})
+		})
+		AfterEach(func() {
+			other.Close()
+		})
+		It("does not answer bearer challenge of other host", func() {
+			resp, err := httpClient.Get(other.URL() + "/v2/library/nginx/tags/list")
+			Expect(err).NotTo(HaveOccurred())
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+			Expect(authorized).To(BeFalse())
+			Expect(other.ReceivedRequests()).To(HaveLen(1))
+		})
+		It("does not answer basic challenge of other host", func() {
+			resp, err := httpClient.Get(other.URL() + "/v2/private/app/tags/list")
+			Expect(err).NotTo(HaveOccurred())
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+			Expect(authorized).To(BeFalse())
+		})
+		It("does not send credentials to realm of other host without https", func() {
+			server.RouteToHandler(http.MethodGet, "/v2/library/nginx/tags/list", func(resp http.ResponseWriter, req *http.Request) {
+				if req.Header.Get("Authorization") != "Bearer secret" {
+					resp.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="registry.example.com"`, other.URL()))
+					resp.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				fmt.Fprint(resp, `{"tags":["1.15"]}`)
+			})
+			resp, err := httpClient.Get(server.URL() + "/v2/library/nginx/tags/list")
+			Expect(err).NotTo(HaveOccurred())
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(other.ReceivedRequests()).To(HaveLen(1))
+			Expect(authorized).To(BeFalse())
+		})
+	})
+	It("returns unauthorized response if no challenge", func() {
+		server.RouteToHandler(http.MethodGet, "/v2/private/app/tags/list", func(resp http.ResponseWriter, req *http.Request) {
+			resp.WriteHeader(http.StatusUnauthorized)
+		})
+		resp, err := httpClient.Get(server.URL() + "/v2/private/app/tags/list")
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+	})
+})
+
+var _ = Describe("Challenge", func() {
+	It("parses bearer challenge", func() {
+		challenge, err := version.ParseChallenge(`Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/nginx:pull,push"`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(challenge.Scheme).To(Equal("bearer"))
+		Expect(challenge.Realm).To(Equal("https://auth.docker.io/token"))
+		Expect(challenge.Service).To(Equal("registry.docker.io"))
+		Expect(challenge.Scope).To(Equal("repository:library/nginx:pull,push"))
+	})
+	It("parses basic challenge", func() {
+		challenge, err := version.ParseChallenge(`Basic realm="Registry Realm"`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(challenge.Scheme).To(Equal("basic"))
+		Expect(challenge.Realm).To(Equal("Registry Realm"))
+	})
+	It("returns error if bearer has no realm", func() {
+		_, err := version.ParseChallenge(`Bearer service="registry.docker.io"`)
+		Expect(err).To(HaveOccurred())
+	})
+	It("returns error if empty", func() {
+		_, err := version.ParseChallenge("")
+		Expect(err).To(HaveOccurred())
+	})
+})
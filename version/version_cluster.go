@@ -0,0 +1,435 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/bborbe/kafka-k8s-version-collector/avro"
+	"github.com/bborbe/run"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+// Kinds of cluster resources published as deployed versions.
+const (
+	KindDeployment  = "Deployment"
+	KindStatefulSet = "StatefulSet"
+	KindDaemonSet   = "DaemonSet"
+	KindNode        = "Node"
+)
+
+// clusterListLimit is the number of items requested per page from the API server.
+const clusterListLimit = 500
+
+//go:generate counterfeiter -o ../mocks/deployment_fetcher.go --fake-name DeploymentFetcher . DeploymentFetcher
+type DeploymentFetcher interface {
+	// Fetch sends all deployed versions to the channel.
+	Fetch(ctx context.Context, deployments chan<- avro.ApplicationVersionDeployed) error
+}
+
+// ImageApps returns the app of a image repository, empty if unknown.
+type ImageApps func(host string, repository string) string
+
+// NewImageApps returns the apps of the registry and dockerhub sources of the config by their repository,
+// so deployed images can be joined with the available versions.
+func NewImageApps(config *Config) (ImageApps, error) {
+	type source struct {
+		host       string
+		repository string
+		app        string
+	}
+	var sources []source
+	for _, sourceConfig := range config.Sources {
+		if !containsString(registrySourceTypes, sourceConfig.Type) {
+			continue
+		}
+		host, repository, err := webhookSourceRepository(sourceConfig)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parse repository of source %s failed", sourceConfig.Name)
+		}
+		sources = append(sources, source{host: host, repository: repository, app: sourceConfig.App})
+	}
+	return func(host string, repository string) string {
+		for _, source := range sources {
+			if source.repository == repository && sameRegistryHost(source.host, host) {
+				return source.app
+			}
+		}
+		return ""
+	}, nil
+}
+
+// Cluster describes the inventory of a Kubernetes cluster.
+type Cluster struct {
+	// Name is published as cluster of each deployed version.
+	Name string
+	// Server is the url of the API server.
+	Server string
+	// Namespaces the workloads are listed of, all if empty.
+	Namespaces []string
+	// App is published as app of the kubelet versions of the nodes.
+	App  string
+	Apps ImageApps
+}
+
+// NewClusterInventory returns a DeploymentFetcher listing the containers of all Deployments, StatefulSets and DaemonSets
+// and the kubelet version of all Nodes of the cluster.
+// The digest of a container is the digest its pods run, the digest of the image if no pod reports one.
+func NewClusterInventory(httpClient *http.Client, cluster Cluster) DeploymentFetcher {
+	return &clusterInventory{
+		httpClient: httpClient,
+		cluster:    cluster,
+	}
+}
+
+type clusterInventory struct {
+	httpClient *http.Client
+	cluster    Cluster
+}
+
+// kubernetesList contains the fields of workload and node lists used by the inventory.
+type kubernetesList struct {
+	Metadata struct {
+		Continue string `json:"continue"`
+	} `json:"metadata"`
+	Items []struct {
+		Metadata struct {
+			Name            string            `json:"name"`
+			Namespace       string            `json:"namespace"`
+			Labels          map[string]string `json:"labels"`
+			OwnerReferences []struct {
+				Kind string `json:"kind"`
+				Name string `json:"name"`
+			} `json:"ownerReferences"`
+		} `json:"metadata"`
+		Spec struct {
+			Template struct {
+				Spec struct {
+					InitContainers []kubernetesContainer `json:"initContainers"`
+					Containers     []kubernetesContainer `json:"containers"`
+				} `json:"spec"`
+			} `json:"template"`
+		} `json:"spec"`
+		Status struct {
+			NodeInfo struct {
+				KubeletVersion string `json:"kubeletVersion"`
+			} `json:"nodeInfo"`
+			InitContainerStatuses []kubernetesContainerStatus `json:"initContainerStatuses"`
+			ContainerStatuses     []kubernetesContainerStatus `json:"containerStatuses"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+type kubernetesContainer struct {
+	Name  string `json:"name"`
+	Image string `json:"image"`
+}
+
+type kubernetesContainerStatus struct {
+	Name string `json:"name"`
+	// ImageID is the image the container runs, like docker-pullable://nginx@sha256:abc.
+	ImageID string `json:"imageID"`
+}
+
+func (c *clusterInventory) Fetch(ctx context.Context, deployments chan<- avro.ApplicationVersionDeployed) error {
+	if err := c.fetch(ctx, deployments); err != nil {
+		if ctx.Err() != nil {
+			glog.Infof("context done => return")
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+func (c *clusterInventory) fetch(ctx context.Context, deployments chan<- avro.ApplicationVersionDeployed) error {
+	workloads := []struct {
+		kind     string
+		resource string
+	}{
+		{kind: KindDeployment, resource: "deployments"},
+		{kind: KindStatefulSet, resource: "statefulsets"},
+		{kind: KindDaemonSet, resource: "daemonsets"},
+	}
+	namespaces := c.cluster.Namespaces
+	if len(namespaces) == 0 {
+		namespaces = []string{""}
+	}
+	digests, err := c.runningDigests(ctx, namespaces)
+	if err != nil {
+		return errors.Wrap(err, "list pods failed")
+	}
+	for _, workload := range workloads {
+		for _, namespace := range namespaces {
+			if err := c.list(ctx, namespacedPath("/apis/apps/v1", namespace, workload.resource), func(list kubernetesList) error {
+				for _, item := range list.Items {
+					var containers []kubernetesContainer
+					containers = append(containers, item.Spec.Template.Spec.InitContainers...)
+					containers = append(containers, item.Spec.Template.Spec.Containers...)
+					for _, container := range containers {
+						deployed := c.containerDeployed(workload.kind, item.Metadata.Namespace, item.Metadata.Name, container)
+						if digest := digests.digest(workloadContainerKey(workload.kind, item.Metadata.Namespace, item.Metadata.Name, container.Name)); digest != "" {
+							deployed.Digest = digest
+						}
+						if err := c.send(ctx, deployments, deployed); err != nil {
+							return err
+						}
+					}
+				}
+				return nil
+			}); err != nil {
+				return errors.Wrapf(err, "list %s failed", workload.resource)
+			}
+		}
+	}
+	return errors.Wrap(c.list(ctx, "/api/v1/nodes", func(list kubernetesList) error {
+		for _, item := range list.Items {
+			deployed := *avro.NewApplicationVersionDeployed()
+			deployed.Cluster = c.cluster.Name
+			deployed.Kind = KindNode
+			deployed.Workload = item.Metadata.Name
+			deployed.KubeletVersion = item.Status.NodeInfo.KubeletVersion
+			deployed.App = c.cluster.App
+			if err := c.send(ctx, deployments, deployed); err != nil {
+				return err
+			}
+		}
+		return nil
+	}), "list nodes failed")
+}
+
+// namespacedPath returns the path of the resource in the namespace, of all namespaces if empty.
+func namespacedPath(prefix string, namespace string, resource string) string {
+	if namespace == "" {
+		return prefix + "/" + resource
+	}
+	return prefix + "/namespaces/" + url.PathEscape(namespace) + "/" + resource
+}
+
+// runningDigests counts the digests the containers of the pods of a workload run by workload container key.
+type runningDigests map[string]map[string]int
+
+// digest returns the digest most pods of the workload container run, empty if unknown.
+func (r runningDigests) digest(key string) string {
+	var result string
+	for digest, count := range r[key] {
+		if count > r[key][result] || count == r[key][result] && digest < result {
+			result = digest
+		}
+	}
+	return result
+}
+
+// runningDigests lists the pods to read the digests their containers run.
+// Pods are assigned to the workload owning them, to the Deployment of their ReplicaSet.
+func (c *clusterInventory) runningDigests(ctx context.Context, namespaces []string) (runningDigests, error) {
+	result := make(runningDigests)
+	for _, namespace := range namespaces {
+		if err := c.list(ctx, namespacedPath("/api/v1", namespace, "pods"), func(list kubernetesList) error {
+			for _, item := range list.Items {
+				for _, owner := range item.Metadata.OwnerReferences {
+					kind, workload := owner.Kind, owner.Name
+					if kind == "ReplicaSet" {
+						hash := item.Metadata.Labels["pod-template-hash"]
+						if hash == "" || !strings.HasSuffix(workload, "-"+hash) {
+							continue
+						}
+						kind, workload = KindDeployment, strings.TrimSuffix(workload, "-"+hash)
+					}
+					var statuses []kubernetesContainerStatus
+					statuses = append(statuses, item.Status.InitContainerStatuses...)
+					statuses = append(statuses, item.Status.ContainerStatuses...)
+					for _, status := range statuses {
+						digest := ImageIDDigest(status.ImageID)
+						if digest == "" {
+							continue
+						}
+						key := workloadContainerKey(kind, item.Metadata.Namespace, workload, status.Name)
+						if result[key] == nil {
+							result[key] = make(map[string]int)
+						}
+						result[key][digest]++
+					}
+				}
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func workloadContainerKey(kind string, namespace string, workload string, container string) string {
+	return strings.Join([]string{kind, namespace, workload, container}, "/")
+}
+
+// ImageIDDigest returns the digest of the image id of a container status like docker-pullable://nginx@sha256:abc.
+// Image ids without repository digest, like the id of a local image, return empty.
+func ImageIDDigest(imageID string) string {
+	pos := strings.LastIndex(imageID, "@")
+	if pos == -1 {
+		return ""
+	}
+	return imageID[pos+1:]
+}
+
+func (c *clusterInventory) containerDeployed(kind string, namespace string, workload string, container kubernetesContainer) avro.ApplicationVersionDeployed {
+	reference := ParseImageReference(container.Image)
+	result := *avro.NewApplicationVersionDeployed()
+	result.Cluster = c.cluster.Name
+	result.Kind = kind
+	result.Namespace = namespace
+	result.Workload = workload
+	result.Container = container.Name
+	result.Image = container.Image
+	result.Repository = reference.Host + "/" + reference.Repository
+	result.Tag = reference.Tag
+	result.Digest = reference.Digest
+	if c.cluster.Apps != nil {
+		result.App = c.cluster.Apps(reference.Host, reference.Repository)
+	}
+	return result
+}
+
+func (c *clusterInventory) send(ctx context.Context, deployments chan<- avro.ApplicationVersionDeployed, deployed avro.ApplicationVersionDeployed) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case deployments <- deployed:
+		return nil
+	}
+}
+
+// list requests all pages of the list at path from the API server.
+func (c *clusterInventory) list(ctx context.Context, path string, fn func(list kubernetesList) error) error {
+	var continueToken string
+	for {
+		values := url.Values{}
+		values.Set("limit", strconv.Itoa(clusterListLimit))
+		if continueToken != "" {
+			values.Set("continue", continueToken)
+		}
+		req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(c.cluster.Server, "/")+path+"?"+values.Encode(), nil)
+		if err != nil {
+			return errors.Wrap(err, "build request failed")
+		}
+		req = req.WithContext(ctx)
+		req.Header.Set("Accept", "application/json")
+		glog.V(1).Infof("%s %s", req.Method, req.URL.String())
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return errors.Wrap(err, "request failed")
+		}
+		var list kubernetesList
+		err = decodeKubernetesResponse(resp, &list)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+		if err := fn(list); err != nil {
+			return err
+		}
+		if list.Metadata.Continue == "" {
+			return nil
+		}
+		continueToken = list.Metadata.Continue
+	}
+}
+
+func decodeKubernetesResponse(resp *http.Response, value interface{}) error {
+	if resp.StatusCode/100 != 2 {
+		var status struct {
+			Message string `json:"message"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&status); err == nil && status.Message != "" {
+			return errors.Errorf("request status code %d != 2xx: %s", resp.StatusCode, status.Message)
+		}
+		return errors.Errorf("request status code %d != 2xx", resp.StatusCode)
+	}
+	return errors.Wrap(json.NewDecoder(resp.Body).Decode(value), "decode json failed")
+}
+
+// ImageReference is a parsed container image like registry.example.com/group/app:1.0@sha256:abc.
+type ImageReference struct {
+	Host       string
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// ParseImageReference parses a container image like the container runtime.
+// Images without registry are located on Docker Hub, official images in the library namespace.
+// Images without tag and digest use the tag latest.
+func ParseImageReference(image string) ImageReference {
+	var result ImageReference
+	name := image
+	if pos := strings.Index(name, "@"); pos != -1 {
+		result.Digest = name[pos+1:]
+		name = name[:pos]
+	}
+	if pos := strings.LastIndex(name, ":"); pos != -1 && !strings.Contains(name[pos:], "/") {
+		result.Tag = name[pos+1:]
+		name = name[:pos]
+	}
+	if result.Tag == "" && result.Digest == "" {
+		result.Tag = "latest"
+	}
+	parts := strings.SplitN(name, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		result.Host = parts[0]
+		result.Repository = parts[1]
+		return result
+	}
+	result.Host = "docker.io"
+	result.Repository = DockerHubRepository(name)
+	return result
+}
+
+// NewClusterFetcher returns a Fetcher for the runner publishing the deployed versions of the inventory with the sender.
+// It does not fetch available versions.
+// The channel of the sender is only closed once the inventory is complete, so the sender can delete what is gone.
+func NewClusterFetcher(inventory DeploymentFetcher, sender DeploymentSender) Fetcher {
+	return &clusterFetcher{
+		inventory: inventory,
+		sender:    sender,
+	}
+}
+
+type clusterFetcher struct {
+	inventory DeploymentFetcher
+	sender    DeploymentSender
+}
+
+func (c *clusterFetcher) Fetch(ctx context.Context, versions chan<- avro.ApplicationVersionAvailable) error {
+	deployments := make(chan avro.ApplicationVersionDeployed, runtime.NumCPU())
+	return run.CancelOnFirstError(
+		ctx,
+		func(ctx context.Context) error {
+			if err := c.inventory.Fetch(ctx, deployments); err != nil {
+				return err
+			}
+			if ctx.Err() == nil {
+				close(deployments)
+			}
+			return nil
+		},
+		func(ctx context.Context) error {
+			published, err := c.sender.Send(ctx, deployments)
+			if err != nil {
+				return errors.Wrap(err, "send deployed versions failed")
+			}
+			glog.V(1).Infof("published %d changed deployed versions", published)
+			return nil
+		},
+	)
+}
@@ -0,0 +1,245 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/bborbe/kafka-k8s-version-collector/avro"
+	"github.com/bborbe/kafka-k8s-version-collector/mocks"
+	"github.com/bborbe/kafka-k8s-version-collector/version"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+)
+
+func kubernetesWorkloads(namespace string, name string, images ...string) string {
+	var containers []string
+	for i, image := range images {
+		containers = append(containers, fmt.Sprintf(`{"name":"c%d","image":%q}`, i, image))
+	}
+	return fmt.Sprintf(`{"metadata":{"name":%q,"namespace":%q},"spec":{"template":{"spec":{"containers":[%s]}}}}`, name, namespace, strings.Join(containers, ","))
+}
+
+func kubernetesPod(namespace string, owner string, name string, hash string, imageIDs ...string) string {
+	var statuses []string
+	for i, imageID := range imageIDs {
+		statuses = append(statuses, fmt.Sprintf(`{"name":"c%d","imageID":%q}`, i, imageID))
+	}
+	return fmt.Sprintf(`{"metadata":{"namespace":%q,"labels":{"pod-template-hash":%q},"ownerReferences":[{"kind":%q,"name":%q}]},"status":{"containerStatuses":[%s]}}`, namespace, hash, owner, name, strings.Join(statuses, ","))
+}
+
+var _ = Describe("Cluster", func() {
+	var server *ghttp.Server
+	var cluster version.Cluster
+	BeforeEach(func() {
+		server = ghttp.NewServer()
+		server.AllowUnhandledRequests = true
+		server.UnhandledRequestStatusCode = http.StatusNotFound
+		cluster = version.Cluster{
+			Name:   "prod",
+			Server: server.URL(),
+			App:    "Kubernetes",
+		}
+	})
+	AfterEach(func() {
+		server.Close()
+	})
+	serveList := func(path string, items ...string) {
+		server.RouteToHandler(http.MethodGet, path, func(resp http.ResponseWriter, req *http.Request) {
+			fmt.Fprintf(resp, `{"metadata":{},"items":[%s]}`, strings.Join(items, ","))
+		})
+	}
+	serveEmpty := func() {
+		for _, resource := range []string{"deployments", "statefulsets", "daemonsets"} {
+			serveList("/apis/apps/v1/"+resource, "")
+		}
+		serveList("/api/v1/nodes", "")
+		serveList("/api/v1/pods", "")
+	}
+	fetch := func(fetcher version.DeploymentFetcher) ([]avro.ApplicationVersionDeployed, error) {
+		deployments := make(chan avro.ApplicationVersionDeployed, 100)
+		err := fetcher.Fetch(context.Background(), deployments)
+		close(deployments)
+		var list []avro.ApplicationVersionDeployed
+		for deployed := range deployments {
+			list = append(list, deployed)
+		}
+		return list, err
+	}
+	It("returns containers of workloads and kubelet versions of nodes", func() {
+		serveEmpty()
+		serveList("/apis/apps/v1/deployments", kubernetesWorkloads("default", "nginx", "nginx:1.15.8", "quay.io/prometheus/node-exporter:v0.17.0"))
+		serveList("/apis/apps/v1/statefulsets", kubernetesWorkloads("kafka", "kafka", "confluentinc/cp-kafka@sha256:abc"))
+		serveList("/apis/apps/v1/daemonsets", `{"metadata":{"name":"proxy","namespace":"kube-system"},"spec":{"template":{"spec":{"initContainers":[{"name":"init","image":"busybox"}],"containers":[{"name":"proxy","image":"gcr.io/google_containers/kube-proxy:v1.13.4"}]}}}}`)
+		serveList("/api/v1/nodes", `{"metadata":{"name":"node1"},"status":{"nodeInfo":{"kubeletVersion":"v1.13.4"}}}`)
+		list, err := fetch(version.NewClusterInventory(http.DefaultClient, cluster))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(list).To(HaveLen(6))
+		Expect(list[0]).To(Equal(avro.ApplicationVersionDeployed{
+			Cluster:    "prod",
+			Kind:       version.KindDeployment,
+			Namespace:  "default",
+			Workload:   "nginx",
+			Container:  "c0",
+			Image:      "nginx:1.15.8",
+			Repository: "docker.io/library/nginx",
+			Tag:        "1.15.8",
+		}))
+		Expect(list[1].Repository).To(Equal("quay.io/prometheus/node-exporter"))
+		Expect(list[2].Kind).To(Equal(version.KindStatefulSet))
+		Expect(list[2].Tag).To(Equal(""))
+		Expect(list[2].Digest).To(Equal("sha256:abc"))
+		Expect(list[3].Kind).To(Equal(version.KindDaemonSet))
+		Expect(list[3].Container).To(Equal("init"))
+		Expect(list[3].Tag).To(Equal("latest"))
+		Expect(list[4].Container).To(Equal("proxy"))
+		Expect(list[5]).To(Equal(avro.ApplicationVersionDeployed{
+			Cluster:        "prod",
+			Kind:           version.KindNode,
+			Workload:       "node1",
+			KubeletVersion: "v1.13.4",
+			App:            "Kubernetes",
+		}))
+	})
+	It("requests all pages", func() {
+		serveEmpty()
+		server.RouteToHandler(http.MethodGet, "/apis/apps/v1/deployments", func(resp http.ResponseWriter, req *http.Request) {
+			Expect(req.URL.Query().Get("limit")).To(Equal("500"))
+			if req.URL.Query().Get("continue") == "" {
+				fmt.Fprintf(resp, `{"metadata":{"continue":"page2"},"items":[%s]}`, kubernetesWorkloads("default", "a", "nginx:1"))
+				return
+			}
+			Expect(req.URL.Query().Get("continue")).To(Equal("page2"))
+			fmt.Fprintf(resp, `{"metadata":{},"items":[%s]}`, kubernetesWorkloads("default", "b", "nginx:2"))
+		})
+		list, err := fetch(version.NewClusterInventory(http.DefaultClient, cluster))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(list).To(HaveLen(2))
+		Expect(list[0].Workload).To(Equal("a"))
+		Expect(list[1].Workload).To(Equal("b"))
+	})
+	It("lists workloads of configured namespaces", func() {
+		serveEmpty()
+		cluster.Namespaces = []string{"team-a"}
+		serveList("/apis/apps/v1/namespaces/team-a/deployments", kubernetesWorkloads("team-a", "app", "nginx:1"))
+		serveList("/apis/apps/v1/namespaces/team-a/statefulsets")
+		serveList("/apis/apps/v1/namespaces/team-a/daemonsets")
+		serveList("/api/v1/namespaces/team-a/pods")
+		list, err := fetch(version.NewClusterInventory(http.DefaultClient, cluster))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(list).To(HaveLen(1))
+		Expect(list[0].Namespace).To(Equal("team-a"))
+	})
+	It("sets digest running in pods of workload", func() {
+		serveEmpty()
+		serveList("/apis/apps/v1/deployments", kubernetesWorkloads("default", "nginx", "nginx:1.15.8"))
+		serveList("/apis/apps/v1/statefulsets", kubernetesWorkloads("kafka", "kafka", "confluentinc/cp-kafka@sha256:abc"))
+		serveList("/apis/apps/v1/daemonsets", kubernetesWorkloads("kube-system", "proxy", "kube-proxy:v1.13.4"))
+		serveList("/api/v1/pods",
+			kubernetesPod("default", "ReplicaSet", "nginx-5d8f7c9b4", "5d8f7c9b4", "docker-pullable://nginx@sha256:new"),
+			kubernetesPod("default", "ReplicaSet", "nginx-5d8f7c9b4", "5d8f7c9b4", "docker-pullable://nginx@sha256:new"),
+			kubernetesPod("default", "ReplicaSet", "nginx-7f6d5c4b3", "7f6d5c4b3", "docker.io/library/nginx@sha256:old"),
+			kubernetesPod("default", "ReplicaSet", "nginx-other", "5d8f7c9b4", "docker-pullable://nginx@sha256:other"),
+			kubernetesPod("kafka", "StatefulSet", "kafka", "", "docker-pullable://confluentinc/cp-kafka@sha256:running"),
+			kubernetesPod("kube-system", "DaemonSet", "proxy", "", "sha256:local"),
+		)
+		list, err := fetch(version.NewClusterInventory(http.DefaultClient, cluster))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(list).To(HaveLen(3))
+		Expect(list[0].Digest).To(Equal("sha256:new"))
+		Expect(list[1].Digest).To(Equal("sha256:running"))
+		Expect(list[2].Digest).To(Equal(""))
+	})
+	It("returns digest of image id", func() {
+		Expect(version.ImageIDDigest("docker-pullable://nginx@sha256:abc")).To(Equal("sha256:abc"))
+		Expect(version.ImageIDDigest("docker.io/library/nginx@sha256:abc")).To(Equal("sha256:abc"))
+		Expect(version.ImageIDDigest("sha256:abc")).To(Equal(""))
+		Expect(version.ImageIDDigest("")).To(Equal(""))
+	})
+	It("sets app of images with registry source", func() {
+		serveEmpty()
+		serveList("/apis/apps/v1/deployments", kubernetesWorkloads("default", "nginx", "nginx:1.15.8", "redis:5"))
+		var err error
+		cluster.Apps, err = version.NewImageApps(&version.Config{
+			Sources: []version.SourceConfig{
+				{Name: "nginx", Type: version.SourceTypeDockerHub, Repository: "nginx", App: "Nginx"},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		list, err := fetch(version.NewClusterInventory(http.DefaultClient, cluster))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(list).To(HaveLen(2))
+		Expect(list[0].App).To(Equal("Nginx"))
+		Expect(list[1].App).To(Equal(""))
+	})
+	It("returns error with message of api server", func() {
+		serveEmpty()
+		server.RouteToHandler(http.MethodGet, "/apis/apps/v1/deployments", func(resp http.ResponseWriter, req *http.Request) {
+			resp.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(resp, `{"kind":"Status","message":"deployments.apps is forbidden"}`)
+		})
+		_, err := fetch(version.NewClusterInventory(http.DefaultClient, cluster))
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("deployments.apps is forbidden"))
+	})
+	It("parses image reference like the container runtime", func() {
+		Expect(version.ParseImageReference("nginx")).To(Equal(version.ImageReference{Host: "docker.io", Repository: "library/nginx", Tag: "latest"}))
+		Expect(version.ParseImageReference("bborbe/app:1.0")).To(Equal(version.ImageReference{Host: "docker.io", Repository: "bborbe/app", Tag: "1.0"}))
+		Expect(version.ParseImageReference("localhost:5000/app:1.0")).To(Equal(version.ImageReference{Host: "localhost:5000", Repository: "app", Tag: "1.0"}))
+		Expect(version.ParseImageReference("gcr.io/project/app:1.0@sha256:abc")).To(Equal(version.ImageReference{Host: "gcr.io", Repository: "project/app", Tag: "1.0", Digest: "sha256:abc"}))
+		Expect(version.ParseImageReference("quay.io/app@sha256:abc")).To(Equal(version.ImageReference{Host: "quay.io", Repository: "app", Digest: "sha256:abc"}))
+	})
+	Context("fetcher", func() {
+		It("sends deployed versions of inventory", func() {
+			inventory := &mocks.DeploymentFetcher{}
+			inventory.FetchStub = func(ctx context.Context, deployments chan<- avro.ApplicationVersionDeployed) error {
+				deployments <- avro.ApplicationVersionDeployed{Cluster: "prod", Workload: "nginx"}
+				return nil
+			}
+			var sent []avro.ApplicationVersionDeployed
+			sender := &mocks.DeploymentSender{}
+			sender.SendStub = func(ctx context.Context, deployments <-chan avro.ApplicationVersionDeployed) (int, error) {
+				for deployed := range deployments {
+					sent = append(sent, deployed)
+				}
+				return len(sent), nil
+			}
+			versions := make(chan avro.ApplicationVersionAvailable, 1)
+			Expect(version.NewClusterFetcher(inventory, sender).Fetch(context.Background(), versions)).To(BeNil())
+			Expect(sent).To(HaveLen(1))
+			Expect(sent[0].Workload).To(Equal("nginx"))
+			Expect(versions).To(BeEmpty())
+		})
+		It("returns error of sender", func() {
+			inventory := &mocks.DeploymentFetcher{}
+			sender := &mocks.DeploymentSender{}
+			sender.SendReturns(0, errors.New("banana"))
+			versions := make(chan avro.ApplicationVersionAvailable, 1)
+			Expect(version.NewClusterFetcher(inventory, sender).Fetch(context.Background(), versions)).To(HaveOccurred())
+		})
+		It("does not close deployments of failed inventory", func() {
+			inventory := &mocks.DeploymentFetcher{}
+			inventory.FetchReturns(errors.New("banana"))
+			var closed bool
+			sender := &mocks.DeploymentSender{}
+			sender.SendStub = func(ctx context.Context, deployments <-chan avro.ApplicationVersionDeployed) (int, error) {
+				select {
+				case <-ctx.Done():
+				case _, ok := <-deployments:
+					closed = !ok
+				}
+				return 0, nil
+			}
+			versions := make(chan avro.ApplicationVersionAvailable, 1)
+			Expect(version.NewClusterFetcher(inventory, sender).Fetch(context.Background(), versions)).To(HaveOccurred())
+			Expect(closed).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,392 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// SourceTypeRegistry collects tags of a image from a OCI distribution registry.
+const SourceTypeRegistry = "registry"
+
+// SourceTypeDockerHub collects tags of a image from Docker Hub.
+const SourceTypeDockerHub = "dockerhub"
+
+// SourceTypeGitHub collects releases of a GitHub repository.
+const SourceTypeGitHub = "github"
+
+// SourceTypeGitHubTags collects tags of a GitHub repository.
+const SourceTypeGitHubTags = "githubtags"
+
+// SourceTypeHelm collects versions of a chart from a Helm repository.
+const SourceTypeHelm = "helm"
+
+// SourceTypeKubernetes collects Kubernetes releases from the upstream release markers.
+const SourceTypeKubernetes = "kubernetes"
+
+// SourceTypeGit collects tags of a git repository served via http.
+const SourceTypeGit = "git"
+
+// SourceTypeGoProxy collects versions of a Go module from a Go module proxy.
+const SourceTypeGoProxy = "goproxy"
+
+// SourceTypeNpm collects versions of a package from a npm registry.
+const SourceTypeNpm = "npm"
+
+// SourceTypePyPI collects releases of a project from PyPI.
+const SourceTypePyPI = "pypi"
+
+// SourceTypeMaven collects versions of a artifact from a Maven repository.
+const SourceTypeMaven = "maven"
+
+// SourceTypeCrates collects versions of a crate from the crates.io index.
+const SourceTypeCrates = "crates"
+
+// SourceTypeApt collects versions of a package from the Packages index of a Debian repository.
+const SourceTypeApt = "apt"
+
+// SourceTypeRPM collects versions of a package from the repodata of a RPM repository.
+const SourceTypeRPM = "rpm"
+
+// SourceTypeApk collects versions of a package from the APKINDEX of a Alpine repository.
+const SourceTypeApk = "apk"
+
+// SourceTypeCluster publishes the versions deployed in a Kubernetes cluster to the deployed topic.
+const SourceTypeCluster = "cluster"
+
+// Config describes all sources and the sink of the collector.
+type Config struct {
+	Sink        SinkConfig                   `yaml:"sink"`
+	Credentials map[string]CredentialsConfig `yaml:"credentials"`
+	Sources     []SourceConfig               `yaml:"sources"`
+	Webhooks    WebhooksConfig               `yaml:"webhooks"`
+}
+
+// SinkConfig describes where versions are published.
+type SinkConfig struct {
+	KafkaBrokers      string `yaml:"kafkaBrokers"`
+	KafkaTopic        string `yaml:"kafkaTopic"`
+	SchemaRegistryUrl string `yaml:"schemaRegistryUrl"`
+	// DeployedTopic receives the deployed versions of cluster sources.
+	DeployedTopic string `yaml:"deployedTopic"`
+}
+
+// CredentialsConfig contains username and password for a source.
+// PasswordEnv allows to read the password from a environment variable instead of the config file.
+type CredentialsConfig struct {
+	Username    string `yaml:"username"`
+	Password    string `yaml:"password"`
+	PasswordEnv string `yaml:"passwordEnv"`
+}
+
+// SourceConfig describes a single source of versions.
+// URL is the API of GitHub sources, by default https://api.github.com, the Helm repository
+// or the location of the Kubernetes release markers, by default https://dl.k8s.io/release, the git repository
+// or the package registry, by default the public registry of the ecosystem, or the distribution package repository.
+// Kubeconfig, Context and Namespaces select the cluster and namespaces of cluster sources,
+// the cluster the collector runs in and all namespaces if empty.
+// ResolveDigests publishes the tags of registry and dockerhub sources with the digest of their manifest.
+type SourceConfig struct {
+	Name           string         `yaml:"name"`
+	Type           string         `yaml:"type"`
+	Registry       string         `yaml:"registry"`
+	Repository     string         `yaml:"repository"`
+	ResolveDigests bool           `yaml:"resolveDigests"`
+	URL            string         `yaml:"url"`
+	IncludeDrafts  bool           `yaml:"includeDrafts"`
+	Chart          string         `yaml:"chart"`
+	AppVersionApp  string         `yaml:"appVersionApp"`
+	Listing        string         `yaml:"listing"`
+	Minors         int            `yaml:"minors"`
+	Package        string         `yaml:"package"`
+	Kubeconfig     string         `yaml:"kubeconfig"`
+	Context        string         `yaml:"context"`
+	Namespaces     []string       `yaml:"namespaces"`
+	App            string         `yaml:"app"`
+	Credentials    string         `yaml:"credentials"`
+	PageSize       int            `yaml:"pageSize"`
+	MaxPages       int            `yaml:"maxPages"`
+	Schedule       ScheduleConfig `yaml:"schedule"`
+	Filter         FilterConfig   `yaml:"filter"`
+}
+
+// ScheduleConfig defines how often a source is collected.
+// Either the wait duration between two collects or a cron expression with seconds like "0 */5 * * * *".
+type ScheduleConfig struct {
+	Wait time.Duration `yaml:"wait"`
+	Cron string        `yaml:"cron"`
+}
+
+// WebhooksConfig enables the webhook receivers. A webhook without config is disabled.
+type WebhooksConfig struct {
+	Distribution *WebhookConfig `yaml:"distribution"`
+	DockerHub    *WebhookConfig `yaml:"dockerhub"`
+	Harbor       *WebhookConfig `yaml:"harbor"`
+	GitHub       *WebhookConfig `yaml:"github"`
+}
+
+// WebhookConfig contains the shared secret of a webhook receiver
+// and optional apps of repositories not matching a source with the filter of their pushed tags.
+type WebhookConfig struct {
+	Secret    string            `yaml:"secret"`
+	SecretEnv string            `yaml:"secretEnv"`
+	Apps      map[string]string `yaml:"apps"`
+	Filter    FilterConfig      `yaml:"filter"`
+}
+
+// SecretValue returns the secret or reads it from the environment variable.
+func (w WebhookConfig) SecretValue() string {
+	if w.SecretEnv != "" {
+		return os.Getenv(w.SecretEnv)
+	}
+	return w.Secret
+}
+
+// ReadConfig reads the config from the given file.
+func ReadConfig(path string) (*Config, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "open config %s failed", path)
+	}
+	defer file.Close()
+	config, err := ParseConfig(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse config %s failed", path)
+	}
+	return config, nil
+}
+
+// ParseConfig parses the yaml config. Unknown fields are reported as error.
+func ParseConfig(reader io.Reader) (*Config, error) {
+	content, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, errors.Wrap(err, "read config failed")
+	}
+	config := &Config{}
+	if err := yaml.UnmarshalStrict(bytes.TrimSpace(content), config); err != nil {
+		return nil, errors.Wrap(err, "unmarshal yaml failed")
+	}
+	return config, nil
+}
+
+// Validate returns an error describing the first invalid setting.
+func (c *Config) Validate() error {
+	if c.Sink.KafkaBrokers == "" {
+		return errors.New("sink: kafkaBrokers is required")
+	}
+	if c.Sink.KafkaTopic == "" {
+		return errors.New("sink: kafkaTopic is required")
+	}
+	if c.Sink.SchemaRegistryUrl == "" {
+		return errors.New("sink: schemaRegistryUrl is required")
+	}
+	if len(c.Sources) == 0 {
+		return errors.New("sources: at least one source is required")
+	}
+	for name, credentials := range c.Credentials {
+		if credentials.Password != "" && credentials.PasswordEnv != "" {
+			return errors.Errorf("credentials %s: password and passwordEnv are exclusive", name)
+		}
+	}
+	webhooks := c.Webhooks.enabled()
+	for _, name := range webhookNames {
+		webhook, ok := webhooks[name]
+		if !ok {
+			continue
+		}
+		if webhook.Secret != "" && webhook.SecretEnv != "" {
+			return errors.Errorf("webhooks %s: secret and secretEnv are exclusive", name)
+		}
+		if webhook.Secret == "" && webhook.SecretEnv == "" {
+			return errors.Errorf("webhooks %s: secret or secretEnv is required", name)
+		}
+		if webhook.Filter.KeepNewest != 0 {
+			return errors.Errorf("webhooks %s: keepNewest is not supported for pushed tags", name)
+		}
+		if _, err := NewFilter(webhook.Filter); err != nil {
+			return errors.Wrapf(err, "webhooks %s: invalid filter", name)
+		}
+	}
+	names := make(map[string]bool)
+	for i, source := range c.Sources {
+		if err := c.validateSource(source); err != nil {
+			return errors.Wrapf(err, "sources[%d] %s", i, source.Name)
+		}
+		if names[source.Name] {
+			return errors.Errorf("sources[%d] %s: name is not unique", i, source.Name)
+		}
+		names[source.Name] = true
+	}
+	return nil
+}
+
+func (c *Config) validateSource(source SourceConfig) error {
+	if source.Name == "" {
+		return errors.New("name is required")
+	}
+	if source.App == "" {
+		return errors.New("app is required")
+	}
+	if source.Credentials != "" {
+		if _, ok := c.Credentials[source.Credentials]; !ok {
+			return errors.Errorf("credentials %s not defined", source.Credentials)
+		}
+	}
+	if source.PageSize < 0 {
+		return errors.New("pageSize must not be negative")
+	}
+	if source.MaxPages < 0 {
+		return errors.New("maxPages must not be negative")
+	}
+	if err := source.Schedule.Validate(); err != nil {
+		return errors.Wrap(err, "schedule")
+	}
+	if _, err := NewFilter(source.Filter); err != nil {
+		return errors.Wrap(err, "filter")
+	}
+	if source.ResolveDigests && !containsString(registrySourceTypes, source.Type) {
+		return errors.New("resolveDigests is only supported by registry and dockerhub sources")
+	}
+	switch source.Type {
+	case SourceTypeRegistry:
+		if source.Repository == "" {
+			return errors.New("repository is required")
+		}
+		return validateURL("registry", source.Registry)
+	case SourceTypeDockerHub:
+		if source.Repository == "" {
+			return errors.New("repository is required")
+		}
+		if source.Registry != "" {
+			return validateURL("registry", source.Registry)
+		}
+		return nil
+	case SourceTypeGitHub, SourceTypeGitHubTags:
+		if parts := strings.Split(source.Repository, "/"); len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return errors.Errorf("repository %s must be owner/name", source.Repository)
+		}
+		if source.URL != "" {
+			return validateURL("url", source.URL)
+		}
+		return nil
+	case SourceTypeHelm:
+		if source.Chart == "" {
+			return errors.New("chart is required")
+		}
+		return validateURL("url", source.URL)
+	case SourceTypeGit:
+		return validateURL("url", source.URL)
+	case SourceTypeCluster:
+		if c.Sink.DeployedTopic == "" {
+			return errors.New("sink deployedTopic is required")
+		}
+		return nil
+	case SourceTypeApt, SourceTypeRPM, SourceTypeApk:
+		if source.Package == "" {
+			return errors.New("package is required")
+		}
+		return validateURL("url", source.URL)
+	case SourceTypeGoProxy, SourceTypeNpm, SourceTypePyPI, SourceTypeMaven, SourceTypeCrates:
+		if source.Package == "" {
+			return errors.New("package is required")
+		}
+		if source.Type == SourceTypeMaven {
+			if _, err := mavenMetadataPath(source.Package); err != nil {
+				return err
+			}
+		}
+		if source.URL != "" {
+			return validateURL("url", source.URL)
+		}
+		return nil
+	case SourceTypeKubernetes:
+		if source.Minors < 0 {
+			return errors.New("minors must not be negative")
+		}
+		if source.URL != "" {
+			if err := validateURL("url", source.URL); err != nil {
+				return err
+			}
+		}
+		if source.Listing != "" {
+			return validateURL("listing", source.Listing)
+		}
+		return nil
+	case "":
+		return errors.New("type is required")
+	default:
+		return errors.Errorf("unknown type %s", source.Type)
+	}
+}
+
+func validateURL(field string, value string) error {
+	if value == "" {
+		return errors.Errorf("%s is required", field)
+	}
+	u, err := url.Parse(value)
+	if err != nil {
+		return errors.Wrapf(err, "%s is invalid", field)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.Errorf("%s %s must start with http:// or https://", field, value)
+	}
+	if u.Host == "" {
+		return errors.Errorf("%s %s has no host", field, value)
+	}
+	return nil
+}
+
+// CredentialsFor returns the username and password of the credentials referenced by the source.
+func (c *Config) CredentialsFor(source SourceConfig) (string, string) {
+	if source.Credentials == "" {
+		return "", ""
+	}
+	credentials := c.Credentials[source.Credentials]
+	if credentials.PasswordEnv != "" {
+		return credentials.Username, os.Getenv(credentials.PasswordEnv)
+	}
+	return credentials.Username, credentials.Password
+}
+
+// ApplyDefaults sets the given values on all sources they are not defined for.
+func (c *Config) ApplyDefaults(wait time.Duration, pageSize int, maxPages int) {
+	for i := range c.Sources {
+		source := &c.Sources[i]
+		if source.Schedule.Wait == 0 && source.Schedule.Cron == "" {
+			source.Schedule.Wait = wait
+		}
+		if source.PageSize == 0 {
+			source.PageSize = pageSize
+		}
+		if source.MaxPages == 0 {
+			source.MaxPages = maxPages
+		}
+	}
+}
+
+// enabled returns the configured webhooks by name.
+func (w WebhooksConfig) enabled() map[string]WebhookConfig {
+	result := make(map[string]WebhookConfig)
+	for name, webhook := range map[string]*WebhookConfig{
+		WebhookDistribution: w.Distribution,
+		WebhookDockerHub:    w.DockerHub,
+		WebhookHarbor:       w.Harbor,
+		WebhookGitHub:       w.GitHub,
+	} {
+		if webhook != nil {
+			result[name] = *webhook
+		}
+	}
+	return result
+}
@@ -0,0 +1,270 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version_test
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bborbe/kafka-k8s-version-collector/version"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Config", func() {
+	var config *version.Config
+	BeforeEach(func() {
+		var err error
+		config, err = version.ParseConfig(strings.NewReader(`
+sink:
+  kafkaBrokers: kafka:9092
+  kafkaTopic: application-version-available
+  schemaRegistryUrl: http://schema-registry:8081
+credentials:
+  dockerhub:
+    username: bborbe
+    passwordEnv: CONFIG_TEST_PASSWORD
+sources:
+  - name: kubernetes
+    type: registry
+    registry: https://gcr.io
+    repository: google_containers/hyperkube-amd64
+    app: Kubernetes
+    schedule:
+      wait: 5m
+    filter:
+      exclude:
+        - -amd64$
+      constraint: '>=1.10'
+      stableOnly: true
+      keepNewest: 10
+  - name: grafana
+    type: registry
+    registry: https://registry-1.docker.io
+    repository: grafana/grafana
+    app: Grafana
+    credentials: dockerhub
+  - name: nginx
+    type: registry
+    registry: https://registry-1.docker.io
+    repository: library/nginx
+    app: Nginx
+    schedule:
+      cron: 0 */5 * * * *
+webhooks:
+  dockerhub:
+    secretEnv: CONFIG_TEST_WEBHOOK_SECRET
+    apps:
+      bborbe/banana: Banana
+    filter:
+      exclude:
+        - ^master
+`))
+		Expect(err).NotTo(HaveOccurred())
+		config.ApplyDefaults(time.Hour, 100, 10)
+	})
+	It("parses sink", func() {
+		Expect(config.Sink.KafkaBrokers).To(Equal("kafka:9092"))
+		Expect(config.Sink.KafkaTopic).To(Equal("application-version-available"))
+		Expect(config.Sink.SchemaRegistryUrl).To(Equal("http://schema-registry:8081"))
+	})
+	It("parses sources", func() {
+		Expect(config.Sources).To(HaveLen(3))
+		Expect(config.Sources[0].Name).To(Equal("kubernetes"))
+		Expect(config.Sources[0].Type).To(Equal(version.SourceTypeRegistry))
+		Expect(config.Sources[0].Registry).To(Equal("https://gcr.io"))
+		Expect(config.Sources[0].Repository).To(Equal("google_containers/hyperkube-amd64"))
+		Expect(config.Sources[0].App).To(Equal("Kubernetes"))
+		Expect(config.Sources[0].Schedule.Wait).To(Equal(5 * time.Minute))
+		Expect(config.Sources[0].Filter.Exclude).To(Equal([]string{"-amd64$"}))
+		Expect(config.Sources[0].Filter.Constraint).To(Equal(">=1.10"))
+		Expect(config.Sources[0].Filter.StableOnly).To(BeTrue())
+		Expect(config.Sources[0].Filter.KeepNewest).To(Equal(10))
+	})
+	It("applies defaults", func() {
+		Expect(config.Sources[1].Schedule.Wait).To(Equal(time.Hour))
+		Expect(config.Sources[1].PageSize).To(Equal(100))
+		Expect(config.Sources[1].MaxPages).To(Equal(10))
+	})
+	It("keeps cron schedule", func() {
+		Expect(config.Sources[2].Schedule.Cron).To(Equal("0 */5 * * * *"))
+		Expect(config.Sources[2].Schedule.Wait).To(BeZero())
+	})
+	It("is valid", func() {
+		Expect(config.Validate()).To(BeNil())
+	})
+	It("returns credentials of source", func() {
+		os.Setenv("CONFIG_TEST_PASSWORD", "secret")
+		defer os.Unsetenv("CONFIG_TEST_PASSWORD")
+		username, password := config.CredentialsFor(config.Sources[1])
+		Expect(username).To(Equal("bborbe"))
+		Expect(password).To(Equal("secret"))
+	})
+	It("returns no credentials if source has none", func() {
+		username, password := config.CredentialsFor(config.Sources[0])
+		Expect(username).To(BeEmpty())
+		Expect(password).To(BeEmpty())
+	})
+	It("returns error for unknown fields", func() {
+		_, err := version.ParseConfig(strings.NewReader(`
+sources:
+  - name: kubernetes
+    banana: true
+`))
+		Expect(err).To(HaveOccurred())
+	})
+	It("returns error if sink is missing", func() {
+		config.Sink.KafkaTopic = ""
+		Expect(config.Validate()).To(MatchError("sink: kafkaTopic is required"))
+	})
+	It("returns error if no sources", func() {
+		config.Sources = nil
+		Expect(config.Validate()).To(HaveOccurred())
+	})
+	It("returns error if names are not unique", func() {
+		config.Sources[1].Name = "kubernetes"
+		Expect(config.Validate()).To(MatchError("sources[1] kubernetes: name is not unique"))
+	})
+	It("returns error if type is unknown", func() {
+		config.Sources[0].Type = "banana"
+		Expect(config.Validate()).To(MatchError("sources[0] kubernetes: unknown type banana"))
+	})
+	It("returns error if registry is no url", func() {
+		config.Sources[0].Registry = "gcr.io"
+		Expect(config.Validate()).To(HaveOccurred())
+	})
+	It("returns error if credentials are not defined", func() {
+		config.Sources[0].Credentials = "banana"
+		Expect(config.Validate()).To(MatchError("sources[0] kubernetes: credentials banana not defined"))
+	})
+	It("returns error if schedule is invalid", func() {
+		config.Sources[2].Schedule.Cron = "banana"
+		Expect(config.Validate()).To(HaveOccurred())
+	})
+	It("returns error if filter is invalid", func() {
+		config.Sources[0].Filter.Constraint = ">=banana"
+		Expect(config.Validate()).To(HaveOccurred())
+	})
+	It("parses webhooks", func() {
+		Expect(config.Webhooks.Distribution).To(BeNil())
+		Expect(config.Webhooks.DockerHub).NotTo(BeNil())
+		Expect(config.Webhooks.DockerHub.Apps).To(Equal(map[string]string{"bborbe/banana": "Banana"}))
+		Expect(config.Webhooks.DockerHub.Filter.Exclude).To(Equal([]string{"^master"}))
+	})
+	It("returns secret of webhook", func() {
+		os.Setenv("CONFIG_TEST_WEBHOOK_SECRET", "secret")
+		defer os.Unsetenv("CONFIG_TEST_WEBHOOK_SECRET")
+		Expect(config.Webhooks.DockerHub.SecretValue()).To(Equal("secret"))
+	})
+	It("returns error if webhook has no secret", func() {
+		config.Webhooks.DockerHub.SecretEnv = ""
+		Expect(config.Validate()).To(MatchError("webhooks dockerhub: secret or secretEnv is required"))
+	})
+	It("returns error if webhook secret and secretEnv are set", func() {
+		config.Webhooks.DockerHub.Secret = "secret"
+		Expect(config.Validate()).To(MatchError("webhooks dockerhub: secret and secretEnv are exclusive"))
+	})
+	It("returns error if webhook filter keeps newest", func() {
+		config.Webhooks.DockerHub.SecretEnv = "CONFIG_TEST_WEBHOOK_SECRET"
+		config.Webhooks.DockerHub.Filter.KeepNewest = 3
+		Expect(config.Validate()).To(MatchError("webhooks dockerhub: keepNewest is not supported for pushed tags"))
+	})
+	It("returns error if webhook filter is invalid", func() {
+		config.Webhooks.DockerHub.Filter.Exclude = []string{"("}
+		Expect(config.Validate()).To(HaveOccurred())
+	})
+	It("is valid with dockerhub source without registry", func() {
+		config.Sources[2].Type = version.SourceTypeDockerHub
+		config.Sources[2].Registry = ""
+		config.Sources[2].Repository = "nginx"
+		Expect(config.Validate()).To(BeNil())
+	})
+	It("returns error if dockerhub source has no repository", func() {
+		config.Sources[2].Type = version.SourceTypeDockerHub
+		config.Sources[2].Repository = ""
+		Expect(config.Validate()).To(MatchError("sources[2] nginx: repository is required"))
+	})
+	It("is valid with github source", func() {
+		config.Sources[2].Type = version.SourceTypeGitHub
+		config.Sources[2].Repository = "nginx/nginx"
+		Expect(config.Validate()).To(BeNil())
+	})
+	It("returns error if github repository has no owner", func() {
+		config.Sources[2].Type = version.SourceTypeGitHubTags
+		config.Sources[2].Repository = "nginx"
+		Expect(config.Validate()).To(MatchError("sources[2] nginx: repository nginx must be owner/name"))
+	})
+	It("is valid with helm source", func() {
+		config.Sources[2].Type = version.SourceTypeHelm
+		config.Sources[2].URL = "https://kubernetes-charts.storage.googleapis.com"
+		config.Sources[2].Chart = "nginx-ingress"
+		Expect(config.Validate()).To(BeNil())
+	})
+	It("returns error if helm source has no chart", func() {
+		config.Sources[2].Type = version.SourceTypeHelm
+		config.Sources[2].URL = "https://kubernetes-charts.storage.googleapis.com"
+		Expect(config.Validate()).To(MatchError("sources[2] nginx: chart is required"))
+	})
+	It("is valid with kubernetes source without url", func() {
+		config.Sources[2].Type = version.SourceTypeKubernetes
+		config.Sources[2].Minors = 3
+		Expect(config.Validate()).To(BeNil())
+	})
+	It("returns error if kubernetes listing is no url", func() {
+		config.Sources[2].Type = version.SourceTypeKubernetes
+		config.Sources[2].Listing = "banana"
+		Expect(config.Validate()).To(HaveOccurred())
+	})
+	It("is valid with git source", func() {
+		config.Sources[2].Type = version.SourceTypeGit
+		config.Sources[2].URL = "https://git.example.com/group/project.git"
+		Expect(config.Validate()).To(BeNil())
+	})
+	It("returns error if git source has no url", func() {
+		config.Sources[2].Type = version.SourceTypeGit
+		Expect(config.Validate()).To(MatchError("sources[2] nginx: url is required"))
+	})
+	It("is valid with package source without url", func() {
+		config.Sources[2].Type = version.SourceTypeNpm
+		config.Sources[2].Package = "react"
+		Expect(config.Validate()).To(BeNil())
+	})
+	It("returns error if package source has no package", func() {
+		config.Sources[2].Type = version.SourceTypePyPI
+		Expect(config.Validate()).To(MatchError("sources[2] nginx: package is required"))
+	})
+	It("returns error if maven package is no groupId:artifactId", func() {
+		config.Sources[2].Type = version.SourceTypeMaven
+		config.Sources[2].Package = "kafka-clients"
+		Expect(config.Validate()).To(MatchError("sources[2] nginx: package kafka-clients must be groupId:artifactId"))
+	})
+	It("is valid with distribution package source", func() {
+		config.Sources[2].Type = version.SourceTypeApt
+		config.Sources[2].URL = "http://deb.debian.org/debian/dists/stretch/main/binary-amd64"
+		config.Sources[2].Package = "openssl"
+		Expect(config.Validate()).To(BeNil())
+	})
+	It("returns error if distribution package source has no url", func() {
+		config.Sources[2].Type = version.SourceTypeApk
+		config.Sources[2].Package = "openssl"
+		Expect(config.Validate()).To(MatchError("sources[2] nginx: url is required"))
+	})
+	It("is valid with cluster source and deployed topic", func() {
+		config.Sink.DeployedTopic = "application-version-deployed"
+		config.Sources[2].Type = version.SourceTypeCluster
+		Expect(config.Validate()).To(BeNil())
+	})
+	It("returns error if cluster source has no deployed topic", func() {
+		config.Sources[2].Type = version.SourceTypeCluster
+		Expect(config.Validate()).To(MatchError("sources[2] nginx: sink deployedTopic is required"))
+	})
+	It("returns error if resolveDigests is set on other than registry source", func() {
+		config.Sources[2].Type = version.SourceTypeGitHub
+		config.Sources[2].Repository = "nginx/nginx"
+		config.Sources[2].ResolveDigests = true
+		Expect(config.Validate()).To(MatchError("sources[2] nginx: resolveDigests is only supported by registry and dockerhub sources"))
+	})
+})
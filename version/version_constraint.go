@@ -0,0 +1,125 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Constraint is a semantic version range like ">=1.10 <2.0".
+// Terms separated by space must all match, alternatives are separated by "||".
+// Like npm, prereleases only match alternatives that contain a prerelease themselves.
+type Constraint [][]constraintTerm
+
+type constraintTerm struct {
+	operator string
+	version  SemVer
+}
+
+var constraintOperators = []string{">=", "<=", "!=", ">", "<", "=", "~", "^"}
+
+// ParseConstraint parses a constraint like ">=1.10 <2.0 || ~3.1".
+// Supported operators are =, !=, >, >=, <, <=, ~ (same minor) and ^ (same major).
+func ParseConstraint(value string) (Constraint, error) {
+	var result Constraint
+	for _, alternative := range strings.Split(value, "||") {
+		fields := strings.Fields(alternative)
+		if len(fields) == 0 {
+			return nil, errors.Errorf("parse constraint '%s' failed, empty alternative", value)
+		}
+		var terms []constraintTerm
+		for _, field := range fields {
+			term, err := parseConstraintTerm(field)
+			if err != nil {
+				return nil, errors.Wrapf(err, "parse constraint '%s' failed", value)
+			}
+			terms = append(terms, *term)
+		}
+		result = append(result, terms)
+	}
+	return result, nil
+}
+
+func parseConstraintTerm(value string) (*constraintTerm, error) {
+	operator := "="
+	for _, op := range constraintOperators {
+		if strings.HasPrefix(value, op) {
+			operator = op
+			value = value[len(op):]
+			break
+		}
+	}
+	semVer, err := ParseSemVer(value)
+	if err != nil {
+		return nil, err
+	}
+	return &constraintTerm{
+		operator: operator,
+		version:  *semVer,
+	}, nil
+}
+
+// Matches returns true if the version matches at least one alternative of the constraint.
+func (c Constraint) Matches(semVer SemVer) bool {
+	return c.matches(semVer, !semVer.Stable())
+}
+
+// matches returns true if the version matches at least one alternative of the constraint.
+// A version released as prerelease is matched like a version with prerelease.
+func (c Constraint) matches(semVer SemVer, prerelease bool) bool {
+	for _, terms := range c {
+		if matchesAll(terms, semVer, prerelease) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesAll(terms []constraintTerm, semVer SemVer, prerelease bool) bool {
+	if prerelease && !containsPrerelease(terms) {
+		return false
+	}
+	for _, term := range terms {
+		if !term.matches(semVer) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsPrerelease(terms []constraintTerm) bool {
+	for _, term := range terms {
+		if !term.version.Stable() {
+			return true
+		}
+	}
+	return false
+}
+
+func (t constraintTerm) matches(semVer SemVer) bool {
+	compare := semVer.Compare(t.version)
+	switch t.operator {
+	case "=":
+		return compare == 0
+	case "!=":
+		return compare != 0
+	case ">":
+		return compare > 0
+	case ">=":
+		return compare >= 0
+	case "<":
+		return compare < 0
+	case "<=":
+		return compare <= 0
+	case "~":
+		return compare >= 0 && semVer.Major == t.version.Major && semVer.Minor == t.version.Minor
+	case "^":
+		return compare >= 0 && semVer.Major == t.version.Major
+	default:
+		return false
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version_test
+
+import (
+	"github.com/bborbe/kafka-k8s-version-collector/version"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Constraint", func() {
+	for _, tc := range []struct {
+		constraint string
+		version    string
+		expected   bool
+	}{
+		{">=1.10 <2.0", "1.10.0", true},
+		{">=1.10 <2.0", "v1.13.4", true},
+		{">=1.10 <2.0", "1.9.9", false},
+		{">=1.10 <2.0", "2.0.0", false},
+		{"1.2.3", "1.2.3", true},
+		{"!=1.2.3", "1.2.3", false},
+		{">1.2.3", "1.2.4", true},
+		{"<=1.2.3", "1.2.3", true},
+		{"~1.2", "1.2.9", true},
+		{"~1.2", "1.3.0", false},
+		{"^1.2", "1.9.0", true},
+		{"^1.2", "2.0.0", false},
+		{"<1.0 || >=2.0", "0.9.0", true},
+		{"<1.0 || >=2.0", "1.5.0", false},
+		{"<1.0 || >=2.0", "2.1.0", true},
+		{"<2.0", "2.0.0-rc.1", false},
+		{">=2.0.0-rc.0 <2.0", "2.0.0-rc.1", true},
+	} {
+		tc := tc
+		It("matches "+tc.version+" against "+tc.constraint, func() {
+			constraint, err := version.ParseConstraint(tc.constraint)
+			Expect(err).NotTo(HaveOccurred())
+			semVer, err := version.ParseSemVer(tc.version)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(constraint.Matches(*semVer)).To(Equal(tc.expected))
+		})
+	}
+	for _, value := range []string{"", ">=banana", ">=1.0 ||", "=>1.0"} {
+		value := value
+		It("returns error for '"+value+"'", func() {
+			_, err := version.ParseConstraint(value)
+			Expect(err).To(HaveOccurred())
+		})
+	}
+})
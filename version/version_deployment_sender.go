@@ -0,0 +1,163 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Shopify/sarama"
+	"github.com/bborbe/kafka-k8s-version-collector/avro"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	"github.com/seibert-media/go-kafka/schema"
+)
+
+//go:generate counterfeiter -o ../mocks/deployment_sender.go --fake-name DeploymentSender . DeploymentSender
+type DeploymentSender interface {
+	// Send publishes the deployed versions until the channel is closed and returns the number of published versions.
+	Send(ctx context.Context, deployments <-chan avro.ApplicationVersionDeployed) (int, error)
+}
+
+// NewDeploymentSender returns a DeploymentSender that publishes deployed versions
+// keyed by cluster, kind, namespace, workload and container.
+// A deployed version is only published again once it changed, unless force is true.
+// Published versions are remembered in memory, all are published again after a restart.
+// Once the channel is closed, published versions of the sent clusters that were not sent again are deleted
+// with a tombstone, a message with key and without value.
+func NewDeploymentSender(
+	producer sarama.SyncProducer,
+	schemaRegistry schema.Registry,
+	kafkaTopic string,
+	force bool,
+) DeploymentSender {
+	return &deploymentSender{
+		producer:       producer,
+		schemaRegistry: schemaRegistry,
+		kafkaTopic:     kafkaTopic,
+		force:          force,
+		published:      make(map[string]avro.ApplicationVersionDeployed),
+	}
+}
+
+type deploymentSender struct {
+	producer       sarama.SyncProducer
+	schemaRegistry schema.Registry
+	kafkaTopic     string
+	force          bool
+
+	mux       sync.Mutex
+	published map[string]avro.ApplicationVersionDeployed
+}
+
+// DeploymentKey returns the kafka key of a deployed version.
+func DeploymentKey(deployed avro.ApplicationVersionDeployed) string {
+	return strings.Join([]string{deployed.Cluster, deployed.Kind, deployed.Namespace, deployed.Workload, deployed.Container}, "/")
+}
+
+func (d *deploymentSender) Send(ctx context.Context, deployments <-chan avro.ApplicationVersionDeployed) (int, error) {
+	var published int
+	seen := make(map[string]bool)
+	clusters := make(map[string]bool)
+	for {
+		select {
+		case <-ctx.Done():
+			glog.V(3).Infof("context done => return")
+			return published, nil
+		case deployed, ok := <-deployments:
+			if !ok {
+				if ctx.Err() != nil {
+					glog.V(3).Infof("context done => return")
+					return published, nil
+				}
+				deleted, err := d.deleteGone(ctx, clusters, seen)
+				published += deleted
+				if err != nil {
+					return published, err
+				}
+				glog.V(3).Infof("channel closed => return")
+				return published, nil
+			}
+			key := DeploymentKey(deployed)
+			seen[key] = true
+			clusters[deployed.Cluster] = true
+			if !d.force && d.unchanged(key, deployed) {
+				glog.V(4).Infof("deployed version %s unchanged => skip", key)
+				continue
+			}
+			schemaId, err := d.schemaRegistry.SchemaId(fmt.Sprintf("%s-value", d.kafkaTopic), deployed.Schema())
+			schemaRegistryLookupsCounter.WithLabelValues(resultLabel(err)).Inc()
+			if err != nil {
+				return published, errors.Wrap(err, "get schema id failed")
+			}
+			buf := &bytes.Buffer{}
+			if err := deployed.Serialize(buf); err != nil {
+				return published, errors.Wrap(err, "serialize deployed version failed")
+			}
+			partition, offset, err := sendMessage(ctx, d.producer, &sarama.ProducerMessage{
+				Topic: d.kafkaTopic,
+				Key:   sarama.StringEncoder(key),
+				Value: &schema.AvroEncoder{SchemaId: schemaId, Content: buf.Bytes()},
+			})
+			if err != nil {
+				return published, errors.Wrap(err, "send message to kafka failed")
+			}
+			deploymentsPublishedCounter.WithLabelValues(deployed.Cluster).Inc()
+			published++
+			glog.V(3).Infof("send message successful to %s with partition %d offset %d", d.kafkaTopic, partition, offset)
+			d.mux.Lock()
+			d.published[key] = deployed
+			d.mux.Unlock()
+		}
+	}
+}
+
+// unchanged returns true if the deployed version was already published.
+func (d *deploymentSender) unchanged(key string, deployed avro.ApplicationVersionDeployed) bool {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	published, ok := d.published[key]
+	return ok && published == deployed
+}
+
+// deleteGone publishes a tombstone for each published version of the clusters not seen and returns the number of tombstones.
+func (d *deploymentSender) deleteGone(ctx context.Context, clusters map[string]bool, seen map[string]bool) (int, error) {
+	var deleted int
+	for _, key := range d.gone(clusters, seen) {
+		partition, offset, err := sendMessage(ctx, d.producer, &sarama.ProducerMessage{
+			Topic: d.kafkaTopic,
+			Key:   sarama.StringEncoder(key),
+		})
+		if err != nil {
+			return deleted, errors.Wrap(err, "send tombstone to kafka failed")
+		}
+		d.mux.Lock()
+		cluster := d.published[key].Cluster
+		delete(d.published, key)
+		d.mux.Unlock()
+		deploymentsDeletedCounter.WithLabelValues(cluster).Inc()
+		deleted++
+		glog.V(3).Infof("send tombstone of %s successful to %s with partition %d offset %d", key, d.kafkaTopic, partition, offset)
+	}
+	return deleted, nil
+}
+
+// gone returns the sorted keys of published versions of the clusters that were not seen.
+func (d *deploymentSender) gone(clusters map[string]bool, seen map[string]bool) []string {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	var result []string
+	for key, published := range d.published {
+		if clusters[published.Cluster] && !seen[key] {
+			result = append(result, key)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,155 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version_test
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Shopify/sarama"
+	mocksmocks "github.com/Shopify/sarama/mocks"
+	"github.com/bborbe/kafka-k8s-version-collector/avro"
+	"github.com/bborbe/kafka-k8s-version-collector/version"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	gokafkamocks "github.com/seibert-media/go-kafka/mocks"
+)
+
+// recordingSyncProducer records the messages sent to the mock producer.
+type recordingSyncProducer struct {
+	*mocksmocks.SyncProducer
+	messages []*sarama.ProducerMessage
+}
+
+func (r *recordingSyncProducer) SendMessage(msg *sarama.ProducerMessage) (int32, int64, error) {
+	r.messages = append(r.messages, msg)
+	return r.SyncProducer.SendMessage(msg)
+}
+
+var _ = Describe("Deployment Sender", func() {
+	var producer *mocksmocks.SyncProducer
+	var schemaRegistry *gokafkamocks.SchemaRegistry
+	var deployed avro.ApplicationVersionDeployed
+	BeforeEach(func() {
+		var t GinkgoTestReporter
+		producer = mocksmocks.NewSyncProducer(t, nil)
+		schemaRegistry = &gokafkamocks.SchemaRegistry{}
+		deployed = avro.ApplicationVersionDeployed{
+			Cluster:   "prod",
+			Kind:      version.KindDeployment,
+			Namespace: "default",
+			Workload:  "nginx",
+			Container: "nginx",
+			Tag:       "1.15.8",
+		}
+	})
+	send := func(sender version.DeploymentSender, list ...avro.ApplicationVersionDeployed) (int, error) {
+		deployments := make(chan avro.ApplicationVersionDeployed, len(list))
+		for _, d := range list {
+			deployments <- d
+		}
+		close(deployments)
+		return sender.Send(context.Background(), deployments)
+	}
+	It("returns key of deployed version", func() {
+		Expect(version.DeploymentKey(deployed)).To(Equal("prod/Deployment/default/nginx/nginx"))
+	})
+	It("sends deployed version to deployed topic", func() {
+		producer.ExpectSendMessageAndSucceed()
+		published, err := send(version.NewDeploymentSender(producer, schemaRegistry, "my-deployed-topic", false), deployed)
+		Expect(err).To(BeNil())
+		Expect(published).To(Equal(1))
+		subject, _ := schemaRegistry.SchemaIdArgsForCall(0)
+		Expect(subject).To(Equal("my-deployed-topic-value"))
+	})
+	It("skips unchanged deployed version", func() {
+		producer.ExpectSendMessageAndSucceed()
+		producer.ExpectSendMessageAndSucceed()
+		sender := version.NewDeploymentSender(producer, schemaRegistry, "my-deployed-topic", false)
+		published, err := send(sender, deployed)
+		Expect(err).To(BeNil())
+		Expect(published).To(Equal(1))
+		published, err = send(sender, deployed)
+		Expect(err).To(BeNil())
+		Expect(published).To(Equal(0))
+		deployed.Tag = "1.15.9"
+		published, err = send(sender, deployed)
+		Expect(err).To(BeNil())
+		Expect(published).To(Equal(1))
+	})
+	It("sends unchanged deployed version if forced", func() {
+		producer.ExpectSendMessageAndSucceed()
+		producer.ExpectSendMessageAndSucceed()
+		sender := version.NewDeploymentSender(producer, schemaRegistry, "my-deployed-topic", true)
+		published, err := send(sender, deployed, deployed)
+		Expect(err).To(BeNil())
+		Expect(published).To(Equal(2))
+	})
+	It("returns error if schema registry fails", func() {
+		schemaRegistry.SchemaIdReturns(0, errors.New("banana"))
+		_, err := send(version.NewDeploymentSender(producer, schemaRegistry, "my-deployed-topic", false), deployed)
+		Expect(err).To(HaveOccurred())
+	})
+	It("sends tombstone of deployed version gone", func() {
+		gone := deployed
+		gone.Workload = "apache"
+		producer.ExpectSendMessageAndSucceed()
+		producer.ExpectSendMessageAndSucceed()
+		producer.ExpectSendMessageAndSucceed()
+		recording := &recordingSyncProducer{SyncProducer: producer}
+		sender := version.NewDeploymentSender(recording, schemaRegistry, "my-deployed-topic", false)
+		published, err := send(sender, deployed, gone)
+		Expect(err).To(BeNil())
+		Expect(published).To(Equal(2))
+		published, err = send(sender, deployed)
+		Expect(err).To(BeNil())
+		Expect(published).To(Equal(1))
+		Expect(recording.messages).To(HaveLen(3))
+		Expect(recording.messages[2].Key).To(Equal(sarama.StringEncoder("prod/Deployment/default/apache/nginx")))
+		Expect(recording.messages[2].Value).To(BeNil())
+		published, err = send(sender, deployed)
+		Expect(err).To(BeNil())
+		Expect(published).To(Equal(0))
+	})
+	It("keeps deployed versions of other clusters", func() {
+		other := deployed
+		other.Cluster = "dev"
+		producer.ExpectSendMessageAndSucceed()
+		producer.ExpectSendMessageAndSucceed()
+		sender := version.NewDeploymentSender(producer, schemaRegistry, "my-deployed-topic", false)
+		published, err := send(sender, deployed, other)
+		Expect(err).To(BeNil())
+		Expect(published).To(Equal(2))
+		published, err = send(sender, deployed)
+		Expect(err).To(BeNil())
+		Expect(published).To(Equal(0))
+	})
+	It("sends no tombstone if context is done", func() {
+		gone := deployed
+		gone.Workload = "apache"
+		producer.ExpectSendMessageAndSucceed()
+		producer.ExpectSendMessageAndSucceed()
+		sender := version.NewDeploymentSender(producer, schemaRegistry, "my-deployed-topic", false)
+		_, err := send(sender, deployed, gone)
+		Expect(err).To(BeNil())
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		deployments := make(chan avro.ApplicationVersionDeployed)
+		close(deployments)
+		published, err := sender.Send(ctx, deployments)
+		Expect(err).To(BeNil())
+		Expect(published).To(Equal(0))
+	})
+	It("publishes failed deployed version again", func() {
+		producer.ExpectSendMessageAndFail(errors.New("banana"))
+		producer.ExpectSendMessageAndSucceed()
+		sender := version.NewDeploymentSender(producer, schemaRegistry, "my-deployed-topic", false)
+		_, err := send(sender, deployed)
+		Expect(err).To(HaveOccurred())
+		published, err := send(sender, deployed)
+		Expect(err).To(BeNil())
+		Expect(published).To(Equal(1))
+	})
+})
@@ -0,0 +1,98 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"context"
+	"net/http"
+	"runtime"
+
+	"github.com/bborbe/kafka-k8s-version-collector/avro"
+	"github.com/bborbe/run"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+// NewDigestResolver returns a Filter setting digest, media type and platforms of the tags passing the given filter.
+// The platforms of a manifest list are only downloaded if its digest is new, otherwise the published ones are kept.
+func NewDigestResolver(
+	filter Filter,
+	httpClient *http.Client,
+	image Image,
+	store Store,
+) Filter {
+	return &digestResolver{
+		filter:     filter,
+		httpClient: httpClient,
+		image:      image,
+		store:      store,
+	}
+}
+
+type digestResolver struct {
+	filter     Filter
+	httpClient *http.Client
+	image      Image
+	store      Store
+}
+
+func (d *digestResolver) Filter(ctx context.Context, in <-chan avro.ApplicationVersionAvailable, out chan<- avro.ApplicationVersionAvailable) error {
+	filtered := make(chan avro.ApplicationVersionAvailable, runtime.NumCPU())
+	return run.CancelOnFirstError(
+		ctx,
+		func(ctx context.Context) error {
+			defer close(filtered)
+			return d.filter.Filter(ctx, in, filtered)
+		},
+		func(ctx context.Context) error {
+			for version := range filtered {
+				if err := d.resolve(ctx, &version); err != nil {
+					if ctx.Err() != nil {
+						glog.Infof("context done => return")
+						return nil
+					}
+					return errors.Wrapf(err, "resolve manifest of tag %s failed", version.Version)
+				}
+				select {
+				case <-ctx.Done():
+					glog.Infof("context done => return")
+					return nil
+				case out <- version:
+				}
+			}
+			return nil
+		},
+	)
+}
+
+func (d *digestResolver) resolve(ctx context.Context, version *avro.ApplicationVersionAvailable) error {
+	stored, ok, err := d.store.Get(version.App, version.Version)
+	if err != nil {
+		return errors.Wrap(err, "get version from store failed")
+	}
+	manifest, err := ResolveManifest(ctx, d.httpClient, d.image, version.Version)
+	if err != nil {
+		return err
+	}
+	if manifest == nil {
+		glog.V(2).Infof("manifest of tag %s not found => publish without digest", version.Version)
+		return nil
+	}
+	version.Digest = manifest.Digest
+	version.MediaType = manifest.MediaType
+	switch {
+	case ok && stored.Digest == manifest.Digest:
+		version.Platforms = stored.Platforms
+	case manifest.Platforms != nil:
+		version.Platforms = manifest.Platforms
+	case isManifestList(manifest.MediaType):
+		platforms, err := ManifestPlatforms(ctx, d.httpClient, d.image, manifest.Digest)
+		if err != nil {
+			return errors.Wrap(err, "get platforms failed")
+		}
+		version.Platforms = platforms
+	}
+	return nil
+}
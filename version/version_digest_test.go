@@ -0,0 +1,121 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/bborbe/kafka-k8s-version-collector/avro"
+	"github.com/bborbe/kafka-k8s-version-collector/mocks"
+	"github.com/bborbe/kafka-k8s-version-collector/version"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+)
+
+var _ = Describe("Digest Resolver", func() {
+	var server *ghttp.Server
+	var store *mocks.Store
+	var image version.Image
+	BeforeEach(func() {
+		server = ghttp.NewServer()
+		server.AllowUnhandledRequests = true
+		server.UnhandledRequestStatusCode = http.StatusNotFound
+		store = &mocks.Store{}
+		image = version.Image{
+			Registry:   server.URL(),
+			Repository: "library/nginx",
+			App:        "Nginx",
+		}
+		server.RouteToHandler(http.MethodHead, "/v2/library/nginx/manifests/1.15", func(resp http.ResponseWriter, req *http.Request) {
+			resp.Header().Set("Content-Type", version.MediaTypeDockerManifestList)
+			resp.Header().Set("Docker-Content-Digest", "sha256:list")
+		})
+		server.RouteToHandler(http.MethodGet, "/v2/library/nginx/manifests/sha256:list", func(resp http.ResponseWriter, req *http.Request) {
+			resp.Header().Set("Content-Type", version.MediaTypeDockerManifestList)
+			fmt.Fprint(resp, manifestList)
+		})
+	})
+	AfterEach(func() {
+		server.Close()
+	})
+	resolve := func(tags ...string) ([]avro.ApplicationVersionAvailable, error) {
+		filter, err := version.NewFilter(version.FilterConfig{Exclude: []string{"^master"}})
+		Expect(err).NotTo(HaveOccurred())
+		in := make(chan avro.ApplicationVersionAvailable, len(tags))
+		for _, tag := range tags {
+			in <- version.NewApplicationVersionAvailable("Nginx", tag)
+		}
+		close(in)
+		out := make(chan avro.ApplicationVersionAvailable, len(tags))
+		err = version.NewDigestResolver(filter, http.DefaultClient, image, store).Filter(context.Background(), in, out)
+		close(out)
+		var list []avro.ApplicationVersionAvailable
+		for v := range out {
+			list = append(list, v)
+		}
+		return list, err
+	}
+	requests := func(method string) int {
+		var counter int
+		for _, req := range server.ReceivedRequests() {
+			if req.Method == method {
+				counter++
+			}
+		}
+		return counter
+	}
+	It("resolves digest and platforms of new tags passing the filter", func() {
+		list, err := resolve("1.15", "master-1234")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(list).To(HaveLen(1))
+		Expect(list[0].Digest).To(Equal("sha256:list"))
+		Expect(list[0].MediaType).To(Equal(version.MediaTypeDockerManifestList))
+		Expect(list[0].Platforms).To(Equal([]string{"linux/amd64", "linux/arm/v7"}))
+		Expect(requests(http.MethodHead)).To(Equal(1))
+		Expect(requests(http.MethodGet)).To(Equal(1))
+	})
+	It("keeps platforms of published digest without download", func() {
+		store.GetReturns(avro.ApplicationVersionAvailable{App: "Nginx", Version: "1.15", Digest: "sha256:list", Platforms: []string{"linux/amd64"}}, true, nil)
+		list, err := resolve("1.15")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(list).To(HaveLen(1))
+		Expect(list[0].Digest).To(Equal("sha256:list"))
+		Expect(list[0].Platforms).To(Equal([]string{"linux/amd64"}))
+		Expect(requests(http.MethodGet)).To(Equal(0))
+	})
+	It("downloads platforms once digest changed", func() {
+		store.GetReturns(avro.ApplicationVersionAvailable{App: "Nginx", Version: "1.15", Digest: "sha256:old", Platforms: []string{"linux/amd64"}}, true, nil)
+		list, err := resolve("1.15")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(list).To(HaveLen(1))
+		Expect(list[0].Digest).To(Equal("sha256:list"))
+		Expect(list[0].Platforms).To(Equal([]string{"linux/amd64", "linux/arm/v7"}))
+		Expect(requests(http.MethodGet)).To(Equal(1))
+	})
+	It("resolves tags published without digest", func() {
+		store.GetReturns(avro.ApplicationVersionAvailable{App: "Nginx", Version: "1.15"}, true, nil)
+		list, err := resolve("1.15")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(list).To(HaveLen(1))
+		Expect(list[0].Digest).To(Equal("sha256:list"))
+	})
+	It("passes tag without digest if manifest does not exist", func() {
+		list, err := resolve("banana")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(list).To(HaveLen(1))
+		Expect(list[0].Version).To(Equal("banana"))
+		Expect(list[0].Digest).To(Equal(""))
+	})
+	It("returns error if registry fails", func() {
+		server.RouteToHandler(http.MethodHead, "/v2/library/nginx/manifests/1.15", func(resp http.ResponseWriter, req *http.Request) {
+			resp.WriteHeader(http.StatusInternalServerError)
+		})
+		_, err := resolve("1.15")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,348 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bborbe/kafka-k8s-version-collector/avro"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+// Ecosystems of distribution package versions, named after the package format.
+const (
+	EcosystemDebian = "Debian"
+	EcosystemRPM    = "RPM"
+	EcosystemAlpine = "Alpine"
+)
+
+// DistroPackage describes a package of a distribution package repository.
+type DistroPackage struct {
+	// URL of the repository, the directory containing the Packages index for APT,
+	// the directory containing repodata for RPM and the directory containing APKINDEX.tar.gz for Alpine.
+	URL  string
+	Name string
+	App  string
+}
+
+// NewAptFetcher returns a Fetcher reading the versions of a package from the Packages.gz index of a Debian repository,
+// like http://deb.debian.org/debian/dists/stretch/main/binary-amd64.
+func NewAptFetcher(httpClient *http.Client, pkg DistroPackage) Fetcher {
+	return &distroFetcher{
+		httpClient: httpClient,
+		pkg:        pkg,
+		ecosystem:  EcosystemDebian,
+		versions:   aptVersions,
+	}
+}
+
+// NewRPMFetcher returns a Fetcher reading the versions of a package from the primary repodata of a RPM repository,
+// like http://mirror.centos.org/centos/7/os/x86_64.
+func NewRPMFetcher(httpClient *http.Client, pkg DistroPackage) Fetcher {
+	return &distroFetcher{
+		httpClient: httpClient,
+		pkg:        pkg,
+		ecosystem:  EcosystemRPM,
+		versions:   rpmVersions,
+	}
+}
+
+// NewApkFetcher returns a Fetcher reading the versions of a package from the APKINDEX.tar.gz of a Alpine repository,
+// like http://dl-cdn.alpinelinux.org/alpine/v3.9/main/x86_64.
+func NewApkFetcher(httpClient *http.Client, pkg DistroPackage) Fetcher {
+	return &distroFetcher{
+		httpClient: httpClient,
+		pkg:        pkg,
+		ecosystem:  EcosystemAlpine,
+		versions:   apkVersions,
+	}
+}
+
+// distroVersion is a version of a package found in a index with its optional build time.
+type distroVersion struct {
+	Version   string
+	BuildTime time.Time
+}
+
+type distroFetcher struct {
+	httpClient *http.Client
+	pkg        DistroPackage
+	ecosystem  string
+	versions   func(ctx context.Context, d *distroFetcher) ([]distroVersion, error)
+}
+
+func (d *distroFetcher) Fetch(ctx context.Context, versions chan<- avro.ApplicationVersionAvailable) error {
+	found, err := d.versions(ctx, d)
+	if err != nil {
+		return errors.Wrapf(err, "read %s package %s failed", d.ecosystem, d.pkg.Name)
+	}
+	if len(found) == 0 {
+		return errors.Errorf("%s package %s not found in %s", d.ecosystem, d.pkg.Name, d.pkg.URL)
+	}
+	list := make([]avro.ApplicationVersionAvailable, 0, len(found))
+	seen := make(map[string]bool)
+	for _, version := range found {
+		if seen[version.Version] {
+			continue
+		}
+		seen[version.Version] = true
+		list = append(list, NewDistroVersion(d.pkg.App, d.ecosystem, version.Version, version.BuildTime))
+	}
+	SortVersions(list)
+	for _, version := range list {
+		select {
+		case <-ctx.Done():
+			glog.Infof("context done => return")
+			return nil
+		case versions <- version:
+		}
+	}
+	return nil
+}
+
+// NewDistroVersion returns the version of a distribution package.
+// Distribution versions are not parsed as semantic version, they are ordered by the rules of the ecosystem.
+func NewDistroVersion(app string, ecosystem string, version string, buildTime time.Time) avro.ApplicationVersionAvailable {
+	result := *avro.NewApplicationVersionAvailable()
+	result.App = app
+	result.Version = version
+	result.Ecosystem = ecosystem
+	result.Stable = distroVersionStable(ecosystem, version)
+	if !buildTime.IsZero() {
+		result.PublishedAt = buildTime.UnixNano() / int64(time.Millisecond)
+	}
+	return result
+}
+
+// open returns the body of the path in the repository, gzip compressed content is decompressed.
+func (d *distroFetcher) open(ctx context.Context, path string) (io.ReadCloser, bool, error) {
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(d.pkg.URL, "/")+"/"+path, nil)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "build request failed")
+	}
+	req = req.WithContext(ctx)
+	glog.V(1).Infof("%s %s", req.Method, req.URL.String())
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "request failed")
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, false, nil
+	}
+	if resp.StatusCode/100 != 2 {
+		resp.Body.Close()
+		return nil, false, errors.Errorf("request %s status code %d != 2xx", path, resp.StatusCode)
+	}
+	reader := bufio.NewReader(resp.Body)
+	if magic, err := reader.Peek(2); err != nil || !bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		return &readCloser{Reader: reader, Closer: resp.Body}, true, nil
+	}
+	gzipReader, err := gzip.NewReader(reader)
+	if err != nil {
+		resp.Body.Close()
+		return nil, false, errors.Wrapf(err, "decompress %s failed", path)
+	}
+	return &readCloser{Reader: gzipReader, Closer: resp.Body}, true, nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// aptVersions reads Packages.gz, or Packages if the repository has no compressed index.
+func aptVersions(ctx context.Context, d *distroFetcher) ([]distroVersion, error) {
+	for _, path := range []string{"Packages.gz", "Packages"} {
+		body, found, err := d.open(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			continue
+		}
+		defer body.Close()
+		return parseStanzas(body, d.pkg.Name, "Package", "Version", "")
+	}
+	return nil, errors.New("Packages index not found")
+}
+
+// apkVersions reads the APKINDEX file of APKINDEX.tar.gz.
+// The index follows the signature, which is a separate gzip stream and may be a complete tar archive of its own.
+func apkVersions(ctx context.Context, d *distroFetcher) ([]distroVersion, error) {
+	body, found, err := d.open(ctx, "APKINDEX.tar.gz")
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, errors.New("APKINDEX.tar.gz not found")
+	}
+	defer body.Close()
+	for {
+		reader := tar.NewReader(body)
+		entries := 0
+		for {
+			header, err := reader.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, errors.Wrap(err, "read archive failed")
+			}
+			entries++
+			if header.Name == "APKINDEX" {
+				return parseStanzas(reader, d.pkg.Name, "P", "V", "t")
+			}
+		}
+		if entries == 0 {
+			return nil, errors.New("APKINDEX not found in archive")
+		}
+	}
+}
+
+// parseStanzas returns the versions of the package in a index of blank line separated stanzas with key: value lines,
+// like the Debian Packages index or the APKINDEX. The build time is read from the optional timeKey in unix seconds.
+func parseStanzas(r io.Reader, name string, nameKey string, versionKey string, timeKey string) ([]distroVersion, error) {
+	var result []distroVersion
+	fields := make(map[string]string)
+	flush := func() {
+		if fields[nameKey] == name && fields[versionKey] != "" {
+			version := distroVersion{Version: fields[versionKey]}
+			if seconds, err := strconv.ParseInt(fields[timeKey], 10, 64); err == nil {
+				version.BuildTime = time.Unix(seconds, 0).UTC()
+			}
+			result = append(result, version)
+		}
+		fields = make(map[string]string)
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+		// continuation lines of multi line fields start with a space
+		if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			continue
+		}
+		if pos := strings.IndexByte(line, ':'); pos != -1 {
+			fields[line[:pos]] = strings.TrimSpace(line[pos+1:])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "read index failed")
+	}
+	flush()
+	return result, nil
+}
+
+type rpmRepomd struct {
+	Data []struct {
+		Type     string `xml:"type,attr"`
+		Location struct {
+			Href string `xml:"href,attr"`
+		} `xml:"location"`
+	} `xml:"data"`
+}
+
+type rpmPackage struct {
+	Name    string `xml:"name"`
+	Version struct {
+		Epoch   string `xml:"epoch,attr"`
+		Version string `xml:"ver,attr"`
+		Release string `xml:"rel,attr"`
+	} `xml:"version"`
+	Time struct {
+		Build int64 `xml:"build,attr"`
+	} `xml:"time"`
+}
+
+// rpmVersions reads repodata/repomd.xml to find the primary metadata and reads the versions from it.
+func rpmVersions(ctx context.Context, d *distroFetcher) ([]distroVersion, error) {
+	body, found, err := d.open(ctx, "repodata/repomd.xml")
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, errors.New("repodata/repomd.xml not found")
+	}
+	var repomd rpmRepomd
+	err = xml.NewDecoder(body).Decode(&repomd)
+	body.Close()
+	if err != nil {
+		return nil, errors.Wrap(err, "decode repomd.xml failed")
+	}
+	var primary string
+	for _, data := range repomd.Data {
+		if data.Type == "primary" {
+			primary = data.Location.Href
+		}
+	}
+	if primary == "" {
+		return nil, errors.New("primary metadata not found in repomd.xml")
+	}
+	body, found, err = d.open(ctx, primary)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, errors.Errorf("%s not found", primary)
+	}
+	defer body.Close()
+	return parseRPMPrimary(body, d.pkg.Name)
+}
+
+// parseRPMPrimary returns the [epoch:]version-release of each package with the name.
+// The primary metadata is decoded package by package, it lists all packages of the repository.
+func parseRPMPrimary(r io.Reader, name string) ([]distroVersion, error) {
+	var result []distroVersion
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "decode primary metadata failed")
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "package" {
+			continue
+		}
+		var pkg rpmPackage
+		if err := decoder.DecodeElement(&pkg, &start); err != nil {
+			return nil, errors.Wrap(err, "decode package failed")
+		}
+		if pkg.Name != name || pkg.Version.Version == "" {
+			continue
+		}
+		version := pkg.Version.Version
+		if pkg.Version.Release != "" {
+			version += "-" + pkg.Version.Release
+		}
+		if pkg.Version.Epoch != "" && pkg.Version.Epoch != "0" {
+			version = pkg.Version.Epoch + ":" + version
+		}
+		var buildTime time.Time
+		if pkg.Time.Build > 0 {
+			buildTime = time.Unix(pkg.Time.Build, 0).UTC()
+		}
+		result = append(result, distroVersion{Version: version, BuildTime: buildTime})
+	}
+	return result, nil
+}
@@ -0,0 +1,233 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/bborbe/kafka-k8s-version-collector/avro"
+	"github.com/bborbe/kafka-k8s-version-collector/version"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+)
+
+const debianPackages = `Package: libssl1.1
+Source: openssl
+Version: 1.1.0j-1~deb9u1
+Description: Secure Sockets Layer toolkit
+ multi line description
+ Package: openssl
+
+Package: openssl
+Version: 1.1.0j-1~deb9u1
+Architecture: amd64
+
+Package: openssl
+Version: 1.1.0f-3+deb9u2
+Architecture: amd64
+
+Package: openssl
+Version: 1.1.0j-1~deb9u1
+Architecture: i386
+`
+
+const rpmRepomd = `<?xml version="1.0" encoding="UTF-8"?>
+<repomd xmlns="http://linux.duke.edu/metadata/repo">
+  <data type="filelists">
+    <location href="repodata/1234-filelists.xml.gz"/>
+  </data>
+  <data type="primary">
+    <location href="repodata/5678-primary.xml.gz"/>
+  </data>
+</repomd>`
+
+const rpmPrimary = `<?xml version="1.0" encoding="UTF-8"?>
+<metadata xmlns="http://linux.duke.edu/metadata/common" xmlns:rpm="http://linux.duke.edu/metadata/rpm" packages="3">
+<package type="rpm">
+  <name>openssl</name>
+  <arch>x86_64</arch>
+  <version epoch="1" ver="1.0.2k" rel="16.el7"/>
+  <time file="1541101126" build="1540224000"/>
+</package>
+<package type="rpm">
+  <name>openssl</name>
+  <arch>x86_64</arch>
+  <version epoch="1" ver="1.0.2k" rel="8.el7"/>
+  <time file="1500000000" build="1500000000"/>
+</package>
+<package type="rpm">
+  <name>glibc</name>
+  <arch>x86_64</arch>
+  <version epoch="0" ver="2.17" rel="260.el7"/>
+</package>
+</metadata>`
+
+const alpineIndex = `C:Q1abc=
+P:openssl
+V:1.1.1b-r1
+A:x86_64
+t:1552656000
+
+P:openssl
+V:1.1.1a-r1
+t:1550000000
+
+P:musl
+V:1.1.20-r3
+`
+
+func gzipBytes(content []byte) []byte {
+	buf := &bytes.Buffer{}
+	writer := gzip.NewWriter(buf)
+	_, err := writer.Write(content)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(writer.Close()).To(BeNil())
+	return buf.Bytes()
+}
+
+func tarBytes(files ...string) []byte {
+	buf := &bytes.Buffer{}
+	writer := tar.NewWriter(buf)
+	for i := 0; i < len(files); i += 2 {
+		Expect(writer.WriteHeader(&tar.Header{Name: files[i], Mode: 0644, Size: int64(len(files[i+1]))})).To(BeNil())
+		_, err := writer.Write([]byte(files[i+1]))
+		Expect(err).NotTo(HaveOccurred())
+	}
+	Expect(writer.Close()).To(BeNil())
+	return buf.Bytes()
+}
+
+var _ = Describe("Distro", func() {
+	var server *ghttp.Server
+	var pkg version.DistroPackage
+	BeforeEach(func() {
+		server = ghttp.NewServer()
+		server.AllowUnhandledRequests = true
+		server.UnhandledRequestStatusCode = http.StatusNotFound
+		pkg = version.DistroPackage{
+			URL:  server.URL() + "/repo",
+			Name: "openssl",
+			App:  "OpenSSL",
+		}
+	})
+	AfterEach(func() {
+		server.Close()
+	})
+	serve := func(path string, body []byte) {
+		server.RouteToHandler(http.MethodGet, path, func(resp http.ResponseWriter, req *http.Request) {
+			resp.Write(body)
+		})
+	}
+	fetch := func(fetcher version.Fetcher) ([]avro.ApplicationVersionAvailable, error) {
+		versions := make(chan avro.ApplicationVersionAvailable, 100)
+		err := fetcher.Fetch(context.Background(), versions)
+		close(versions)
+		var list []avro.ApplicationVersionAvailable
+		for version := range versions {
+			list = append(list, version)
+		}
+		return list, err
+	}
+	versionsOf := func(list []avro.ApplicationVersionAvailable) []string {
+		var result []string
+		for _, v := range list {
+			result = append(result, v.Version)
+		}
+		return result
+	}
+	Context("apt", func() {
+		It("returns versions of package in Packages.gz newest first", func() {
+			serve("/repo/Packages.gz", gzipBytes([]byte(debianPackages)))
+			list, err := fetch(version.NewAptFetcher(http.DefaultClient, pkg))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versionsOf(list)).To(Equal([]string{"1.1.0j-1~deb9u1", "1.1.0f-3+deb9u2"}))
+			Expect(list[0].App).To(Equal("OpenSSL"))
+			Expect(list[0].Ecosystem).To(Equal(version.EcosystemDebian))
+			Expect(list[0].Parsed).To(BeFalse())
+			Expect(list[0].Stable).To(BeFalse())
+			Expect(list[1].Stable).To(BeTrue())
+		})
+		It("reads uncompressed Packages if Packages.gz is missing", func() {
+			serve("/repo/Packages", []byte(debianPackages))
+			list, err := fetch(version.NewAptFetcher(http.DefaultClient, pkg))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(list).To(HaveLen(2))
+		})
+		It("returns error if package is not in index", func() {
+			serve("/repo/Packages.gz", gzipBytes([]byte(debianPackages)))
+			pkg.Name = "banana"
+			_, err := fetch(version.NewAptFetcher(http.DefaultClient, pkg))
+			Expect(err).To(HaveOccurred())
+		})
+		It("returns error if index is missing", func() {
+			_, err := fetch(version.NewAptFetcher(http.DefaultClient, pkg))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Context("rpm", func() {
+		It("returns epoch, version and release of primary metadata", func() {
+			serve("/repo/repodata/repomd.xml", []byte(rpmRepomd))
+			serve("/repo/repodata/5678-primary.xml.gz", gzipBytes([]byte(rpmPrimary)))
+			list, err := fetch(version.NewRPMFetcher(http.DefaultClient, pkg))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versionsOf(list)).To(Equal([]string{"1:1.0.2k-16.el7", "1:1.0.2k-8.el7"}))
+			Expect(list[0].Ecosystem).To(Equal(version.EcosystemRPM))
+			Expect(list[0].PublishedAt).To(Equal(time.Unix(1540224000, 0).UnixNano() / int64(time.Millisecond)))
+		})
+		It("omits epoch 0", func() {
+			serve("/repo/repodata/repomd.xml", []byte(rpmRepomd))
+			serve("/repo/repodata/5678-primary.xml.gz", gzipBytes([]byte(rpmPrimary)))
+			pkg.Name = "glibc"
+			list, err := fetch(version.NewRPMFetcher(http.DefaultClient, pkg))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versionsOf(list)).To(Equal([]string{"2.17-260.el7"}))
+			Expect(list[0].PublishedAt).To(BeZero())
+		})
+		It("returns error if primary metadata is missing", func() {
+			serve("/repo/repodata/repomd.xml", []byte(rpmRepomd))
+			_, err := fetch(version.NewRPMFetcher(http.DefaultClient, pkg))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Context("apk", func() {
+		It("returns versions of APKINDEX following the signature", func() {
+			signature := gzipBytes(tarBytes(".SIGN.RSA.alpine-devel@lists.alpinelinux.org-1234.rsa.pub", "signature"))
+			index := gzipBytes(tarBytes("DESCRIPTION", "v3.9", "APKINDEX", alpineIndex))
+			serve("/repo/APKINDEX.tar.gz", append(signature, index...))
+			list, err := fetch(version.NewApkFetcher(http.DefaultClient, pkg))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versionsOf(list)).To(Equal([]string{"1.1.1b-r1", "1.1.1a-r1"}))
+			Expect(list[0].Ecosystem).To(Equal(version.EcosystemAlpine))
+			Expect(list[0].Stable).To(BeTrue())
+			Expect(list[0].PublishedAt).To(Equal(time.Unix(1552656000, 0).UnixNano() / int64(time.Millisecond)))
+		})
+		It("returns versions of APKINDEX without signature", func() {
+			serve("/repo/APKINDEX.tar.gz", gzipBytes(tarBytes("APKINDEX", alpineIndex)))
+			list, err := fetch(version.NewApkFetcher(http.DefaultClient, pkg))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(list).To(HaveLen(2))
+		})
+		It("returns error if archive contains no APKINDEX", func() {
+			serve("/repo/APKINDEX.tar.gz", gzipBytes(tarBytes("DESCRIPTION", "v3.9")))
+			_, err := fetch(version.NewApkFetcher(http.DefaultClient, pkg))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	It("sorts versions of distribution packages by ecosystem rules", func() {
+		list := []avro.ApplicationVersionAvailable{
+			version.NewDistroVersion("OpenSSL", version.EcosystemDebian, "1.1.0f-3", time.Time{}),
+			version.NewDistroVersion("OpenSSL", version.EcosystemDebian, "1.1.0j-1~deb9u1", time.Time{}),
+			version.NewDistroVersion("OpenSSL", version.EcosystemDebian, "1.1.0j-1", time.Time{}),
+		}
+		version.SortVersions(list)
+		Expect(versionsOf(list)).To(Equal([]string{"1.1.0j-1", "1.1.0j-1~deb9u1", "1.1.0f-3"}))
+	})
+})
@@ -0,0 +1,287 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// distroVersionCompare contains the version ordering of each distribution package ecosystem.
+var distroVersionCompare = map[string]func(a, b string) int{
+	EcosystemDebian: CompareDebianVersions,
+	EcosystemRPM:    CompareRPMVersions,
+	EcosystemAlpine: CompareAlpineVersions,
+}
+
+// distroVersionStable returns false for prereleases, marked with ~ in Debian and RPM versions
+// and with the suffixes _alpha, _beta, _pre and _rc in Alpine versions.
+func distroVersionStable(ecosystem string, version string) bool {
+	if ecosystem == EcosystemAlpine {
+		return !alpineUnstableRegexp.MatchString(version)
+	}
+	return !strings.Contains(version, "~")
+}
+
+var alpineUnstableRegexp = regexp.MustCompile(`_(alpha|beta|pre|rc)[0-9]*`)
+
+// CompareDebianVersions compares two Debian package versions like 1:1.1.1a-1~deb9u1 the way dpkg does.
+// It returns a negative number if a is older than b, zero if both are equal and a positive number otherwise.
+// (see https://www.debian.org/doc/debian-policy/ch-controlfields.html#version)
+func CompareDebianVersions(a string, b string) int {
+	epochA, upstreamA, revisionA := splitDebianVersion(a)
+	epochB, upstreamB, revisionB := splitDebianVersion(b)
+	if epochA != epochB {
+		return epochA - epochB
+	}
+	if result := compareDebianPart(upstreamA, upstreamB); result != 0 {
+		return result
+	}
+	return compareDebianPart(revisionA, revisionB)
+}
+
+func splitDebianVersion(version string) (int, string, string) {
+	var epoch int
+	if pos := strings.IndexByte(version, ':'); pos != -1 {
+		epoch, _ = strconv.Atoi(version[:pos])
+		version = version[pos+1:]
+	}
+	var revision string
+	if pos := strings.LastIndexByte(version, '-'); pos != -1 {
+		revision = version[pos+1:]
+		version = version[:pos]
+	}
+	return epoch, version, revision
+}
+
+// compareDebianPart compares alternating non digit and digit parts,
+// ~ sorts before everything, even the end of the part, and letters sort before other characters.
+func compareDebianPart(a string, b string) int {
+	for a != "" || b != "" {
+		for (a != "" && !isDigit(a[0])) || (b != "" && !isDigit(b[0])) {
+			orderA, orderB := debianOrder(a), debianOrder(b)
+			if orderA != orderB {
+				return orderA - orderB
+			}
+			a, b = a[1:], b[1:]
+		}
+		var digitsA, digitsB string
+		digitsA, a = splitDigits(a)
+		digitsB, b = splitDigits(b)
+		if result := compareNumbers(digitsA, digitsB); result != 0 {
+			return result
+		}
+	}
+	return 0
+}
+
+func debianOrder(value string) int {
+	switch {
+	case value == "" || isDigit(value[0]):
+		return 0
+	case value[0] == '~':
+		return -1
+	case isLetter(value[0]):
+		return int(value[0])
+	default:
+		return int(value[0]) + 256
+	}
+}
+
+// CompareRPMVersions compares two RPM versions like 1:1.1.1-8.el8 by epoch, version and release the way rpm does.
+// It returns a negative number if a is older than b, zero if both are equal and a positive number otherwise.
+func CompareRPMVersions(a string, b string) int {
+	epochA, versionA, releaseA := splitRPMVersion(a)
+	epochB, versionB, releaseB := splitRPMVersion(b)
+	if epochA != epochB {
+		return epochA - epochB
+	}
+	if result := compareRPMPart(versionA, versionB); result != 0 {
+		return result
+	}
+	return compareRPMPart(releaseA, releaseB)
+}
+
+func splitRPMVersion(version string) (int, string, string) {
+	// debian and rpm versions are both written as [epoch:]version[-release]
+	return splitDebianVersion(version)
+}
+
+// compareRPMPart compares alternating alphabetic and numeric segments like rpmvercmp,
+// a numeric segment is newer than a alphabetic one, ~ sorts before and ^ after the end of the part.
+func compareRPMPart(a string, b string) int {
+	if a == b {
+		return 0
+	}
+	for a != "" || b != "" {
+		a = strings.TrimLeftFunc(a, isRPMSeparator)
+		b = strings.TrimLeftFunc(b, isRPMSeparator)
+		if strings.HasPrefix(a, "~") || strings.HasPrefix(b, "~") {
+			if !strings.HasPrefix(a, "~") {
+				return 1
+			}
+			if !strings.HasPrefix(b, "~") {
+				return -1
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+		if strings.HasPrefix(a, "^") || strings.HasPrefix(b, "^") {
+			if a == "" {
+				return -1
+			}
+			if b == "" {
+				return 1
+			}
+			if !strings.HasPrefix(a, "^") {
+				return 1
+			}
+			if !strings.HasPrefix(b, "^") {
+				return -1
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+		if a == "" || b == "" {
+			break
+		}
+		var segmentA, segmentB string
+		numeric := isDigit(a[0])
+		if numeric {
+			segmentA, a = splitDigits(a)
+			segmentB, b = splitDigits(b)
+		} else {
+			segmentA, a = splitLetters(a)
+			segmentB, b = splitLetters(b)
+		}
+		if segmentB == "" {
+			if numeric {
+				return 1
+			}
+			return -1
+		}
+		if numeric {
+			if result := compareNumbers(segmentA, segmentB); result != 0 {
+				return result
+			}
+			continue
+		}
+		if result := strings.Compare(segmentA, segmentB); result != 0 {
+			return result
+		}
+	}
+	switch {
+	case a == "" && b == "":
+		return 0
+	case a != "":
+		return 1
+	default:
+		return -1
+	}
+}
+
+func isRPMSeparator(r rune) bool {
+	if r < 128 && (isDigit(byte(r)) || isLetter(byte(r))) {
+		return false
+	}
+	return r != '~' && r != '^'
+}
+
+// alpineSuffixes contains the order of Alpine version suffixes, a version without suffix has order 0.
+var alpineSuffixes = map[string]int{
+	"alpha": -4,
+	"beta":  -3,
+	"pre":   -2,
+	"rc":    -1,
+	"cvs":   1,
+	"svn":   2,
+	"git":   3,
+	"hg":    4,
+	"p":     5,
+}
+
+var alpineVersionRegexp = regexp.MustCompile(`^([0-9]+(?:\.[0-9]+)*)([a-z]?)((?:_[a-z]+[0-9]*)*)(?:-r([0-9]+))?$`)
+
+var alpineSuffixRegexp = regexp.MustCompile(`_([a-z]+)([0-9]*)`)
+
+// CompareAlpineVersions compares two Alpine package versions like 1.1.1b-r1 by numbers, letter, suffixes and revision.
+// It returns a negative number if a is older than b, zero if both are equal and a positive number otherwise.
+// Invalid versions are compared alphabetically.
+func CompareAlpineVersions(a string, b string) int {
+	matchA := alpineVersionRegexp.FindStringSubmatch(a)
+	matchB := alpineVersionRegexp.FindStringSubmatch(b)
+	if matchA == nil || matchB == nil {
+		return strings.Compare(a, b)
+	}
+	numbersA, numbersB := strings.Split(matchA[1], "."), strings.Split(matchB[1], ".")
+	for i := 0; i < len(numbersA) || i < len(numbersB); i++ {
+		if i >= len(numbersA) {
+			return -1
+		}
+		if i >= len(numbersB) {
+			return 1
+		}
+		if result := compareNumbers(numbersA[i], numbersB[i]); result != 0 {
+			return result
+		}
+	}
+	if result := strings.Compare(matchA[2], matchB[2]); result != 0 {
+		return result
+	}
+	suffixesA := alpineSuffixRegexp.FindAllStringSubmatch(matchA[3], -1)
+	suffixesB := alpineSuffixRegexp.FindAllStringSubmatch(matchB[3], -1)
+	for i := 0; i < len(suffixesA) || i < len(suffixesB); i++ {
+		var orderA, orderB int
+		var numberA, numberB string
+		if i < len(suffixesA) {
+			orderA, numberA = alpineSuffixes[suffixesA[i][1]], suffixesA[i][2]
+		}
+		if i < len(suffixesB) {
+			orderB, numberB = alpineSuffixes[suffixesB[i][1]], suffixesB[i][2]
+		}
+		if orderA != orderB {
+			return orderA - orderB
+		}
+		if result := compareNumbers(numberA, numberB); result != 0 {
+			return result
+		}
+	}
+	return compareNumbers(matchA[4], matchB[4])
+}
+
+// compareNumbers compares two strings of digits numerically, a empty string is zero.
+func compareNumbers(a string, b string) int {
+	a = strings.TrimLeft(a, "0")
+	b = strings.TrimLeft(b, "0")
+	if len(a) != len(b) {
+		return len(a) - len(b)
+	}
+	return strings.Compare(a, b)
+}
+
+func splitDigits(value string) (string, string) {
+	i := 0
+	for i < len(value) && isDigit(value[i]) {
+		i++
+	}
+	return value[:i], value[i:]
+}
+
+func splitLetters(value string) (string, string) {
+	i := 0
+	for i < len(value) && isLetter(value[i]) {
+		i++
+	}
+	return value[:i], value[i:]
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
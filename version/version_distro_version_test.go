@@ -0,0 +1,76 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version_test
+
+import (
+	"github.com/bborbe/kafka-k8s-version-collector/version"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Distro Version", func() {
+	sign := func(value int) int {
+		switch {
+		case value < 0:
+			return -1
+		case value > 0:
+			return 1
+		default:
+			return 0
+		}
+	}
+	expectOrder := func(compare func(a, b string) int, cases [][2]string) {
+		for _, c := range cases {
+			Expect(sign(compare(c[0], c[1]))).To(Equal(-1), "%s < %s", c[0], c[1])
+			Expect(sign(compare(c[1], c[0]))).To(Equal(1), "%s > %s", c[1], c[0])
+		}
+	}
+	It("compares debian versions", func() {
+		expectOrder(version.CompareDebianVersions, [][2]string{
+			{"1.0", "1.1"},
+			{"1.1.0f-3", "1.1.1a-1"},
+			{"1.1.1a-1~deb9u1", "1.1.1a-1"},
+			{"1.0~rc1", "1.0"},
+			{"1.0~~", "1.0~"},
+			{"1.0", "1.0a"},
+			{"1.0a", "1.0+"},
+			{"1.2.9", "1.2.10"},
+			{"2.24-11+deb9u4", "2.28-10"},
+			{"9.0", "1:1.0"},
+			{"1.0-1", "1.0-1.1"},
+		})
+		Expect(version.CompareDebianVersions("1.0-01", "1.0-1")).To(Equal(0))
+		Expect(version.CompareDebianVersions("0:1.0", "1.0")).To(Equal(0))
+	})
+	It("compares rpm versions", func() {
+		expectOrder(version.CompareRPMVersions, [][2]string{
+			{"1.0.2k-16.el7", "1.0.2k-19.el7"},
+			{"1.0.2k-19.el7", "1:1.0.2k-8.el7"},
+			{"1.0~rc1-1", "1.0-1"},
+			{"1.0-1", "1.0^git1-1"},
+			{"1.0a", "1.0.1"},
+			{"1.9", "1.10"},
+			{"2.17-260.el7", "2.17-292.el7"},
+			{"1.0", "1.0.1"},
+			{"a", "1"},
+		})
+		Expect(version.CompareRPMVersions("1.0-1.el7", "1.0-1_el7")).To(Equal(0))
+		Expect(version.CompareRPMVersions("1.01", "1.1")).To(Equal(0))
+	})
+	It("compares alpine versions", func() {
+		expectOrder(version.CompareAlpineVersions, [][2]string{
+			{"1.1.1a-r0", "1.1.1b-r0"},
+			{"1.1.1b-r0", "1.1.1b-r1"},
+			{"1.1.1-r0", "1.1.1a-r0"},
+			{"2.0_rc1-r0", "2.0-r0"},
+			{"2.0_alpha1", "2.0_beta1"},
+			{"2.0_beta2", "2.0_beta10"},
+			{"2.0-r5", "2.0_p1-r0"},
+			{"1.9", "1.10"},
+			{"1.2", "1.2.1"},
+		})
+		Expect(version.CompareAlpineVersions("1.2.3-r0", "1.2.3-r0")).To(Equal(0))
+	})
+})
@@ -7,6 +7,7 @@ package version
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 
 	"github.com/bborbe/kafka-k8s-version-collector/avro"
@@ -19,53 +20,97 @@ type Fetcher interface {
 	Fetch(ctx context.Context, versions chan<- avro.ApplicationVersionAvailable) error
 }
 
+// NewFetcher returns a Fetcher that lists the tags of all given images.
+// Tags are requested in pages of pageSize and at most maxPages pages are followed per image.
 func NewFetcher(
 	httpClient *http.Client,
-	url string,
+	pageSize int,
+	maxPages int,
+	images ...Image,
 ) Fetcher {
 	return &fetcher{
 		httpClient: httpClient,
-		url:        url,
+		pageSize:   pageSize,
+		maxPages:   maxPages,
+		images:     images,
 	}
 }
 
 type fetcher struct {
 	httpClient *http.Client
-	url        string
+	pageSize   int
+	maxPages   int
+	images     []Image
 }
 
 func (f *fetcher) Fetch(ctx context.Context, versions chan<- avro.ApplicationVersionAvailable) error {
-	url := f.url + "/v2/google_containers/hyperkube-amd64/tags/list"
+	for _, image := range f.images {
+		select {
+		case <-ctx.Done():
+			glog.Infof("context done => return")
+			return nil
+		default:
+			if err := f.fetchImage(ctx, image, versions); err != nil {
+				return errors.Wrapf(err, "fetch tags of %s/%s failed", image.Registry, image.Repository)
+			}
+		}
+	}
+	return nil
+}
+
+func (f *fetcher) fetchImage(ctx context.Context, image Image, versions chan<- avro.ApplicationVersionAvailable) error {
+	url := image.TagsURL()
+	if f.pageSize > 0 {
+		url = fmt.Sprintf("%s?n=%d", url, f.pageSize)
+	}
+	for page := 1; url != ""; page++ {
+		if f.maxPages > 0 && page > f.maxPages {
+			glog.Warningf("fetch tags of %s/%s stopped after %d pages", image.Registry, image.Repository, f.maxPages)
+			return nil
+		}
+		tags, next, err := f.fetchPage(ctx, url)
+		if err != nil {
+			if ctx.Err() != nil {
+				glog.Infof("context done => return")
+				return nil
+			}
+			return errors.Wrapf(err, "fetch page %d failed", page)
+		}
+		for _, tag := range tags {
+			select {
+			case <-ctx.Done():
+				glog.Infof("context done => return")
+				return nil
+			case versions <- NewApplicationVersionAvailable(image.App, tag):
+			}
+		}
+		url = next
+	}
+	return nil
+}
+
+// fetchPage returns the tags of the given url and the url of the next page if exists.
+func (f *fetcher) fetchPage(ctx context.Context, url string) ([]string, string, error) {
 	req, err := http.NewRequest(http.MethodGet, url, nil)
 	if err != nil {
-		return errors.Wrap(err, "build request failed")
+		return nil, "", errors.Wrap(err, "build request failed")
 	}
+	req = req.WithContext(ctx)
 	glog.V(1).Infof("%s %s", req.Method, req.URL.String())
 	resp, err := f.httpClient.Do(req)
 	if err != nil {
-		return errors.Wrap(err, "request failed")
+		return nil, "", errors.Wrap(err, "request failed")
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode/100 != 2 {
-		return errors.New("request status code != 2xx")
+		return nil, "", errors.New("request status code != 2xx")
 	}
 	var data struct {
 		Tags []string `json:"tags"`
 	}
 	err = json.NewDecoder(resp.Body).Decode(&data)
 	if err != nil {
-		return errors.Wrap(err, "decode json failed")
-	}
-	for _, tag := range data.Tags {
-		select {
-		case <-ctx.Done():
-			glog.Infof("context done => return")
-			return nil
-		case versions <- avro.ApplicationVersionAvailable{
-			App:     "Kubernetes",
-			Version: tag,
-		}:
-		}
+		return nil, "", errors.Wrap(err, "decode json failed")
 	}
-	return nil
+	return data.Tags, NextLink(req.URL, resp.Header["Link"]), nil
 }
@@ -23,7 +23,13 @@ var _ = Describe("Version Fetcher", func() {
 		server = ghttp.NewServer()
 		fetcher = version.NewFetcher(
 			http.DefaultClient,
-			server.URL(),
+			0,
+			0,
+			version.Image{
+				Registry:   server.URL(),
+				Repository: "google_containers/hyperkube-amd64",
+				App:        "Kubernetes",
+			},
 		)
 	})
 	AfterEach(func() {
@@ -68,6 +74,108 @@ var _ = Describe("Version Fetcher", func() {
 		Expect(list[2].App).To(Equal("Kubernetes"))
 		Expect(list[2].Version).To(Equal("v3"))
 	})
+	It("returns versions of all images", func() {
+		server.RouteToHandler(http.MethodGet, "/v2/google_containers/hyperkube-amd64/tags/list", func(resp http.ResponseWriter, req *http.Request) {
+			fmt.Fprint(resp, `{"tags":["v1"]}`)
+		})
+		server.RouteToHandler(http.MethodGet, "/v2/grafana/grafana/tags/list", func(resp http.ResponseWriter, req *http.Request) {
+			fmt.Fprint(resp, `{"tags":["6.0.0"]}`)
+		})
+		fetcher = version.NewFetcher(
+			http.DefaultClient,
+			0,
+			0,
+			version.Image{
+				Registry:   server.URL(),
+				Repository: "google_containers/hyperkube-amd64",
+				App:        "Kubernetes",
+			},
+			version.Image{
+				Registry:   server.URL(),
+				Repository: "grafana/grafana",
+				App:        "Grafana",
+			},
+		)
+		versions := make(chan avro.ApplicationVersionAvailable)
+		var list []avro.ApplicationVersionAvailable
+		go func() {
+			defer close(versions)
+			err := fetcher.Fetch(context.Background(), versions)
+			Expect(err).NotTo(HaveOccurred())
+		}()
+		for version := range versions {
+			list = append(list, version)
+		}
+		Expect(list).To(HaveLen(2))
+		Expect(list[0].App).To(Equal("Kubernetes"))
+		Expect(list[0].Version).To(Equal("v1"))
+		Expect(list[1].App).To(Equal("Grafana"))
+		Expect(list[1].Version).To(Equal("6.0.0"))
+	})
+	It("follows pagination", func() {
+		fetcher = version.NewFetcher(
+			http.DefaultClient,
+			2,
+			0,
+			version.Image{
+				Registry:   server.URL(),
+				Repository: "google_containers/hyperkube-amd64",
+				App:        "Kubernetes",
+			},
+		)
+		server.RouteToHandler(http.MethodGet, "/v2/google_containers/hyperkube-amd64/tags/list", func(resp http.ResponseWriter, req *http.Request) {
+			Expect(req.URL.Query().Get("n")).To(Equal("2"))
+			switch req.URL.Query().Get("last") {
+			case "":
+				resp.Header().Set("Link", `</v2/google_containers/hyperkube-amd64/tags/list?n=2&last=v2>; rel="next"`)
+				fmt.Fprint(resp, `{"tags":["v1","v2"]}`)
+			case "v2":
+				resp.Header().Set("Link", `</v2/google_containers/hyperkube-amd64/tags/list?n=2&last=v4>; rel="next"`)
+				fmt.Fprint(resp, `{"tags":["v3","v4"]}`)
+			default:
+				fmt.Fprint(resp, `{"tags":["v5"]}`)
+			}
+		})
+		versions := make(chan avro.ApplicationVersionAvailable)
+		var list []avro.ApplicationVersionAvailable
+		go func() {
+			defer close(versions)
+			err := fetcher.Fetch(context.Background(), versions)
+			Expect(err).NotTo(HaveOccurred())
+		}()
+		for version := range versions {
+			list = append(list, version)
+		}
+		Expect(list).To(HaveLen(5))
+		Expect(list[4].Version).To(Equal("v5"))
+	})
+	It("stops pagination after max pages", func() {
+		fetcher = version.NewFetcher(
+			http.DefaultClient,
+			1,
+			3,
+			version.Image{
+				Registry:   server.URL(),
+				Repository: "google_containers/hyperkube-amd64",
+				App:        "Kubernetes",
+			},
+		)
+		server.RouteToHandler(http.MethodGet, "/v2/google_containers/hyperkube-amd64/tags/list", func(resp http.ResponseWriter, req *http.Request) {
+			resp.Header().Set("Link", `</v2/google_containers/hyperkube-amd64/tags/list?n=1&last=v>; rel="next"`)
+			fmt.Fprint(resp, `{"tags":["v"]}`)
+		})
+		versions := make(chan avro.ApplicationVersionAvailable)
+		var list []avro.ApplicationVersionAvailable
+		go func() {
+			defer close(versions)
+			err := fetcher.Fetch(context.Background(), versions)
+			Expect(err).NotTo(HaveOccurred())
+		}()
+		for version := range versions {
+			list = append(list, version)
+		}
+		Expect(list).To(HaveLen(3))
+	})
 	It("returns an error if not valid json", func() {
 		server.RouteToHandler(http.MethodGet, "/v2/google_containers/hyperkube-amd64/tags/list", func(resp http.ResponseWriter, req *http.Request) {
 			fmt.Fprint(resp, `asdf`)
@@ -91,7 +199,13 @@ var _ = Describe("Version Fetcher", func() {
 			&http.Client{
 				Transport: &ErrorRoundTripper{},
 			},
-			server.URL(),
+			0,
+			0,
+			version.Image{
+				Registry:   server.URL(),
+				Repository: "google_containers/hyperkube-amd64",
+				App:        "Kubernetes",
+			},
 		)
 		versions := make(chan avro.ApplicationVersionAvailable)
 		defer close(versions)
@@ -109,4 +223,16 @@ var _ = Describe("Version Fetcher", func() {
 		err := fetcher.Fetch(ctx, versions)
 		Expect(err).To(BeNil())
 	})
+	It("cancels request of tag list if context is done", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		release := make(chan struct{})
+		defer close(release)
+		server.RouteToHandler(http.MethodGet, "/v2/google_containers/hyperkube-amd64/tags/list", func(resp http.ResponseWriter, req *http.Request) {
+			cancel()
+			<-release
+		})
+		versions := make(chan avro.ApplicationVersionAvailable)
+		err := fetcher.Fetch(ctx, versions)
+		Expect(err).To(BeNil())
+	})
 })
@@ -0,0 +1,173 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/bborbe/kafka-k8s-version-collector/avro"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+//go:generate counterfeiter -o ../mocks/filter.go --fake-name Filter . Filter
+type Filter interface {
+	Filter(ctx context.Context, in <-chan avro.ApplicationVersionAvailable, out chan<- avro.ApplicationVersionAvailable) error
+}
+
+// FilterConfig defines which versions pass the filter.
+type FilterConfig struct {
+	// Include versions matching at least one of the regular expressions. All versions if empty.
+	Include []string `yaml:"include"`
+	// Exclude versions matching any of the regular expressions.
+	Exclude []string `yaml:"exclude"`
+	// Constraint like ">=1.10 <2.0" the semantic version must match.
+	Constraint string `yaml:"constraint"`
+	// StableOnly excludes prereleases and versions that are no semantic version.
+	StableOnly bool `yaml:"stableOnly"`
+	// KeepNewest only passes the newest n semantic versions or distribution package versions per app. All versions if 0.
+	KeepNewest int `yaml:"keepNewest"`
+}
+
+// stateless returns the config without KeepNewest, which needs all versions of the app to decide.
+func (f FilterConfig) stateless() FilterConfig {
+	f.KeepNewest = 0
+	return f
+}
+
+// NewFilter returns a Filter for the given config.
+func NewFilter(config FilterConfig) (Filter, error) {
+	result := &filter{
+		stableOnly: config.StableOnly,
+		keepNewest: config.KeepNewest,
+	}
+	var err error
+	if result.includes, err = compileRegexps(config.Include); err != nil {
+		return nil, errors.Wrap(err, "compile include failed")
+	}
+	if result.excludes, err = compileRegexps(config.Exclude); err != nil {
+		return nil, errors.Wrap(err, "compile exclude failed")
+	}
+	if config.Constraint != "" {
+		if result.constraint, err = ParseConstraint(config.Constraint); err != nil {
+			return nil, errors.Wrap(err, "parse constraint failed")
+		}
+	}
+	if config.KeepNewest < 0 {
+		return nil, errors.Errorf("keep newest %d is negative", config.KeepNewest)
+	}
+	return result, nil
+}
+
+func compileRegexps(values []string) ([]*regexp.Regexp, error) {
+	var result []*regexp.Regexp
+	for _, value := range values {
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "compile regexp '%s' failed", value)
+		}
+		result = append(result, re)
+	}
+	return result, nil
+}
+
+type filter struct {
+	includes   []*regexp.Regexp
+	excludes   []*regexp.Regexp
+	constraint Constraint
+	stableOnly bool
+	keepNewest int
+}
+
+func (f *filter) Filter(ctx context.Context, in <-chan avro.ApplicationVersionAvailable, out chan<- avro.ApplicationVersionAvailable) error {
+	var kept []avro.ApplicationVersionAvailable
+	for {
+		select {
+		case <-ctx.Done():
+			glog.V(3).Infof("context done => return")
+			return nil
+		case version, ok := <-in:
+			if !ok {
+				glog.V(3).Infof("channel closed => return")
+				return f.sendNewest(ctx, kept, out)
+			}
+			if !f.matches(version) {
+				glog.V(4).Infof("version %s of %s filtered", version.Version, version.App)
+				continue
+			}
+			if f.keepNewest > 0 {
+				kept = append(kept, version)
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				glog.V(3).Infof("context done => return")
+				return nil
+			case out <- version:
+			}
+		}
+	}
+}
+
+func (f *filter) matches(version avro.ApplicationVersionAvailable) bool {
+	if len(f.includes) > 0 && !matchesAny(f.includes, version.Version) {
+		return false
+	}
+	if matchesAny(f.excludes, version.Version) {
+		return false
+	}
+	if f.stableOnly && !version.Stable {
+		return false
+	}
+	if f.constraint != nil && !version.Parsed {
+		return false
+	}
+	if _, distro := distroVersionCompare[version.Ecosystem]; f.keepNewest > 0 && !version.Parsed && !distro {
+		return false
+	}
+	if f.constraint != nil && !f.constraint.matches(semVerOf(version), !version.Stable) {
+		return false
+	}
+	return true
+}
+
+// sendNewest sends the newest versions per app.
+func (f *filter) sendNewest(ctx context.Context, versions []avro.ApplicationVersionAvailable, out chan<- avro.ApplicationVersionAvailable) error {
+	SortVersions(versions)
+	counter := make(map[string]int)
+	for _, version := range versions {
+		if counter[version.App] >= f.keepNewest {
+			continue
+		}
+		counter[version.App]++
+		select {
+		case <-ctx.Done():
+			glog.V(3).Infof("context done => return")
+			return nil
+		case out <- version:
+		}
+	}
+	return nil
+}
+
+func matchesAny(regexps []*regexp.Regexp, value string) bool {
+	for _, re := range regexps {
+		if re.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func semVerOf(version avro.ApplicationVersionAvailable) SemVer {
+	return SemVer{
+		Major:      int(version.Major),
+		Minor:      int(version.Minor),
+		Patch:      int(version.Patch),
+		Prerelease: version.Prerelease,
+		Build:      version.Build,
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/bborbe/kafka-k8s-version-collector/avro"
+	"github.com/bborbe/kafka-k8s-version-collector/version"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Version Filter", func() {
+	var filter version.Filter
+	var config version.FilterConfig
+	var versions []avro.ApplicationVersionAvailable
+	BeforeEach(func() {
+		config = version.FilterConfig{}
+		versions = []avro.ApplicationVersionAvailable{
+			version.NewApplicationVersionAvailable("Kubernetes", "latest"),
+			version.NewApplicationVersionAvailable("Kubernetes", "v1.9.11"),
+			version.NewApplicationVersionAvailable("Kubernetes", "v1.13.4"),
+			version.NewApplicationVersionAvailable("Kubernetes", "v1.14.0-beta.0"),
+			version.NewApplicationVersionAvailable("Kubernetes", "v1.12.6"),
+			version.NewApplicationVersionAvailable("Kubernetes", "v1.13.4-amd64"),
+		}
+	})
+	filtered := func() []string {
+		var err error
+		filter, err = version.NewFilter(config)
+		Expect(err).NotTo(HaveOccurred())
+		in := make(chan avro.ApplicationVersionAvailable, len(versions))
+		for _, v := range versions {
+			in <- v
+		}
+		close(in)
+		out := make(chan avro.ApplicationVersionAvailable, len(versions))
+		err = filter.Filter(context.Background(), in, out)
+		Expect(err).NotTo(HaveOccurred())
+		close(out)
+		var result []string
+		for v := range out {
+			result = append(result, v.Version)
+		}
+		return result
+	}
+	It("passes all versions without config", func() {
+		Expect(filtered()).To(HaveLen(len(versions)))
+	})
+	It("includes only matching versions", func() {
+		config.Include = []string{`^v1\.13\.`}
+		Expect(filtered()).To(Equal([]string{"v1.13.4", "v1.13.4-amd64"}))
+	})
+	It("excludes matching versions", func() {
+		config.Exclude = []string{`^latest$`, `-amd64$`}
+		Expect(filtered()).To(Equal([]string{"v1.9.11", "v1.13.4", "v1.14.0-beta.0", "v1.12.6"}))
+	})
+	It("passes only stable versions", func() {
+		config.StableOnly = true
+		Expect(filtered()).To(Equal([]string{"v1.9.11", "v1.13.4", "v1.12.6"}))
+	})
+	It("passes only versions matching constraint", func() {
+		config.Constraint = ">=1.10 <1.14"
+		Expect(filtered()).To(Equal([]string{"v1.13.4", "v1.12.6"}))
+	})
+	It("passes no release marked as prerelease for constraint without prerelease", func() {
+		versions = []avro.ApplicationVersionAvailable{
+			version.NewGitHubReleaseVersion("Grafana", version.GitHubRelease{TagName: "v6.0.0", Prerelease: true}),
+			version.NewGitHubReleaseVersion("Grafana", version.GitHubRelease{TagName: "v5.4.0"}),
+		}
+		config.Constraint = ">=5.0"
+		Expect(filtered()).To(Equal([]string{"v5.4.0"}))
+	})
+	It("keeps newest versions", func() {
+		config.KeepNewest = 2
+		config.StableOnly = true
+		Expect(filtered()).To(Equal([]string{"v1.13.4", "v1.12.6"}))
+	})
+	It("keeps newest versions per app", func() {
+		versions = append(versions, version.NewApplicationVersionAvailable("Grafana", "6.0.0"))
+		config.KeepNewest = 1
+		Expect(filtered()).To(ConsistOf("v1.14.0-beta.0", "6.0.0"))
+	})
+	It("keeps newest distribution package versions", func() {
+		versions = nil
+		for _, v := range []string{"1.1.0f-3+deb9u2", "1.1.0j-1~deb9u1", "1.1.0c-2"} {
+			versions = append(versions, version.NewDistroVersion("OpenSSL", version.EcosystemDebian, v, time.Time{}))
+		}
+		config.KeepNewest = 2
+		Expect(filtered()).To(Equal([]string{"1.1.0j-1~deb9u1", "1.1.0f-3+deb9u2"}))
+	})
+	It("returns error if regexp is invalid", func() {
+		_, err := version.NewFilter(version.FilterConfig{Include: []string{"("}})
+		Expect(err).To(HaveOccurred())
+	})
+	It("returns error if constraint is invalid", func() {
+		_, err := version.NewFilter(version.FilterConfig{Constraint: ">=banana"})
+		Expect(err).To(HaveOccurred())
+	})
+	It("returns without error if context is canceled", func() {
+		var err error
+		filter, err = version.NewFilter(config)
+		Expect(err).NotTo(HaveOccurred())
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		in := make(chan avro.ApplicationVersionAvailable)
+		out := make(chan avro.ApplicationVersionAvailable)
+		Expect(filter.Filter(ctx, in, out)).To(BeNil())
+	})
+})
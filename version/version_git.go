@@ -0,0 +1,188 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/bborbe/kafka-k8s-version-collector/avro"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+const (
+	gitTagPrefix          = "refs/tags/"
+	gitPeeledSuffix       = "^{}"
+	gitUploadPackMimeType = "application/x-git-upload-pack-advertisement"
+)
+
+// GitRepository describes a git repository served via http.
+type GitRepository struct {
+	// URL of the repository, like https://git.example.com/group/project.git.
+	URL string
+	App string
+}
+
+// GitRef is a reference advertised by a git server.
+type GitRef struct {
+	Name string
+}
+
+// NewGitTagsFetcher returns a Fetcher listing the tags of a git repository
+// with the reference advertisement of the smart http protocol, without cloning the repository.
+// (see https://git-scm.com/docs/http-protocol)
+func NewGitTagsFetcher(
+	httpClient *http.Client,
+	repository GitRepository,
+) Fetcher {
+	return &gitTagsFetcher{
+		httpClient: httpClient,
+		repository: repository,
+	}
+}
+
+type gitTagsFetcher struct {
+	httpClient *http.Client
+	repository GitRepository
+}
+
+func (g *gitTagsFetcher) Fetch(ctx context.Context, versions chan<- avro.ApplicationVersionAvailable) error {
+	refs, err := g.refs(ctx)
+	if err != nil {
+		return errors.Wrapf(err, "list refs of %s failed", g.repository.URL)
+	}
+	var tags []string
+	for _, ref := range refs {
+		if strings.HasPrefix(ref.Name, gitTagPrefix) {
+			tags = append(tags, strings.TrimPrefix(ref.Name, gitTagPrefix))
+		}
+	}
+	for _, tag := range tags {
+		select {
+		case <-ctx.Done():
+			glog.Infof("context done => return")
+			return nil
+		case versions <- NewApplicationVersionAvailable(g.repository.App, tag):
+		}
+	}
+	return nil
+}
+
+func (g *gitTagsFetcher) refs(ctx context.Context) ([]GitRef, error) {
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(g.repository.URL, "/")+"/info/refs?service=git-upload-pack", nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "build request failed")
+	}
+	req = req.WithContext(ctx)
+	// some servers only answer the smart protocol to git clients
+	req.Header.Set("User-Agent", "git/2.0 (kafka-k8s-version-collector)")
+	glog.V(1).Infof("%s %s", req.Method, req.URL.String())
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "request failed")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return nil, errors.Errorf("request status code %d != 2xx", resp.StatusCode)
+	}
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), gitUploadPackMimeType) {
+		glog.V(2).Infof("%s answered without smart protocol => parse dumb refs", g.repository.URL)
+		return ParseGitDumbRefs(resp.Body)
+	}
+	return ParseGitRefs(resp.Body)
+}
+
+// ParseGitRefs parses the pkt-line reference advertisement of git-upload-pack.
+// Peeled entries of annotated tags are skipped, each tag is returned once.
+func ParseGitRefs(r io.Reader) ([]GitRef, error) {
+	reader := bufio.NewReader(r)
+	var lines []string
+	for {
+		line, err := readPktLine(reader)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if line == "" || strings.HasPrefix(line, "# service=") {
+			continue
+		}
+		if line == "version 2" {
+			return nil, errors.New("protocol version 2 is not supported")
+		}
+		// capabilities are appended to the first reference
+		if pos := strings.IndexByte(line, 0); pos != -1 {
+			line = line[:pos]
+		}
+		lines = append(lines, line)
+	}
+	return parseGitRefLines(lines, " ")
+}
+
+// ParseGitDumbRefs parses the info/refs file served by dumb http servers.
+func ParseGitDumbRefs(r io.Reader) ([]GitRef, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "read refs failed")
+	}
+	return parseGitRefLines(lines, "\t")
+}
+
+// parseGitRefLines parses lines of object id and name separated by sep.
+func parseGitRefLines(lines []string, sep string) ([]GitRef, error) {
+	var result []GitRef
+	for _, line := range lines {
+		parts := strings.SplitN(line, sep, 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return nil, errors.Errorf("invalid ref line %q", line)
+		}
+		if strings.HasSuffix(parts[1], gitPeeledSuffix) {
+			continue
+		}
+		result = append(result, GitRef{
+			Name: parts[1],
+		})
+	}
+	return result, nil
+}
+
+// readPktLine returns the payload of the next pkt-line without trailing newline.
+// A flush or delimiter packet returns an empty line.
+func readPktLine(reader *bufio.Reader) (string, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		if err == io.EOF {
+			return "", io.EOF
+		}
+		return "", errors.Wrap(err, "read pkt-line length failed")
+	}
+	length, err := strconv.ParseUint(string(header), 16, 16)
+	if err != nil {
+		return "", errors.Errorf("invalid pkt-line length %q", header)
+	}
+	if length <= 2 {
+		return "", nil
+	}
+	if length < 4 {
+		return "", errors.Errorf("invalid pkt-line length %d", length)
+	}
+	payload := make([]byte, length-4)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		return "", errors.Wrap(err, "read pkt-line failed")
+	}
+	return strings.TrimSuffix(string(payload), "\n"), nil
+}
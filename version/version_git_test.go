@@ -0,0 +1,128 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/bborbe/kafka-k8s-version-collector/avro"
+	"github.com/bborbe/kafka-k8s-version-collector/version"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+)
+
+const (
+	gitCommitA = "1111111111111111111111111111111111111111"
+	gitCommitB = "2222222222222222222222222222222222222222"
+	gitTagB    = "3333333333333333333333333333333333333333"
+)
+
+func gitAdvertisement(lines ...string) string {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "%04x# service=git-upload-pack\n", len("# service=git-upload-pack\n")+4)
+	buf.WriteString("0000")
+	for _, line := range lines {
+		fmt.Fprintf(buf, "%04x%s\n", len(line)+5, line)
+	}
+	buf.WriteString("0000")
+	return buf.String()
+}
+
+var _ = Describe("Git", func() {
+	var server *ghttp.Server
+	var repository version.GitRepository
+	var advertisement string
+	var requests []*http.Request
+	BeforeEach(func() {
+		requests = nil
+		advertisement = gitAdvertisement(
+			gitCommitB+" HEAD\x00multi_ack thin-pack side-band symref=HEAD:refs/heads/master agent=git/2.20.1",
+			gitCommitB+" refs/heads/master",
+			gitCommitA+" refs/tags/1.0.0",
+			gitTagB+" refs/tags/v1.1.0",
+			gitCommitB+" refs/tags/v1.1.0^{}",
+		)
+		server = ghttp.NewServer()
+		server.RouteToHandler(http.MethodGet, "/group/project.git/info/refs", func(resp http.ResponseWriter, req *http.Request) {
+			requests = append(requests, req)
+			resp.Header().Set("Content-Type", "application/x-git-upload-pack-advertisement")
+			fmt.Fprint(resp, advertisement)
+		})
+		repository = version.GitRepository{
+			URL: server.URL() + "/group/project.git",
+			App: "Project",
+		}
+	})
+	AfterEach(func() {
+		server.Close()
+	})
+	fetch := func() ([]avro.ApplicationVersionAvailable, error) {
+		versions := make(chan avro.ApplicationVersionAvailable, 100)
+		err := version.NewGitTagsFetcher(http.DefaultClient, repository).Fetch(context.Background(), versions)
+		close(versions)
+		var list []avro.ApplicationVersionAvailable
+		for version := range versions {
+			list = append(list, version)
+		}
+		return list, err
+	}
+	It("returns each tag once as version", func() {
+		list, err := fetch()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(list).To(HaveLen(2))
+		Expect(list[0].App).To(Equal("Project"))
+		Expect(list[0].Version).To(Equal("1.0.0"))
+		Expect(list[1].Version).To(Equal("v1.1.0"))
+		Expect(list[1].Stable).To(BeTrue())
+	})
+	It("requests the upload pack advertisement", func() {
+		_, err := fetch()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(requests).To(HaveLen(1))
+		Expect(requests[0].URL.Query().Get("service")).To(Equal("git-upload-pack"))
+	})
+	It("returns error if repository does not exist", func() {
+		server.AllowUnhandledRequests = true
+		repository.URL = server.URL() + "/banana.git"
+		_, err := fetch()
+		Expect(err).To(HaveOccurred())
+	})
+	It("returns error on invalid advertisement", func() {
+		advertisement = "zzzz"
+		_, err := fetch()
+		Expect(err).To(HaveOccurred())
+	})
+	It("returns error on protocol version 2", func() {
+		advertisement = gitAdvertisement("version 2", "ls-refs")
+		_, err := fetch()
+		Expect(err).To(HaveOccurred())
+	})
+	Context("ParseGitRefs", func() {
+		It("returns annotated tags once", func() {
+			refs, err := version.ParseGitRefs(bytes.NewBufferString(advertisement))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(refs).To(HaveLen(5 - 1))
+			Expect(refs[0]).To(Equal(version.GitRef{Name: "HEAD"}))
+			Expect(refs[2]).To(Equal(version.GitRef{Name: "refs/tags/1.0.0"}))
+			Expect(refs[3]).To(Equal(version.GitRef{Name: "refs/tags/v1.1.0"}))
+		})
+		It("returns no refs of empty repository", func() {
+			refs, err := version.ParseGitRefs(bytes.NewBufferString(gitAdvertisement()))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(refs).To(BeEmpty())
+		})
+	})
+	Context("ParseGitDumbRefs", func() {
+		It("returns annotated tags once", func() {
+			refs, err := version.ParseGitDumbRefs(bytes.NewBufferString(gitTagB + "\trefs/tags/v1.1.0\n" + gitCommitB + "\trefs/tags/v1.1.0^{}\n"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(refs).To(Equal([]version.GitRef{{Name: "refs/tags/v1.1.0"}}))
+		})
+	})
+})
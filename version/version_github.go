@@ -0,0 +1,223 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/bborbe/kafka-k8s-version-collector/avro"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+// GitHubAPI is the url of the public GitHub API.
+const GitHubAPI = "https://api.github.com"
+
+// gitHubMaxPageSize is the max number of entries GitHub returns per page.
+const gitHubMaxPageSize = 100
+
+// GitHubRepository describes a repository on GitHub or GitHub Enterprise.
+type GitHubRepository struct {
+	// API is the url of the GitHub API, like https://api.github.com or https://github.example.com/api/v3.
+	API string
+	// Repository is owner and name, like grafana/grafana.
+	Repository string
+	App        string
+}
+
+// GitHubRelease is a release as returned by the GitHub API and in release webhooks.
+type GitHubRelease struct {
+	TagName     string     `json:"tag_name"`
+	HTMLURL     string     `json:"html_url"`
+	Draft       bool       `json:"draft"`
+	Prerelease  bool       `json:"prerelease"`
+	PublishedAt *time.Time `json:"published_at"`
+}
+
+// Channels of GitHub releases not published as stable release.
+const (
+	GitHubChannelDraft      = "draft"
+	GitHubChannelPrerelease = "prerelease"
+)
+
+// NewGitHubReleaseVersion returns the record of the given release.
+// Drafts and releases marked as prerelease on GitHub are never stable, they are published with channel draft or prerelease.
+// The channel changes once the release is published as stable release, so it is published again.
+func NewGitHubReleaseVersion(app string, release GitHubRelease) avro.ApplicationVersionAvailable {
+	result := NewApplicationVersionAvailable(app, release.TagName)
+	switch {
+	case release.Draft:
+		result.Stable = false
+		result.Channel = GitHubChannelDraft
+	case release.Prerelease:
+		result.Stable = false
+		result.Channel = GitHubChannelPrerelease
+	}
+	if release.PublishedAt != nil {
+		result.PublishedAt = release.PublishedAt.UnixNano() / int64(time.Millisecond)
+	}
+	result.Url = release.HTMLURL
+	return result
+}
+
+// NewGitHubReleasesFetcher returns a Fetcher listing the releases of the repository.
+// Drafts are only visible with a token of a user with push access and skipped unless includeDrafts is set.
+func NewGitHubReleasesFetcher(
+	httpClient *http.Client,
+	pageSize int,
+	maxPages int,
+	includeDrafts bool,
+	repository GitHubRepository,
+) Fetcher {
+	return &gitHubFetcher{
+		httpClient: httpClient,
+		pageSize:   pageSize,
+		maxPages:   maxPages,
+		repository: repository,
+		path:       "releases",
+		decode: func(r io.Reader) ([]avro.ApplicationVersionAvailable, error) {
+			var releases []GitHubRelease
+			if err := json.NewDecoder(r).Decode(&releases); err != nil {
+				return nil, errors.Wrap(err, "decode json failed")
+			}
+			var result []avro.ApplicationVersionAvailable
+			for _, release := range releases {
+				if release.Draft && !includeDrafts {
+					glog.V(3).Infof("skip draft %s of %s", release.TagName, repository.Repository)
+					continue
+				}
+				result = append(result, NewGitHubReleaseVersion(repository.App, release))
+			}
+			return result, nil
+		},
+	}
+}
+
+// NewGitHubTagsFetcher returns a Fetcher listing the tags of the repository.
+func NewGitHubTagsFetcher(
+	httpClient *http.Client,
+	pageSize int,
+	maxPages int,
+	repository GitHubRepository,
+) Fetcher {
+	return &gitHubFetcher{
+		httpClient: httpClient,
+		pageSize:   pageSize,
+		maxPages:   maxPages,
+		repository: repository,
+		path:       "tags",
+		decode: func(r io.Reader) ([]avro.ApplicationVersionAvailable, error) {
+			var tags []struct {
+				Name string `json:"name"`
+			}
+			if err := json.NewDecoder(r).Decode(&tags); err != nil {
+				return nil, errors.Wrap(err, "decode json failed")
+			}
+			var result []avro.ApplicationVersionAvailable
+			for _, tag := range tags {
+				result = append(result, NewApplicationVersionAvailable(repository.App, tag.Name))
+			}
+			return result, nil
+		},
+	}
+}
+
+type gitHubFetcher struct {
+	httpClient *http.Client
+	pageSize   int
+	maxPages   int
+	repository GitHubRepository
+	path       string
+	// decode returns the versions of a page.
+	decode func(r io.Reader) ([]avro.ApplicationVersionAvailable, error)
+}
+
+func (g *gitHubFetcher) Fetch(ctx context.Context, versions chan<- avro.ApplicationVersionAvailable) error {
+	url := fmt.Sprintf("%s/repos/%s/%s", strings.TrimSuffix(g.repository.API, "/"), g.repository.Repository, g.path)
+	if g.pageSize > 0 {
+		pageSize := g.pageSize
+		if pageSize > gitHubMaxPageSize {
+			pageSize = gitHubMaxPageSize
+		}
+		url = fmt.Sprintf("%s?per_page=%d", url, pageSize)
+	}
+	for page := 1; url != ""; page++ {
+		if g.maxPages > 0 && page > g.maxPages {
+			glog.Warningf("fetch %s of %s stopped after %d pages", g.path, g.repository.Repository, g.maxPages)
+			return nil
+		}
+		list, next, err := g.fetchPage(ctx, url)
+		if err != nil {
+			return errors.Wrapf(err, "fetch %s of %s page %d failed", g.path, g.repository.Repository, page)
+		}
+		for _, version := range list {
+			select {
+			case <-ctx.Done():
+				glog.Infof("context done => return")
+				return nil
+			case versions <- version:
+			}
+		}
+		url = next
+	}
+	return nil
+}
+
+// fetchPage returns the versions of the given url and the url of the next page if exists.
+func (g *gitHubFetcher) fetchPage(ctx context.Context, url string) ([]avro.ApplicationVersionAvailable, string, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "build request failed")
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	glog.V(1).Infof("%s %s", req.Method, req.URL.String())
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "request failed")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return nil, "", errors.Errorf("request status code %d != 2xx", resp.StatusCode)
+	}
+	list, err := g.decode(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	return list, NextLink(req.URL, resp.Header["Link"]), nil
+}
+
+// NewAuthorizationRoundTripper returns a http.RoundTripper setting the given Authorization header
+// on all requests without one to the host of baseURL. Requests to other hosts, like redirects to a CDN,
+// are sent without. An empty authorization leaves the requests unchanged.
+func NewAuthorizationRoundTripper(roundTripper http.RoundTripper, baseURL string, authorization string) http.RoundTripper {
+	if authorization == "" {
+		return roundTripper
+	}
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Host == "" {
+		glog.Warningf("host of %s unknown => send no authorization", baseURL)
+		return roundTripper
+	}
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Header.Get("Authorization") != "" || req.URL.Host != u.Host {
+			return roundTripper.RoundTrip(req)
+		}
+		return roundTripper.RoundTrip(withAuthorization(req, authorization))
+	})
+}
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (r roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return r(req)
+}
@@ -0,0 +1,186 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/bborbe/kafka-k8s-version-collector/avro"
+	"github.com/bborbe/kafka-k8s-version-collector/version"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+)
+
+var _ = Describe("GitHub", func() {
+	var server *ghttp.Server
+	var repository version.GitHubRepository
+	BeforeEach(func() {
+		server = ghttp.NewServer()
+		repository = version.GitHubRepository{
+			API:        server.URL(),
+			Repository: "grafana/grafana",
+			App:        "Grafana",
+		}
+	})
+	AfterEach(func() {
+		server.Close()
+	})
+	fetch := func(fetcher version.Fetcher) ([]avro.ApplicationVersionAvailable, error) {
+		versions := make(chan avro.ApplicationVersionAvailable)
+		var list []avro.ApplicationVersionAvailable
+		var err error
+		go func() {
+			defer close(versions)
+			err = fetcher.Fetch(context.Background(), versions)
+		}()
+		for version := range versions {
+			list = append(list, version)
+		}
+		return list, err
+	}
+	Context("releases", func() {
+		var fetcher version.Fetcher
+		var includeDrafts bool
+		BeforeEach(func() {
+			includeDrafts = false
+			server.RouteToHandler(http.MethodGet, "/repos/grafana/grafana/releases", func(resp http.ResponseWriter, req *http.Request) {
+				if req.URL.Query().Get("page") == "2" {
+					fmt.Fprint(resp, `[{"tag_name":"v5.4.0","html_url":"https://github.com/grafana/grafana/releases/tag/v5.4.0","published_at":"2018-12-04T10:00:00Z"}]`)
+					return
+				}
+				resp.Header().Set("Link", fmt.Sprintf(`<%s/repos/grafana/grafana/releases?per_page=100&page=2>; rel="next"`, server.URL()))
+				fmt.Fprint(resp, `[
+					{"tag_name":"v6.1.0","html_url":"https://github.com/grafana/grafana/releases/tag/v6.1.0","draft":true,"published_at":null},
+					{"tag_name":"v6.0.0","html_url":"https://github.com/grafana/grafana/releases/tag/v6.0.0","published_at":"2019-02-25T12:00:00Z"},
+					{"tag_name":"v6.0.0-beta1","html_url":"https://github.com/grafana/grafana/releases/tag/v6.0.0-beta1","prerelease":true,"published_at":"2019-01-30T12:00:00Z"},
+					{"tag_name":"v6.0.0","html_url":"https://github.com/grafana/grafana/releases/tag/v6.0.0","prerelease":true,"published_at":"2019-02-25T12:00:00Z"}
+				]`)
+			})
+		})
+		JustBeforeEach(func() {
+			fetcher = version.NewGitHubReleasesFetcher(http.DefaultClient, 100, 10, includeDrafts, repository)
+		})
+		It("returns releases of all pages", func() {
+			list, err := fetch(fetcher)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(list).To(HaveLen(4))
+			Expect(list[0].App).To(Equal("Grafana"))
+			Expect(list[0].Version).To(Equal("v6.0.0"))
+			Expect(list[3].Version).To(Equal("v5.4.0"))
+			Expect(server.ReceivedRequests()[0].URL.Query().Get("per_page")).To(Equal("100"))
+			Expect(server.ReceivedRequests()[0].Header.Get("Accept")).To(Equal("application/vnd.github.v3+json"))
+		})
+		It("returns publish time and url", func() {
+			list, err := fetch(fetcher)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(list[0].PublishedAt).To(Equal(time.Date(2019, 2, 25, 12, 0, 0, 0, time.UTC).UnixNano() / int64(time.Millisecond)))
+			Expect(list[0].Url).To(Equal("https://github.com/grafana/grafana/releases/tag/v6.0.0"))
+			Expect(list[0].Stable).To(BeTrue())
+		})
+		It("marks prereleases as not stable", func() {
+			list, err := fetch(fetcher)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(list[1].Version).To(Equal("v6.0.0-beta1"))
+			Expect(list[1].Stable).To(BeFalse())
+			Expect(list[2].Version).To(Equal("v6.0.0"))
+			Expect(list[2].Parsed).To(BeTrue())
+			Expect(list[2].Stable).To(BeFalse())
+			Expect(list[2].Channel).To(Equal(version.GitHubChannelPrerelease))
+			Expect(list[0].Channel).To(Equal(""))
+		})
+		Context("with drafts", func() {
+			BeforeEach(func() {
+				includeDrafts = true
+			})
+			It("returns drafts", func() {
+				list, err := fetch(fetcher)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(list).To(HaveLen(5))
+				Expect(list[0].Version).To(Equal("v6.1.0"))
+				Expect(list[0].PublishedAt).To(BeZero())
+				Expect(list[0].Stable).To(BeFalse())
+				Expect(list[0].Channel).To(Equal(version.GitHubChannelDraft))
+			})
+		})
+		It("stops after max pages", func() {
+			list, err := fetch(version.NewGitHubReleasesFetcher(http.DefaultClient, 100, 1, false, repository))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(list).To(HaveLen(3))
+		})
+		It("returns error if request fails", func() {
+			server.RouteToHandler(http.MethodGet, "/repos/grafana/grafana/releases", ghttp.RespondWith(http.StatusNotFound, `{"message":"Not Found"}`))
+			_, err := fetch(fetcher)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Context("tags", func() {
+		It("returns tags", func() {
+			server.RouteToHandler(http.MethodGet, "/repos/grafana/grafana/tags", func(resp http.ResponseWriter, req *http.Request) {
+				fmt.Fprint(resp, `[{"name":"v6.0.0"},{"name":"v5.4.0"}]`)
+			})
+			list, err := fetch(version.NewGitHubTagsFetcher(http.DefaultClient, 0, 0, repository))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(list).To(HaveLen(2))
+			Expect(list[0].Version).To(Equal("v6.0.0"))
+			Expect(list[0].Url).To(BeEmpty())
+		})
+	})
+	Context("source", func() {
+		It("sends token of credentials", func() {
+			server.RouteToHandler(http.MethodGet, "/repos/grafana/grafana/releases", func(resp http.ResponseWriter, req *http.Request) {
+				fmt.Fprint(resp, `[]`)
+			})
+			config := &version.Config{
+				Credentials: map[string]version.CredentialsConfig{
+					"github": {Password: "my-token"},
+				},
+			}
+			fetcher, err := version.NewSourceFetcher(http.DefaultTransport, config, version.SourceConfig{
+				Name:        "grafana",
+				Type:        version.SourceTypeGitHub,
+				URL:         server.URL(),
+				Repository:  "grafana/grafana",
+				App:         "Grafana",
+				Credentials: "github",
+			})
+			Expect(err).NotTo(HaveOccurred())
+			_, err = fetch(fetcher)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(server.ReceivedRequests()[0].Header.Get("Authorization")).To(Equal("Bearer my-token"))
+		})
+		It("sends no token to redirect target on other host", func() {
+			other := ghttp.NewServer()
+			defer other.Close()
+			other.RouteToHandler(http.MethodGet, "/releases", func(resp http.ResponseWriter, req *http.Request) {
+				fmt.Fprint(resp, `[]`)
+			})
+			server.RouteToHandler(http.MethodGet, "/repos/grafana/grafana/releases", func(resp http.ResponseWriter, req *http.Request) {
+				http.Redirect(resp, req, other.URL()+"/releases", http.StatusFound)
+			})
+			fetcher, err := version.NewSourceFetcher(http.DefaultTransport, &version.Config{
+				Credentials: map[string]version.CredentialsConfig{
+					"github": {Password: "my-token"},
+				},
+			}, version.SourceConfig{
+				Name:        "grafana",
+				Type:        version.SourceTypeGitHub,
+				URL:         server.URL(),
+				Repository:  "grafana/grafana",
+				App:         "Grafana",
+				Credentials: "github",
+			})
+			Expect(err).NotTo(HaveOccurred())
+			_, err = fetch(fetcher)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(server.ReceivedRequests()[0].Header.Get("Authorization")).To(Equal("Bearer my-token"))
+			Expect(other.ReceivedRequests()).To(HaveLen(1))
+			Expect(other.ReceivedRequests()[0].Header.Get("Authorization")).To(Equal(""))
+		})
+	})
+})
@@ -0,0 +1,167 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+// Check tests one dependency of the collector.
+type Check struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// NewHealthzHandler returns a http.Handler that reports the process is up.
+func NewHealthzHandler() http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		fmt.Fprintln(resp, "ok")
+	})
+}
+
+// NewReadinessHandler returns a http.Handler that reports ready if all checks pass.
+// Otherwise it responds with status 503 and the failed checks.
+func NewReadinessHandler(timeout time.Duration, checks ...Check) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithTimeout(req.Context(), timeout)
+		defer cancel()
+		var failed []string
+		for _, check := range checks {
+			if err := check.Check(ctx); err != nil {
+				glog.V(1).Infof("readiness check %s failed: %v", check.Name, err)
+				failed = append(failed, fmt.Sprintf("%s: %v", check.Name, err))
+			}
+		}
+		if len(failed) > 0 {
+			resp.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprintln(resp, strings.Join(failed, "\n"))
+			return
+		}
+		fmt.Fprintln(resp, "ok")
+	})
+}
+
+// NewKafkaCheck returns a Check that passes if the metadata of the cluster can be refreshed.
+// Brokers are connected lazily, so the connection state alone does not tell if Kafka is reachable.
+func NewKafkaCheck(client sarama.Client) Check {
+	return Check{
+		Name: "kafka",
+		Check: func(ctx context.Context) error {
+			if client.Closed() {
+				return errors.New("client closed")
+			}
+			refreshed := make(chan error, 1)
+			go func() {
+				refreshed <- client.RefreshMetadata()
+			}()
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case err := <-refreshed:
+				return errors.Wrap(err, "refresh metadata failed")
+			}
+		},
+	}
+}
+
+// NewSchemaRegistryCheck returns a Check that passes if the schema registry lists its subjects.
+func NewSchemaRegistryCheck(httpClient *http.Client, schemaRegistryUrl string) Check {
+	return Check{
+		Name: "schema-registry",
+		Check: func(ctx context.Context) error {
+			req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(schemaRegistryUrl, "/")+"/subjects", nil)
+			if err != nil {
+				return errors.Wrap(err, "build request failed")
+			}
+			resp, err := httpClient.Do(req.WithContext(ctx))
+			if err != nil {
+				return errors.Wrap(err, "request failed")
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode/100 != 2 {
+				return errors.Errorf("request status code %d != 2xx", resp.StatusCode)
+			}
+			return nil
+		},
+	}
+}
+
+// NewLastSuccessfulSync returns a LastSuccessfulSync starting with the given time,
+// so the collector is not reported as stale before the first sync had a chance to run.
+func NewLastSuccessfulSync(start time.Time) *LastSuccessfulSync {
+	return &LastSuccessfulSync{
+		time: start,
+	}
+}
+
+// LastSuccessfulSync remembers when a sync succeeded the last time.
+type LastSuccessfulSync struct {
+	mux  sync.Mutex
+	time time.Time
+}
+
+// Set records the time of a successful sync.
+func (l *LastSuccessfulSync) Set(syncTime time.Time) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	if syncTime.After(l.time) {
+		l.time = syncTime
+	}
+}
+
+// Get returns the time of the last successful sync.
+func (l *LastSuccessfulSync) Get() time.Time {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	return l.time
+}
+
+// Runner returns a Runner recording the finish time of each successful run of the given runner.
+func (l *LastSuccessfulSync) Runner(runner Runner) Runner {
+	return runnerFunc(func(ctx context.Context, trigger string, sources ...string) (SyncRun, error) {
+		run, err := runner.Run(ctx, trigger, sources...)
+		if err == nil && run.Status == SyncStatusSuccess {
+			l.Set(run.Finished)
+		}
+		return run, err
+	})
+}
+
+// readinessSyncWindowFactor is the number of the longest schedule intervals a sync may be missing.
+const readinessSyncWindowFactor = 3
+
+// ReadinessSyncWindow returns three times the longest interval between two scheduled runs of the sources,
+// so sources on a daily cron do not make the collector not ready.
+func ReadinessSyncWindow(sources []SourceConfig, now time.Time) time.Duration {
+	var result time.Duration
+	for _, source := range sources {
+		if interval := source.Schedule.Interval(now); interval > result {
+			result = interval
+		}
+	}
+	return readinessSyncWindowFactor * result
+}
+
+// Check returns a Check that passes if a sync succeeded within the given window.
+func (l *LastSuccessfulSync) Check(window time.Duration) Check {
+	return Check{
+		Name: "sync",
+		Check: func(ctx context.Context) error {
+			if last := l.Get(); time.Since(last) > window {
+				return errors.Errorf("no successful sync since %s", last.Format(time.RFC3339))
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/bborbe/kafka-k8s-version-collector/mocks"
+	"github.com/bborbe/kafka-k8s-version-collector/version"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+)
+
+var _ = Describe("Health", func() {
+	passing := version.Check{
+		Name:  "passing",
+		Check: func(ctx context.Context) error { return nil },
+	}
+	failing := version.Check{
+		Name:  "failing",
+		Check: func(ctx context.Context) error { return errors.New("banana") },
+	}
+	serve := func(handler http.Handler) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+		return recorder
+	}
+	It("reports healthz ok", func() {
+		recorder := serve(version.NewHealthzHandler())
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Body.String()).To(Equal("ok\n"))
+	})
+	It("reports ready if all checks pass", func() {
+		recorder := serve(version.NewReadinessHandler(time.Second, passing, passing))
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+	})
+	It("reports not ready with failed checks", func() {
+		recorder := serve(version.NewReadinessHandler(time.Second, passing, failing))
+		Expect(recorder.Code).To(Equal(http.StatusServiceUnavailable))
+		Expect(recorder.Body.String()).To(Equal("failing: banana\n"))
+	})
+
+	Context("kafka check", func() {
+		var broker *sarama.MockBroker
+		var client sarama.Client
+		BeforeEach(func() {
+			var t GinkgoTestReporter
+			broker = sarama.NewMockBroker(t, 1)
+			broker.SetHandlerByMap(map[string]sarama.MockResponse{
+				"MetadataRequest": sarama.NewMockMetadataResponse(t).SetBroker(broker.Addr(), broker.BrokerID()),
+			})
+			config := sarama.NewConfig()
+			config.Metadata.Retry.Max = 0
+			var err error
+			client, err = sarama.NewClient([]string{broker.Addr()}, config)
+			Expect(err).NotTo(HaveOccurred())
+		})
+		AfterEach(func() {
+			client.Close()
+		})
+		It("passes if metadata is refreshed", func() {
+			Expect(version.NewKafkaCheck(client).Check(context.Background())).To(BeNil())
+			broker.Close()
+		})
+		It("fails if broker is gone", func() {
+			broker.Close()
+			Expect(version.NewKafkaCheck(client).Check(context.Background())).To(HaveOccurred())
+		})
+		It("fails if client is closed", func() {
+			broker.Close()
+			client.Close()
+			Expect(version.NewKafkaCheck(client).Check(context.Background())).To(MatchError("client closed"))
+		})
+	})
+
+	Context("readiness sync window", func() {
+		It("returns three times the longest source interval", func() {
+			now := time.Date(2019, 3, 1, 12, 3, 0, 0, time.UTC)
+			Expect(version.ReadinessSyncWindow([]version.SourceConfig{
+				{Schedule: version.ScheduleConfig{Wait: time.Hour}},
+				{Schedule: version.ScheduleConfig{Cron: "0 0 6 * * *"}},
+			}, now)).To(Equal(72 * time.Hour))
+		})
+	})
+
+	Context("schema registry check", func() {
+		var server *ghttp.Server
+		BeforeEach(func() {
+			server = ghttp.NewServer()
+		})
+		AfterEach(func() {
+			server.Close()
+		})
+		It("passes if subjects are listed", func() {
+			server.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyRequest(http.MethodGet, "/subjects"),
+				ghttp.RespondWith(http.StatusOK, `[]`),
+			))
+			check := version.NewSchemaRegistryCheck(http.DefaultClient, server.URL()+"/")
+			Expect(check.Check(context.Background())).To(BeNil())
+		})
+		It("fails on error status", func() {
+			server.AppendHandlers(ghttp.RespondWith(http.StatusInternalServerError, ""))
+			check := version.NewSchemaRegistryCheck(http.DefaultClient, server.URL())
+			Expect(check.Check(context.Background())).To(HaveOccurred())
+		})
+	})
+
+	Context("last successful sync", func() {
+		var lastSuccessfulSync *version.LastSuccessfulSync
+		var start time.Time
+		BeforeEach(func() {
+			start = time.Now().Add(-time.Hour)
+			lastSuccessfulSync = version.NewLastSuccessfulSync(start)
+		})
+		It("passes within window", func() {
+			Expect(lastSuccessfulSync.Check(2 * time.Hour).Check(context.Background())).To(BeNil())
+		})
+		It("fails after window", func() {
+			Expect(lastSuccessfulSync.Check(time.Minute).Check(context.Background())).To(HaveOccurred())
+		})
+		It("records successful run", func() {
+			finished := time.Now()
+			runner := &mocks.Runner{}
+			runner.RunReturns(version.SyncRun{Status: version.SyncStatusSuccess, Finished: finished}, nil)
+			_, err := lastSuccessfulSync.Runner(runner).Run(context.Background(), version.SyncTriggerManual)
+			Expect(err).To(BeNil())
+			Expect(runner.RunCallCount()).To(Equal(1))
+			Expect(lastSuccessfulSync.Get()).To(Equal(finished))
+		})
+		It("ignores failed run", func() {
+			runner := &mocks.Runner{}
+			runner.RunReturns(version.SyncRun{Status: version.SyncStatusFailure, Finished: time.Now()}, nil)
+			_, err := lastSuccessfulSync.Runner(runner).Run(context.Background(), version.SyncTriggerManual)
+			Expect(err).To(BeNil())
+			Expect(lastSuccessfulSync.Get()).To(Equal(start))
+		})
+	})
+})
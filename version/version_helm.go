@@ -0,0 +1,154 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bborbe/kafka-k8s-version-collector/avro"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// HelmChart describes a chart in a Helm repository.
+type HelmChart struct {
+	// Repository is the url of the Helm repository containing the index.yaml.
+	Repository string
+	Chart      string
+	App        string
+	// AppVersionApp publishes the appVersions of the chart as separate versions of this app if set.
+	AppVersionApp string
+}
+
+// helmIndex contains the fields of a Helm repository index.yaml used by the fetcher.
+type helmIndex struct {
+	Entries map[string][]helmChartVersion `yaml:"entries"`
+}
+
+type helmChartVersion struct {
+	Version    string    `yaml:"version"`
+	AppVersion string    `yaml:"appVersion"`
+	Created    time.Time `yaml:"created"`
+	URLs       []string  `yaml:"urls"`
+}
+
+// NewHelmFetcher returns a Fetcher listing the versions of a chart in a Helm repository index.
+// Each chart version is published with its appVersion. The index is requested with
+// If-None-Match and If-Modified-Since, versions of the last download are reused if it is unchanged.
+func NewHelmFetcher(
+	httpClient *http.Client,
+	chart HelmChart,
+) Fetcher {
+	return &helmFetcher{
+		httpClient: httpClient,
+		chart:      chart,
+	}
+}
+
+type helmFetcher struct {
+	httpClient *http.Client
+	chart      HelmChart
+
+	mux          sync.Mutex
+	etag         string
+	lastModified string
+	versions     []avro.ApplicationVersionAvailable
+}
+
+func (h *helmFetcher) Fetch(ctx context.Context, versions chan<- avro.ApplicationVersionAvailable) error {
+	list, err := h.fetchIndex(ctx)
+	if err != nil {
+		return errors.Wrapf(err, "fetch index of %s failed", h.chart.Repository)
+	}
+	for _, version := range list {
+		select {
+		case <-ctx.Done():
+			glog.Infof("context done => return")
+			return nil
+		case versions <- version:
+		}
+	}
+	return nil
+}
+
+// fetchIndex returns the versions of the chart, from the cache if the index is not modified.
+func (h *helmFetcher) fetchIndex(ctx context.Context) ([]avro.ApplicationVersionAvailable, error) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	indexURL := HelmIndexURL(h.chart.Repository)
+	req, err := http.NewRequest(http.MethodGet, indexURL, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "build request failed")
+	}
+	req = req.WithContext(ctx)
+	if h.etag != "" {
+		req.Header.Set("If-None-Match", h.etag)
+	}
+	if h.lastModified != "" {
+		req.Header.Set("If-Modified-Since", h.lastModified)
+	}
+	glog.V(1).Infof("%s %s", req.Method, req.URL.String())
+	resp, err := h.httpClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "request failed")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified {
+		glog.V(2).Infof("index %s not modified => use %d cached versions", indexURL, len(h.versions))
+		return h.versions, nil
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, errors.Errorf("request status code %d != 2xx", resp.StatusCode)
+	}
+	var index helmIndex
+	if err := yaml.NewDecoder(resp.Body).Decode(&index); err != nil {
+		return nil, errors.Wrap(err, "decode yaml failed")
+	}
+	entries, ok := index.Entries[h.chart.Chart]
+	if !ok {
+		return nil, errors.Errorf("chart %s not found in index", h.chart.Chart)
+	}
+	h.versions = h.chartVersions(req.URL, entries)
+	h.etag = resp.Header.Get("ETag")
+	h.lastModified = resp.Header.Get("Last-Modified")
+	return h.versions, nil
+}
+
+func (h *helmFetcher) chartVersions(indexURL *url.URL, entries []helmChartVersion) []avro.ApplicationVersionAvailable {
+	var result []avro.ApplicationVersionAvailable
+	appVersions := make(map[string]bool)
+	for _, entry := range entries {
+		version := NewApplicationVersionAvailable(h.chart.App, entry.Version)
+		version.AppVersion = entry.AppVersion
+		if !entry.Created.IsZero() {
+			version.PublishedAt = entry.Created.UnixNano() / int64(time.Millisecond)
+		}
+		if len(entry.URLs) > 0 {
+			if u, err := indexURL.Parse(entry.URLs[0]); err == nil {
+				version.Url = u.String()
+			}
+		}
+		result = append(result, version)
+		if h.chart.AppVersionApp == "" || entry.AppVersion == "" || appVersions[entry.AppVersion] {
+			continue
+		}
+		appVersions[entry.AppVersion] = true
+		appVersion := NewApplicationVersionAvailable(h.chart.AppVersionApp, entry.AppVersion)
+		appVersion.AppVersion = entry.AppVersion
+		result = append(result, appVersion)
+	}
+	return result
+}
+
+// HelmIndexURL returns the url of the index.yaml of the given Helm repository.
+func HelmIndexURL(repository string) string {
+	return strings.TrimSuffix(repository, "/") + "/index.yaml"
+}
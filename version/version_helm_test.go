@@ -0,0 +1,151 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/bborbe/kafka-k8s-version-collector/avro"
+	"github.com/bborbe/kafka-k8s-version-collector/version"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+)
+
+const helmIndex = `apiVersion: v1
+entries:
+  ingress-nginx:
+  - apiVersion: v1
+    appVersion: 0.23.0
+    created: 2019-03-05T10:00:00.000000000Z
+    name: ingress-nginx
+    urls:
+    - ingress-nginx-1.3.1.tgz
+    version: 1.3.1
+  - apiVersion: v1
+    appVersion: 0.23.0
+    created: 2019-03-01T10:00:00.000000000Z
+    name: ingress-nginx
+    urls:
+    - https://charts.example.com/ingress-nginx-1.3.0.tgz
+    version: 1.3.0
+  - apiVersion: v1
+    appVersion: 0.22.0
+    created: 2019-02-01T10:00:00.000000000Z
+    name: ingress-nginx
+    version: 1.2.0
+  cert-manager:
+  - appVersion: v0.7.0
+    name: cert-manager
+    version: v0.7.0
+generated: 2019-03-05T10:00:00.000000000Z
+`
+
+var _ = Describe("Helm", func() {
+	var server *ghttp.Server
+	var chart version.HelmChart
+	var fetcher version.Fetcher
+	var requests []*http.Request
+	BeforeEach(func() {
+		requests = nil
+		server = ghttp.NewServer()
+		server.RouteToHandler(http.MethodGet, "/stable/index.yaml", func(resp http.ResponseWriter, req *http.Request) {
+			requests = append(requests, req)
+			if req.Header.Get("If-None-Match") == `"1234"` {
+				resp.WriteHeader(http.StatusNotModified)
+				return
+			}
+			resp.Header().Set("ETag", `"1234"`)
+			resp.Header().Set("Last-Modified", "Tue, 05 Mar 2019 10:00:00 GMT")
+			fmt.Fprint(resp, helmIndex)
+		})
+		chart = version.HelmChart{
+			Repository: server.URL() + "/stable/",
+			Chart:      "ingress-nginx",
+			App:        "Ingress Nginx Chart",
+		}
+	})
+	JustBeforeEach(func() {
+		fetcher = version.NewHelmFetcher(http.DefaultClient, chart)
+	})
+	AfterEach(func() {
+		server.Close()
+	})
+	fetch := func() ([]avro.ApplicationVersionAvailable, error) {
+		versions := make(chan avro.ApplicationVersionAvailable, 100)
+		err := fetcher.Fetch(context.Background(), versions)
+		close(versions)
+		var list []avro.ApplicationVersionAvailable
+		for version := range versions {
+			list = append(list, version)
+		}
+		return list, err
+	}
+	It("returns chart versions with app version", func() {
+		list, err := fetch()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(list).To(HaveLen(3))
+		Expect(list[0].App).To(Equal("Ingress Nginx Chart"))
+		Expect(list[0].Version).To(Equal("1.3.1"))
+		Expect(list[0].AppVersion).To(Equal("0.23.0"))
+		Expect(list[0].Stable).To(BeTrue())
+		Expect(list[0].PublishedAt).To(Equal(time.Date(2019, 3, 5, 10, 0, 0, 0, time.UTC).UnixNano() / int64(time.Millisecond)))
+	})
+	It("returns absolute chart url", func() {
+		list, err := fetch()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(list[0].Url).To(Equal(server.URL() + "/stable/ingress-nginx-1.3.1.tgz"))
+		Expect(list[1].Url).To(Equal("https://charts.example.com/ingress-nginx-1.3.0.tgz"))
+		Expect(list[2].Url).To(BeEmpty())
+	})
+	It("reuses versions if index is not modified", func() {
+		_, err := fetch()
+		Expect(err).NotTo(HaveOccurred())
+		list, err := fetch()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(list).To(HaveLen(3))
+		Expect(requests).To(HaveLen(2))
+		Expect(requests[0].Header.Get("If-None-Match")).To(BeEmpty())
+		Expect(requests[1].Header.Get("If-None-Match")).To(Equal(`"1234"`))
+		Expect(requests[1].Header.Get("If-Modified-Since")).To(Equal("Tue, 05 Mar 2019 10:00:00 GMT"))
+	})
+	It("returns error if chart is not in index", func() {
+		chart.Chart = "banana"
+		fetcher = version.NewHelmFetcher(http.DefaultClient, chart)
+		_, err := fetch()
+		Expect(err).To(HaveOccurred())
+	})
+	It("returns error if index is missing", func() {
+		server.AllowUnhandledRequests = true
+		chart.Repository = server.URL() + "/banana"
+		fetcher = version.NewHelmFetcher(http.DefaultClient, chart)
+		_, err := fetch()
+		Expect(err).To(HaveOccurred())
+	})
+	Context("with app version app", func() {
+		BeforeEach(func() {
+			chart.AppVersionApp = "Ingress Nginx"
+		})
+		It("returns each app version once as separate version", func() {
+			list, err := fetch()
+			Expect(err).NotTo(HaveOccurred())
+			var appVersions []string
+			for _, v := range list {
+				if v.App == "Ingress Nginx" {
+					appVersions = append(appVersions, v.Version)
+				}
+			}
+			Expect(list).To(HaveLen(5))
+			Expect(appVersions).To(Equal([]string{"0.23.0", "0.22.0"}))
+		})
+	})
+	It("returns index url of repository", func() {
+		Expect(version.HelmIndexURL("https://charts.example.com/")).To(Equal("https://charts.example.com/index.yaml"))
+		Expect(version.HelmIndexURL("https://charts.example.com")).To(Equal("https://charts.example.com/index.yaml"))
+	})
+})
@@ -0,0 +1,118 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// NewHistory returns a History keeping the given number of most recent runs.
+func NewHistory(size int) *History {
+	return &History{
+		size:          size,
+		lastResults:   make(map[string]SourceRun),
+		lastScheduled: make(map[string]time.Time),
+	}
+}
+
+// History keeps the recent runs in memory and the last result of each source.
+type History struct {
+	mux           sync.Mutex
+	size          int
+	runs          []SyncRun
+	lastResults   map[string]SourceRun
+	lastScheduled map[string]time.Time
+}
+
+// SourceRun is the result of a source within a run.
+type SourceRun struct {
+	RunID string `json:"runId"`
+	SourceResult
+	Duration string `json:"duration"`
+}
+
+// Add records the finished run. Runs already recorded are ignored.
+// Results without trigger are recorded with the trigger of the run.
+func (h *History) Add(run SyncRun) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	for _, existing := range h.runs {
+		if existing.ID == run.ID {
+			return
+		}
+	}
+	if h.size > 0 {
+		h.runs = append([]SyncRun{run}, h.runs...)
+		if len(h.runs) > h.size {
+			h.runs = h.runs[:h.size]
+		}
+	}
+	for _, result := range run.Results {
+		if last, ok := h.lastResults[result.Source]; ok && last.Started.After(result.Started) {
+			continue
+		}
+		if result.Trigger == "" {
+			result.Trigger = run.Trigger
+		}
+		h.lastResults[result.Source] = SourceRun{
+			RunID:        run.ID,
+			SourceResult: result,
+			Duration:     result.Finished.Sub(result.Started).String(),
+		}
+	}
+}
+
+// addScheduled records the finish of a scheduled call of the run, the schedule waits from there for the next run.
+// A scheduled call joining a manual run is recorded as well.
+func (h *History) addScheduled(run SyncRun) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	for _, source := range run.Sources {
+		if run.Finished.After(h.lastScheduled[source]) {
+			h.lastScheduled[source] = run.Finished
+		}
+	}
+}
+
+// Runs returns the recorded runs, newest first.
+func (h *History) Runs() []SyncRun {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	result := make([]SyncRun, len(h.runs))
+	copy(result, h.runs)
+	return result
+}
+
+// LastResult returns the result of the last run of the given source.
+func (h *History) LastResult(source string) (SourceRun, bool) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	result, ok := h.lastResults[source]
+	return result, ok
+}
+
+// LastScheduled returns the finish time of the last run of the given source called by the schedule.
+func (h *History) LastScheduled(source string) time.Time {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	return h.lastScheduled[source]
+}
+
+// Runner returns a Runner recording each finished run of the given runner.
+// Runs called with the schedule trigger are recorded as scheduled, even if they joined a run of another trigger.
+func (h *History) Runner(runner Runner) Runner {
+	return runnerFunc(func(ctx context.Context, trigger string, sources ...string) (SyncRun, error) {
+		run, err := runner.Run(ctx, trigger, sources...)
+		if err == nil {
+			h.Add(run)
+			if trigger == SyncTriggerSchedule {
+				h.addScheduled(run)
+			}
+		}
+		return run, err
+	})
+}
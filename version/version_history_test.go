@@ -0,0 +1,129 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/bborbe/kafka-k8s-version-collector/mocks"
+	"github.com/bborbe/kafka-k8s-version-collector/version"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("History", func() {
+	var history *version.History
+	var started time.Time
+	BeforeEach(func() {
+		history = version.NewHistory(2)
+		started = time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC)
+	})
+	syncRun := func(id string, trigger string, started time.Time) version.SyncRun {
+		return version.SyncRun{
+			ID:       id,
+			Trigger:  trigger,
+			Sources:  []string{"grafana"},
+			Started:  started,
+			Finished: started.Add(time.Minute),
+			Status:   version.SyncStatusSuccess,
+			Results: []version.SourceResult{
+				{
+					Source:    "grafana",
+					Started:   started,
+					Finished:  started.Add(time.Minute),
+					Fetched:   10,
+					Published: 2,
+				},
+			},
+		}
+	}
+	It("returns runs newest first", func() {
+		history.Add(syncRun("1", version.SyncTriggerSchedule, started))
+		history.Add(syncRun("2", version.SyncTriggerManual, started.Add(time.Hour)))
+		runs := history.Runs()
+		Expect(runs).To(HaveLen(2))
+		Expect(runs[0].ID).To(Equal("2"))
+		Expect(runs[1].ID).To(Equal("1"))
+	})
+	It("keeps only the given number of runs", func() {
+		history.Add(syncRun("1", version.SyncTriggerSchedule, started))
+		history.Add(syncRun("2", version.SyncTriggerSchedule, started.Add(time.Hour)))
+		history.Add(syncRun("3", version.SyncTriggerSchedule, started.Add(2*time.Hour)))
+		runs := history.Runs()
+		Expect(runs).To(HaveLen(2))
+		Expect(runs[1].ID).To(Equal("2"))
+	})
+	It("records joined runs once", func() {
+		history.Add(syncRun("1", version.SyncTriggerSchedule, started))
+		history.Add(syncRun("1", version.SyncTriggerSchedule, started))
+		Expect(history.Runs()).To(HaveLen(1))
+	})
+	It("returns last result of source", func() {
+		history.Add(syncRun("1", version.SyncTriggerSchedule, started))
+		history.Add(syncRun("2", version.SyncTriggerManual, started.Add(time.Hour)))
+		result, ok := history.LastResult("grafana")
+		Expect(ok).To(BeTrue())
+		Expect(result.RunID).To(Equal("2"))
+		Expect(result.Fetched).To(Equal(10))
+		Expect(result.Published).To(Equal(2))
+		Expect(result.Duration).To(Equal("1m0s"))
+		Expect(result.Trigger).To(Equal(version.SyncTriggerManual))
+	})
+	It("returns trigger of run executing the source sync", func() {
+		run := syncRun("1", version.SyncTriggerManual, started)
+		run.Results[0].Trigger = version.SyncTriggerSchedule
+		history.Add(run)
+		result, ok := history.LastResult("grafana")
+		Expect(ok).To(BeTrue())
+		Expect(result.Trigger).To(Equal(version.SyncTriggerSchedule))
+	})
+	It("returns last scheduled run of source", func() {
+		runner := &mocks.Runner{}
+		runner.RunReturns(syncRun("1", version.SyncTriggerSchedule, started), nil)
+		_, err := history.Runner(runner).Run(context.Background(), version.SyncTriggerSchedule, "grafana")
+		Expect(err).NotTo(HaveOccurred())
+		runner.RunReturns(syncRun("2", version.SyncTriggerManual, started.Add(time.Hour)), nil)
+		_, err = history.Runner(runner).Run(context.Background(), version.SyncTriggerManual, "grafana")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(history.LastScheduled("grafana")).To(Equal(started.Add(time.Minute)))
+	})
+	It("returns scheduled run joining manual run as last scheduled run", func() {
+		runner := &mocks.Runner{}
+		runner.RunReturns(syncRun("1", version.SyncTriggerManual, started), nil)
+		_, err := history.Runner(runner).Run(context.Background(), version.SyncTriggerSchedule, "grafana")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(history.LastScheduled("grafana")).To(Equal(started.Add(time.Minute)))
+		result, _ := history.LastResult("grafana")
+		Expect(result.Trigger).To(Equal(version.SyncTriggerManual))
+	})
+	It("returns no scheduled run for manual run joining scheduled run", func() {
+		history.Add(syncRun("1", version.SyncTriggerSchedule, started))
+		Expect(history.LastScheduled("grafana")).To(BeZero())
+	})
+	It("returns status of sources", func() {
+		runner := &mocks.Runner{}
+		runner.RunReturns(syncRun("1", version.SyncTriggerSchedule, started), nil)
+		_, err := history.Runner(runner).Run(context.Background(), version.SyncTriggerSchedule, "grafana")
+		Expect(err).NotTo(HaveOccurred())
+		status := history.Status([]version.SourceConfig{
+			{Name: "grafana", Schedule: version.ScheduleConfig{Wait: time.Hour}},
+			{Name: "nginx", Schedule: version.ScheduleConfig{Wait: time.Hour}},
+		}, started.Add(time.Hour))
+		Expect(status.Sources).To(HaveLen(2))
+		Expect(status.Sources[0].LastRun).NotTo(BeNil())
+		Expect(*status.Sources[0].NextRun).To(Equal(started.Add(time.Hour + time.Minute)))
+		Expect(status.Sources[1].LastRun).To(BeNil())
+		Expect(status.Sources[1].NextRun).To(BeNil())
+		Expect(status.Runs).To(HaveLen(1))
+	})
+	It("records runs of runner", func() {
+		runner := &mocks.Runner{}
+		runner.RunReturns(syncRun("1", version.SyncTriggerManual, started), nil)
+		_, err := history.Runner(runner).Run(context.Background(), version.SyncTriggerManual)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(history.Runs()).To(HaveLen(1))
+	})
+})
@@ -0,0 +1,72 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Image identifies a repository in a OCI distribution registry and the app name its tags are published as.
+type Image struct {
+	Registry   string
+	Repository string
+	App        string
+}
+
+// TagsURL returns the url to list all tags of the image.
+func (i Image) TagsURL() string {
+	return i.Registry + "/v2/" + i.Repository + "/tags/list"
+}
+
+// ParseImages parses a comma separated list of images in the format app=registry/repository.
+// Example: Kubernetes=https://gcr.io/google_containers/hyperkube-amd64
+func ParseImages(value string) ([]Image, error) {
+	var result []Image
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		image, err := ParseImage(part)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *image)
+	}
+	return result, nil
+}
+
+// ParseImage parses a image in the format app=registry/repository.
+// Registries without scheme default to https.
+func ParseImage(value string) (*Image, error) {
+	pos := strings.Index(value, "=")
+	if pos == -1 {
+		return nil, errors.Errorf("parse image '%s' failed, expected format app=registry/repository", value)
+	}
+	app := strings.TrimSpace(value[:pos])
+	if app == "" {
+		return nil, errors.Errorf("parse image '%s' failed, app is empty", value)
+	}
+	location := strings.TrimSpace(value[pos+1:])
+	if !strings.Contains(location, "://") {
+		location = "https://" + location
+	}
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse image '%s' failed", value)
+	}
+	repository := strings.Trim(u.Path, "/")
+	if u.Host == "" || repository == "" {
+		return nil, errors.Errorf("parse image '%s' failed, expected format app=registry/repository", value)
+	}
+	return &Image{
+		Registry:   u.Scheme + "://" + u.Host,
+		Repository: repository,
+		App:        app,
+	}, nil
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version_test
+
+import (
+	"github.com/bborbe/kafka-k8s-version-collector/version"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Image", func() {
+	It("returns tags url", func() {
+		image := version.Image{
+			Registry:   "https://gcr.io",
+			Repository: "google_containers/hyperkube-amd64",
+			App:        "Kubernetes",
+		}
+		Expect(image.TagsURL()).To(Equal("https://gcr.io/v2/google_containers/hyperkube-amd64/tags/list"))
+	})
+	It("parses image", func() {
+		image, err := version.ParseImage("Kubernetes=https://gcr.io/google_containers/hyperkube-amd64")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(image.App).To(Equal("Kubernetes"))
+		Expect(image.Registry).To(Equal("https://gcr.io"))
+		Expect(image.Repository).To(Equal("google_containers/hyperkube-amd64"))
+	})
+	It("parses image without scheme", func() {
+		image, err := version.ParseImage("Grafana=registry-1.docker.io/grafana/grafana")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(image.Registry).To(Equal("https://registry-1.docker.io"))
+		Expect(image.Repository).To(Equal("grafana/grafana"))
+	})
+	It("returns error if app is missing", func() {
+		_, err := version.ParseImage("https://gcr.io/google_containers/hyperkube-amd64")
+		Expect(err).To(HaveOccurred())
+	})
+	It("returns error if repository is missing", func() {
+		_, err := version.ParseImage("Kubernetes=https://gcr.io")
+		Expect(err).To(HaveOccurred())
+	})
+	It("parses list of images", func() {
+		images, err := version.ParseImages("Kubernetes=https://gcr.io/google_containers/hyperkube-amd64, Grafana=registry-1.docker.io/grafana/grafana")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(images).To(HaveLen(2))
+		Expect(images[0].App).To(Equal("Kubernetes"))
+		Expect(images[1].App).To(Equal("Grafana"))
+	})
+	It("returns error if one image is invalid", func() {
+		_, err := version.ParseImages("Kubernetes=https://gcr.io/google_containers/hyperkube-amd64,banana")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,216 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// ServiceAccountDir contains the token and ca.crt mounted into pods.
+const ServiceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// ClusterConfig describes how to connect to a Kubernetes API server.
+type ClusterConfig struct {
+	Server   string
+	Token    string
+	Username string
+	Password string
+	// CAData contains the PEM encoded certificates of the server, the system pool is used if empty.
+	CAData   []byte
+	CertData []byte
+	KeyData  []byte
+	Insecure bool
+}
+
+// InClusterConfig returns the config of the API server the pod is running in
+// with the token and certificate of the service account mounted at dir.
+func InClusterConfig(dir string) (*ClusterConfig, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, errors.New("KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT not set, not running in a cluster")
+	}
+	token, err := ioutil.ReadFile(filepath.Join(dir, "token"))
+	if err != nil {
+		return nil, errors.Wrap(err, "read service account token failed")
+	}
+	caData, err := ioutil.ReadFile(filepath.Join(dir, "ca.crt"))
+	if err != nil {
+		return nil, errors.Wrap(err, "read service account ca.crt failed")
+	}
+	return &ClusterConfig{
+		Server: "https://" + net.JoinHostPort(host, port),
+		Token:  strings.TrimSpace(string(token)),
+		CAData: caData,
+	}, nil
+}
+
+// kubeconfig contains the fields of a kubeconfig file used by the collector.
+type kubeconfig struct {
+	CurrentContext string `yaml:"current-context"`
+	Clusters       []struct {
+		Name    string `yaml:"name"`
+		Cluster struct {
+			Server                   string `yaml:"server"`
+			CertificateAuthority     string `yaml:"certificate-authority"`
+			CertificateAuthorityData string `yaml:"certificate-authority-data"`
+			InsecureSkipTLSVerify    bool   `yaml:"insecure-skip-tls-verify"`
+		} `yaml:"cluster"`
+	} `yaml:"clusters"`
+	Users []struct {
+		Name string `yaml:"name"`
+		User struct {
+			Token                 string `yaml:"token"`
+			TokenFile             string `yaml:"tokenFile"`
+			ClientCertificate     string `yaml:"client-certificate"`
+			ClientCertificateData string `yaml:"client-certificate-data"`
+			ClientKey             string `yaml:"client-key"`
+			ClientKeyData         string `yaml:"client-key-data"`
+			Username              string `yaml:"username"`
+			Password              string `yaml:"password"`
+		} `yaml:"user"`
+	} `yaml:"users"`
+	Contexts []struct {
+		Name    string `yaml:"name"`
+		Context struct {
+			Cluster string `yaml:"cluster"`
+			User    string `yaml:"user"`
+		} `yaml:"context"`
+	} `yaml:"contexts"`
+}
+
+// LoadKubeconfig returns the config of the given context of the kubeconfig file, the current context if empty.
+// Tokens, client certificates and basic auth are supported, exec and auth provider plugins are not.
+// Relative file paths are resolved against the directory of the kubeconfig.
+func LoadKubeconfig(path string, context string) (*ClusterConfig, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "read kubeconfig %s failed", path)
+	}
+	var config kubeconfig
+	if err := yaml.Unmarshal(content, &config); err != nil {
+		return nil, errors.Wrapf(err, "parse kubeconfig %s failed", path)
+	}
+	if context == "" {
+		context = config.CurrentContext
+	}
+	var clusterName, userName string
+	found := false
+	for _, c := range config.Contexts {
+		if c.Name == context {
+			clusterName, userName, found = c.Context.Cluster, c.Context.User, true
+		}
+	}
+	if !found {
+		return nil, errors.Errorf("context %q not found in kubeconfig %s", context, path)
+	}
+	dir := filepath.Dir(path)
+	result := &ClusterConfig{}
+	found = false
+	for _, c := range config.Clusters {
+		if c.Name != clusterName {
+			continue
+		}
+		found = true
+		result.Server = c.Cluster.Server
+		result.Insecure = c.Cluster.InsecureSkipTLSVerify
+		if result.CAData, err = kubeconfigData(dir, c.Cluster.CertificateAuthorityData, c.Cluster.CertificateAuthority); err != nil {
+			return nil, errors.Wrap(err, "read certificate authority failed")
+		}
+	}
+	if !found {
+		return nil, errors.Errorf("cluster %q of context %q not found in kubeconfig %s", clusterName, context, path)
+	}
+	for _, u := range config.Users {
+		if u.Name != userName {
+			continue
+		}
+		result.Token = u.User.Token
+		result.Username = u.User.Username
+		result.Password = u.User.Password
+		if u.User.TokenFile != "" {
+			token, err := ioutil.ReadFile(kubeconfigPath(dir, u.User.TokenFile))
+			if err != nil {
+				return nil, errors.Wrap(err, "read token file failed")
+			}
+			result.Token = strings.TrimSpace(string(token))
+		}
+		if result.CertData, err = kubeconfigData(dir, u.User.ClientCertificateData, u.User.ClientCertificate); err != nil {
+			return nil, errors.Wrap(err, "read client certificate failed")
+		}
+		if result.KeyData, err = kubeconfigData(dir, u.User.ClientKeyData, u.User.ClientKey); err != nil {
+			return nil, errors.Wrap(err, "read client key failed")
+		}
+	}
+	if result.Server == "" {
+		return nil, errors.Errorf("cluster %q has no server", clusterName)
+	}
+	return result, nil
+}
+
+// kubeconfigData returns the base64 encoded data or the content of the file.
+func kubeconfigData(dir string, data string, file string) ([]byte, error) {
+	if data != "" {
+		return base64.StdEncoding.DecodeString(data)
+	}
+	if file != "" {
+		return ioutil.ReadFile(kubeconfigPath(dir, file))
+	}
+	return nil, nil
+}
+
+func kubeconfigPath(dir string, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// HTTPClient returns a client for the API server with the TLS settings and credentials of the config.
+func (c *ClusterConfig) HTTPClient() (*http.Client, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: c.Insecure,
+	}
+	if len(c.CAData) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(c.CAData) {
+			return nil, errors.New("no certificate found in certificate authority")
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if len(c.CertData) > 0 {
+		cert, err := tls.X509KeyPair(c.CertData, c.KeyData)
+		if err != nil {
+			return nil, errors.Wrap(err, "load client certificate failed")
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	var authorization string
+	switch {
+	case c.Token != "":
+		authorization = "Bearer " + c.Token
+	case c.Username != "":
+		authorization = basicAuthorization(c.Username, c.Password)
+	}
+	return &http.Client{
+		Transport: NewAuthorizationRoundTripper(&http.Transport{
+			Proxy:               http.ProxyFromEnvironment,
+			TLSClientConfig:     tlsConfig,
+			TLSHandshakeTimeout: 10 * time.Second,
+		}, c.Server, authorization),
+		Timeout: time.Minute,
+	}, nil
+}
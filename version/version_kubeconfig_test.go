@@ -0,0 +1,132 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	"github.com/bborbe/kafka-k8s-version-collector/version"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const kubeconfigContent = `apiVersion: v1
+kind: Config
+current-context: prod
+clusters:
+- name: prod
+  cluster:
+    server: https://prod.example.com
+    insecure-skip-tls-verify: true
+- name: dev
+  cluster:
+    server: https://dev.example.com
+users:
+- name: admin
+  user:
+    token: secret-token
+- name: developer
+  user:
+    username: dev
+    password: S3CR3T
+- name: robot
+  user:
+    tokenFile: token
+contexts:
+- name: prod
+  context:
+    cluster: prod
+    user: admin
+- name: dev
+  context:
+    cluster: dev
+    user: developer
+- name: robot
+  context:
+    cluster: dev
+    user: robot
+`
+
+var _ = Describe("Kubeconfig", func() {
+	var dir string
+	var path string
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "kubeconfig")
+		Expect(err).NotTo(HaveOccurred())
+		path = filepath.Join(dir, "config")
+		Expect(ioutil.WriteFile(path, []byte(kubeconfigContent), 0600)).To(BeNil())
+		Expect(ioutil.WriteFile(filepath.Join(dir, "token"), []byte("file-token\n"), 0600)).To(BeNil())
+	})
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+	It("returns current context", func() {
+		config, err := version.LoadKubeconfig(path, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config.Server).To(Equal("https://prod.example.com"))
+		Expect(config.Token).To(Equal("secret-token"))
+		Expect(config.Insecure).To(BeTrue())
+	})
+	It("returns given context with basic auth", func() {
+		config, err := version.LoadKubeconfig(path, "dev")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config.Server).To(Equal("https://dev.example.com"))
+		Expect(config.Username).To(Equal("dev"))
+		Expect(config.Password).To(Equal("S3CR3T"))
+		Expect(config.Insecure).To(BeFalse())
+	})
+	It("reads token file relative to kubeconfig", func() {
+		config, err := version.LoadKubeconfig(path, "robot")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config.Token).To(Equal("file-token"))
+	})
+	It("returns error if context is unknown", func() {
+		_, err := version.LoadKubeconfig(path, "banana")
+		Expect(err).To(HaveOccurred())
+	})
+	It("returns in cluster config of service account", func() {
+		Expect(ioutil.WriteFile(filepath.Join(dir, "ca.crt"), []byte("ca"), 0600)).To(BeNil())
+		os.Setenv("KUBERNETES_SERVICE_HOST", "10.0.0.1")
+		os.Setenv("KUBERNETES_SERVICE_PORT", "443")
+		defer os.Unsetenv("KUBERNETES_SERVICE_HOST")
+		defer os.Unsetenv("KUBERNETES_SERVICE_PORT")
+		config, err := version.InClusterConfig(dir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config.Server).To(Equal("https://10.0.0.1:443"))
+		Expect(config.Token).To(Equal("file-token"))
+		Expect(config.CAData).To(Equal([]byte("ca")))
+	})
+	It("returns error if not running in cluster", func() {
+		os.Unsetenv("KUBERNETES_SERVICE_HOST")
+		_, err := version.InClusterConfig(dir)
+		Expect(err).To(HaveOccurred())
+	})
+	It("returns http client sending token", func() {
+		server := httptest.NewTLSServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+			fmt.Fprint(resp, req.Header.Get("Authorization"))
+		}))
+		defer server.Close()
+		config := &version.ClusterConfig{Server: server.URL, Token: "secret-token", Insecure: true}
+		client, err := config.HTTPClient()
+		Expect(err).NotTo(HaveOccurred())
+		resp, err := client.Get(server.URL)
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		content, err := ioutil.ReadAll(resp.Body)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(content)).To(Equal("Bearer secret-token"))
+	})
+	It("returns error if certificate authority contains no certificate", func() {
+		config := &version.ClusterConfig{CAData: []byte("banana")}
+		_, err := config.HTTPClient()
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,198 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/bborbe/kafka-k8s-version-collector/avro"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+// KubernetesReleaseURL is the location of the upstream Kubernetes release markers.
+const KubernetesReleaseURL = "https://dl.k8s.io/release"
+
+// DefaultKubernetesMinors is the number of minor lines release markers are read for.
+const DefaultKubernetesMinors = 4
+
+// Release marker channels of Kubernetes, minor lines are suffixed with -{major}.{minor}.
+const (
+	KubernetesChannelStable = "stable"
+	KubernetesChannelLatest = "latest"
+)
+
+// kubernetesListingRegexp matches release versions in a directory or bucket listing.
+var kubernetesListingRegexp = regexp.MustCompile(`(v\d+\.\d+\.\d+(?:-(?:alpha|beta|rc)\.\d+)?)(?:/|"|<|\s|$)`)
+
+// KubernetesRelease describes where Kubernetes releases are read from.
+type KubernetesRelease struct {
+	// URL contains the release markers stable.txt, latest.txt, stable-1.13.txt and latest-1.13.txt.
+	URL string
+	// Listing is a optional url listing all releases, like a directory listing or bucket listing.
+	Listing string
+	// Minors is the number of minor lines, starting with the latest, markers are read for.
+	Minors int
+	App    string
+}
+
+// NewKubernetesFetcher returns a Fetcher reading the Kubernetes release markers and the release listing.
+// Each version is published with the channels pointing to it, like "stable,stable-1.13".
+func NewKubernetesFetcher(
+	httpClient *http.Client,
+	release KubernetesRelease,
+) Fetcher {
+	return &kubernetesFetcher{
+		httpClient: httpClient,
+		release:    release,
+	}
+}
+
+type kubernetesFetcher struct {
+	httpClient *http.Client
+	release    KubernetesRelease
+}
+
+func (k *kubernetesFetcher) Fetch(ctx context.Context, versions chan<- avro.ApplicationVersionAvailable) error {
+	channels, err := k.channels(ctx)
+	if err != nil {
+		return errors.Wrap(err, "read release markers failed")
+	}
+	all := make(map[string]bool)
+	for version := range channels {
+		all[version] = true
+	}
+	if k.release.Listing != "" {
+		listed, err := k.listing(ctx)
+		if err != nil {
+			return errors.Wrap(err, "read release listing failed")
+		}
+		for _, version := range listed {
+			all[version] = true
+		}
+	}
+	var list []avro.ApplicationVersionAvailable
+	for version := range all {
+		record := NewApplicationVersionAvailable(k.release.App, version)
+		sort.Strings(channels[version])
+		record.Channel = strings.Join(channels[version], ",")
+		list = append(list, record)
+	}
+	SortVersions(list)
+	for _, version := range list {
+		select {
+		case <-ctx.Done():
+			glog.Infof("context done => return")
+			return nil
+		case versions <- version:
+		}
+	}
+	return nil
+}
+
+// channels returns the channels of each version marked by stable.txt, latest.txt and the markers of the minor lines.
+func (k *kubernetesFetcher) channels(ctx context.Context) (map[string][]string, error) {
+	result := make(map[string][]string)
+	var latestSemVer *SemVer
+	for _, channel := range []string{KubernetesChannelStable, KubernetesChannelLatest} {
+		version, found, err := k.marker(ctx, channel)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			return nil, errors.Errorf("marker %s not found", channel)
+		}
+		result[version] = append(result[version], channel)
+		if semVer, err := ParseSemVer(version); err == nil && (latestSemVer == nil || semVer.Compare(*latestSemVer) > 0) {
+			latestSemVer = semVer
+		}
+	}
+	if latestSemVer == nil {
+		return result, nil
+	}
+	minors := k.release.Minors
+	if minors <= 0 {
+		minors = DefaultKubernetesMinors
+	}
+	for minor := latestSemVer.Minor; minor >= 0 && minor > latestSemVer.Minor-minors; minor-- {
+		for _, prefix := range []string{KubernetesChannelStable, KubernetesChannelLatest} {
+			channel := fmt.Sprintf("%s-%d.%d", prefix, latestSemVer.Major, minor)
+			version, found, err := k.marker(ctx, channel)
+			if err != nil {
+				return nil, err
+			}
+			if !found {
+				glog.V(2).Infof("marker %s not found => skip", channel)
+				continue
+			}
+			result[version] = append(result[version], channel)
+		}
+	}
+	return result, nil
+}
+
+// marker returns the version of the given channel and false if the marker does not exist.
+func (k *kubernetesFetcher) marker(ctx context.Context, channel string) (string, bool, error) {
+	body, found, err := k.get(ctx, fmt.Sprintf("%s/%s.txt", strings.TrimSuffix(k.release.URL, "/"), channel))
+	if err != nil {
+		return "", false, errors.Wrapf(err, "read marker %s failed", channel)
+	}
+	if !found {
+		return "", false, nil
+	}
+	version := strings.TrimSpace(body)
+	if version == "" {
+		return "", false, errors.Errorf("marker %s is empty", channel)
+	}
+	return version, true, nil
+}
+
+// listing returns the release versions found in the listing.
+func (k *kubernetesFetcher) listing(ctx context.Context) ([]string, error) {
+	body, found, err := k.get(ctx, k.release.Listing)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, errors.Errorf("listing %s not found", k.release.Listing)
+	}
+	var result []string
+	for _, match := range kubernetesListingRegexp.FindAllStringSubmatch(body, -1) {
+		result = append(result, match[1])
+	}
+	return result, nil
+}
+
+// get returns the body of the url and false if it does not exist.
+func (k *kubernetesFetcher) get(ctx context.Context, url string) (string, bool, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return "", false, errors.Wrap(err, "build request failed")
+	}
+	req = req.WithContext(ctx)
+	glog.V(1).Infof("%s %s", req.Method, req.URL.String())
+	resp, err := k.httpClient.Do(req)
+	if err != nil {
+		return "", false, errors.Wrap(err, "request failed")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return "", false, nil
+	}
+	if resp.StatusCode/100 != 2 {
+		return "", false, errors.Errorf("request status code %d != 2xx", resp.StatusCode)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", false, errors.Wrap(err, "read body failed")
+	}
+	return string(body), true, nil
+}
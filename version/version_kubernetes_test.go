@@ -0,0 +1,118 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	"github.com/bborbe/kafka-k8s-version-collector/avro"
+	"github.com/bborbe/kafka-k8s-version-collector/version"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Kubernetes", func() {
+	var dir string
+	var server *httptest.Server
+	var release version.KubernetesRelease
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "kubernetes-release")
+		Expect(err).NotTo(HaveOccurred())
+		for name, content := range map[string]string{
+			"stable.txt":      "v1.13.4\n",
+			"latest.txt":      "v1.14.0-beta.1\n",
+			"stable-1.14.txt": "v1.14.0-beta.1\n",
+			"latest-1.14.txt": "v1.14.0-beta.1\n",
+			"stable-1.13.txt": "v1.13.4\n",
+			"latest-1.13.txt": "v1.13.5-beta.0\n",
+			"stable-1.12.txt": "v1.12.6\n",
+			"latest-1.12.txt": "v1.12.7-beta.0\n",
+			"stable-1.11.txt": "v1.11.8\n",
+			"latest-1.11.txt": "v1.11.9-beta.0\n",
+		} {
+			Expect(ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600)).To(BeNil())
+		}
+		for _, name := range []string{"v1.13.3", "v1.13.4", "v1.12.6", "v1.14.0-beta.1"} {
+			Expect(os.Mkdir(filepath.Join(dir, name), 0700)).To(BeNil())
+		}
+		server = httptest.NewServer(http.FileServer(http.Dir(dir)))
+		release = version.KubernetesRelease{
+			URL:    server.URL,
+			Minors: 2,
+			App:    "Kubernetes",
+		}
+	})
+	AfterEach(func() {
+		server.Close()
+		os.RemoveAll(dir)
+	})
+	fetch := func() ([]avro.ApplicationVersionAvailable, error) {
+		versions := make(chan avro.ApplicationVersionAvailable, 100)
+		err := version.NewKubernetesFetcher(http.DefaultClient, release).Fetch(context.Background(), versions)
+		close(versions)
+		var list []avro.ApplicationVersionAvailable
+		for version := range versions {
+			list = append(list, version)
+		}
+		return list, err
+	}
+	channelsOf := func(list []avro.ApplicationVersionAvailable) map[string]string {
+		result := make(map[string]string)
+		for _, v := range list {
+			result[v.Version] = v.Channel
+		}
+		return result
+	}
+	It("returns versions of markers with channels", func() {
+		list, err := fetch()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(list[0].App).To(Equal("Kubernetes"))
+		Expect(channelsOf(list)).To(Equal(map[string]string{
+			"v1.14.0-beta.1": "latest,latest-1.14,stable-1.14",
+			"v1.13.5-beta.0": "latest-1.13",
+			"v1.13.4":        "stable,stable-1.13",
+		}))
+	})
+	It("reads markers of the given number of minor lines", func() {
+		release.Minors = 4
+		list, err := fetch()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(channelsOf(list)).To(HaveKeyWithValue("v1.11.8", "stable-1.11"))
+		Expect(channelsOf(list)).To(HaveKeyWithValue("v1.12.6", "stable-1.12"))
+	})
+	It("skips missing markers of minor lines", func() {
+		release.Minors = 10
+		list, err := fetch()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(list).To(HaveLen(7))
+	})
+	It("returns versions of the listing without channel", func() {
+		release.Listing = server.URL + "/"
+		list, err := fetch()
+		Expect(err).NotTo(HaveOccurred())
+		channels := channelsOf(list)
+		Expect(channels).To(HaveLen(5))
+		Expect(channels).To(HaveKeyWithValue("v1.13.3", ""))
+		Expect(channels).To(HaveKeyWithValue("v1.12.6", ""))
+		Expect(channels).To(HaveKeyWithValue("v1.13.4", "stable,stable-1.13"))
+	})
+	It("returns versions newest first", func() {
+		list, err := fetch()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(list[0].Version).To(Equal("v1.14.0-beta.1"))
+		Expect(list[len(list)-1].Version).To(Equal("v1.13.4"))
+	})
+	It("returns error if stable marker is missing", func() {
+		Expect(os.Remove(filepath.Join(dir, "stable.txt"))).To(BeNil())
+		_, err := fetch()
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,115 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"net/url"
+	"strings"
+)
+
+// NextLink returns the url with rel="next" of the given RFC 8288 Link headers resolved against base.
+// An empty string is returned if no next link exists.
+func NextLink(base *url.URL, headers []string) string {
+	for _, header := range headers {
+		for _, link := range parseLinks(header) {
+			for _, rel := range strings.Fields(link.params["rel"]) {
+				if strings.ToLower(rel) != "next" {
+					continue
+				}
+				u, err := url.Parse(link.target)
+				if err != nil {
+					return ""
+				}
+				if base == nil {
+					return u.String()
+				}
+				return base.ResolveReference(u).String()
+			}
+		}
+	}
+	return ""
+}
+
+// link is a link-value of a Link header with its parameters by lower case name.
+type link struct {
+	target string
+	params map[string]string
+}
+
+// parseLinks returns the link-values of a Link header. Commas and semicolons within
+// the <target> or a quoted parameter value do not separate links or parameters.
+func parseLinks(header string) []link {
+	var result []link
+	for {
+		start := strings.Index(header, "<")
+		if start < 0 {
+			return result
+		}
+		end := strings.Index(header[start:], ">")
+		if end < 0 {
+			return result
+		}
+		current := link{
+			target: header[start+1 : start+end],
+			params: make(map[string]string),
+		}
+		header = header[start+end+1:]
+		var params []string
+		params, header = splitParams(header)
+		for _, param := range params {
+			pos := strings.Index(param, "=")
+			if pos < 0 {
+				continue
+			}
+			name := strings.ToLower(strings.TrimSpace(param[:pos]))
+			if _, ok := current.params[name]; ok {
+				continue
+			}
+			current.params[name] = unquote(strings.TrimSpace(param[pos+1:]))
+		}
+		result = append(result, current)
+	}
+}
+
+// splitParams returns the parameters of a link-value up to the comma ending it and the remaining header.
+func splitParams(header string) ([]string, string) {
+	var params []string
+	var quoted, escaped bool
+	begin := 0
+	for i := 0; i < len(header); i++ {
+		switch c := header[i]; {
+		case escaped:
+			escaped = false
+		case quoted && c == '\\':
+			escaped = true
+		case c == '"':
+			quoted = !quoted
+		case !quoted && c == ';':
+			params = append(params, header[begin:i])
+			begin = i + 1
+		case !quoted && c == ',':
+			return append(params, header[begin:i]), header[i+1:]
+		}
+	}
+	return append(params, header[begin:]), ""
+}
+
+// unquote returns the value of a quoted-string without quotes and escapes, other values unchanged.
+func unquote(value string) string {
+	if len(value) < 2 || value[0] != '"' || value[len(value)-1] != '"' {
+		return value
+	}
+	var result strings.Builder
+	var escaped bool
+	for _, c := range value[1 : len(value)-1] {
+		if c == '\\' && !escaped {
+			escaped = true
+			continue
+		}
+		escaped = false
+		result.WriteRune(c)
+	}
+	return result.String()
+}
@@ -0,0 +1,43 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version_test
+
+import (
+	"net/url"
+
+	"github.com/bborbe/kafka-k8s-version-collector/version"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Link", func() {
+	var base *url.URL
+	BeforeEach(func() {
+		var err error
+		base, err = url.Parse("https://gcr.io/v2/google_containers/hyperkube-amd64/tags/list?n=2")
+		Expect(err).NotTo(HaveOccurred())
+	})
+	It("returns empty string without header", func() {
+		Expect(version.NextLink(base, nil)).To(Equal(""))
+	})
+	It("resolves relative next link", func() {
+		Expect(version.NextLink(base, []string{`</v2/google_containers/hyperkube-amd64/tags/list?n=2&last=v2>; rel="next"`})).To(Equal("https://gcr.io/v2/google_containers/hyperkube-amd64/tags/list?n=2&last=v2"))
+	})
+	It("returns absolute next link", func() {
+		Expect(version.NextLink(base, []string{`<https://api.github.com/repos/a/b/releases?page=2>; rel="next", <https://api.github.com/repos/a/b/releases?page=5>; rel="last"`})).To(Equal("https://api.github.com/repos/a/b/releases?page=2"))
+	})
+	It("returns next link with comma in url", func() {
+		Expect(version.NextLink(base, []string{`<https://registry.example.com/v2/_catalog?last=a,b&n=2>; rel="next"`})).To(Equal("https://registry.example.com/v2/_catalog?last=a,b&n=2"))
+	})
+	It("returns next link after link with comma in parameter", func() {
+		Expect(version.NextLink(base, []string{`<https://example.com/a>; title="a, b; c"; rel="prev", <https://example.com/b>; rel="next"`})).To(Equal("https://example.com/b"))
+	})
+	It("returns next link of multiple relations", func() {
+		Expect(version.NextLink(base, []string{`<https://example.com/b>; rel="last next"`})).To(Equal("https://example.com/b"))
+	})
+	It("ignores other relations", func() {
+		Expect(version.NextLink(base, []string{`<https://api.github.com/repos/a/b/releases?page=1>; rel="prev"`})).To(Equal(""))
+	})
+})
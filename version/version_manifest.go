@@ -0,0 +1,177 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+// Media types of the manifests a tag can point to.
+const (
+	MediaTypeDockerManifest     = "application/vnd.docker.distribution.manifest.v2+json"
+	MediaTypeDockerManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+	MediaTypeOCIManifest        = "application/vnd.oci.image.manifest.v1+json"
+	MediaTypeOCIIndex           = "application/vnd.oci.image.index.v1+json"
+)
+
+// manifestMediaTypes are accepted when requesting a manifest, without them registries convert to schema 1.
+var manifestMediaTypes = []string{
+	MediaTypeOCIIndex,
+	MediaTypeDockerManifestList,
+	MediaTypeOCIManifest,
+	MediaTypeDockerManifest,
+}
+
+// Manifest describes the manifest a tag points to.
+type Manifest struct {
+	Digest    string
+	MediaType string
+	// Platforms of a manifest list or index like linux/amd64 or linux/arm/v7.
+	Platforms []string
+}
+
+// ManifestURL returns the url of the manifest of the tag or digest.
+func (i Image) ManifestURL(reference string) string {
+	return i.Registry + "/v2/" + i.Repository + "/manifests/" + reference
+}
+
+// ResolveManifest returns digest and media type of the manifest the tag points to, nil if the tag does not exist.
+// The manifest is requested with HEAD, the digest is the Docker-Content-Digest of the HEAD response.
+// Only manifests of registries not sending Docker-Content-Digest are downloaded to compute the digest,
+// their platforms are set as well. Platforms of other manifest lists are read with ManifestPlatforms.
+func ResolveManifest(ctx context.Context, httpClient *http.Client, image Image, tag string) (*Manifest, error) {
+	resp, err := requestManifest(ctx, httpClient, http.MethodHead, image.ManifestURL(tag))
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, errors.Errorf("request status code %d != 2xx", resp.StatusCode)
+	}
+	result := &Manifest{
+		Digest:    resp.Header.Get("Docker-Content-Digest"),
+		MediaType: manifestMediaType(resp.Header.Get("Content-Type")),
+	}
+	if result.Digest != "" {
+		return result, nil
+	}
+	content, mediaType, err := downloadManifest(ctx, httpClient, image, tag)
+	if err != nil || content == nil {
+		return nil, err
+	}
+	result.Digest = fmt.Sprintf("sha256:%x", sha256.Sum256(content))
+	result.MediaType = mediaType
+	if isManifestList(result.MediaType) {
+		if result.Platforms, err = manifestPlatforms(content); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// ManifestPlatforms downloads the manifest list or index with the given digest and returns its platforms.
+func ManifestPlatforms(ctx context.Context, httpClient *http.Client, image Image, digest string) ([]string, error) {
+	content, mediaType, err := downloadManifest(ctx, httpClient, image, digest)
+	if err != nil {
+		return nil, err
+	}
+	if content == nil {
+		return nil, errors.Errorf("manifest %s not found", digest)
+	}
+	if !isManifestList(mediaType) {
+		return nil, nil
+	}
+	return manifestPlatforms(content)
+}
+
+// downloadManifest returns content and media type of the manifest, nil if it does not exist.
+func downloadManifest(ctx context.Context, httpClient *http.Client, image Image, reference string) ([]byte, string, error) {
+	resp, err := requestManifest(ctx, httpClient, http.MethodGet, image.ManifestURL(reference))
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, "", nil
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, "", errors.Errorf("request status code %d != 2xx", resp.StatusCode)
+	}
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "read manifest failed")
+	}
+	return content, manifestMediaType(resp.Header.Get("Content-Type")), nil
+}
+
+func requestManifest(ctx context.Context, httpClient *http.Client, method string, url string) (*http.Response, error) {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "build request failed")
+	}
+	req = req.WithContext(ctx)
+	for _, mediaType := range manifestMediaTypes {
+		req.Header.Add("Accept", mediaType)
+	}
+	glog.V(2).Infof("%s %s", req.Method, req.URL.String())
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "request failed")
+	}
+	return resp, nil
+}
+
+// manifestMediaType returns the content type without parameters.
+func manifestMediaType(contentType string) string {
+	return strings.TrimSpace(strings.Split(contentType, ";")[0])
+}
+
+func isManifestList(mediaType string) bool {
+	return mediaType == MediaTypeDockerManifestList || mediaType == MediaTypeOCIIndex
+}
+
+// manifestPlatforms returns the platforms of a manifest list in the order of the list.
+// Entries without platform like attestations (os unknown) are skipped.
+func manifestPlatforms(content []byte) ([]string, error) {
+	var list struct {
+		Manifests []struct {
+			Platform struct {
+				OS           string `json:"os"`
+				Architecture string `json:"architecture"`
+				Variant      string `json:"variant"`
+			} `json:"platform"`
+		} `json:"manifests"`
+	}
+	if err := json.Unmarshal(content, &list); err != nil {
+		return nil, errors.Wrap(err, "decode manifest list failed")
+	}
+	var result []string
+	for _, manifest := range list.Manifests {
+		platform := manifest.Platform
+		if platform.OS == "" || platform.OS == "unknown" {
+			continue
+		}
+		name := platform.OS + "/" + platform.Architecture
+		if platform.Variant != "" {
+			name += "/" + platform.Variant
+		}
+		if !containsString(result, name) {
+			result = append(result, name)
+		}
+	}
+	return result, nil
+}
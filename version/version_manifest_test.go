@@ -0,0 +1,111 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+
+	"github.com/bborbe/kafka-k8s-version-collector/version"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+)
+
+const manifestList = `{
+  "schemaVersion": 2,
+  "mediaType": "application/vnd.docker.distribution.manifest.list.v2+json",
+  "manifests": [
+    {"digest": "sha256:1", "platform": {"architecture": "amd64", "os": "linux"}},
+    {"digest": "sha256:2", "platform": {"architecture": "arm", "os": "linux", "variant": "v7"}},
+    {"digest": "sha256:3", "platform": {"architecture": "unknown", "os": "unknown"}}
+  ]
+}`
+
+var _ = Describe("Manifest", func() {
+	var server *ghttp.Server
+	var image version.Image
+	BeforeEach(func() {
+		server = ghttp.NewServer()
+		server.AllowUnhandledRequests = true
+		server.UnhandledRequestStatusCode = http.StatusNotFound
+		image = version.Image{
+			Registry:   server.URL(),
+			Repository: "library/nginx",
+			App:        "Nginx",
+		}
+	})
+	AfterEach(func() {
+		server.Close()
+	})
+	It("returns digest of HEAD request", func() {
+		server.RouteToHandler(http.MethodHead, "/v2/library/nginx/manifests/1.15", func(resp http.ResponseWriter, req *http.Request) {
+			Expect(req.Header["Accept"]).To(ContainElement(version.MediaTypeOCIIndex))
+			Expect(req.Header["Accept"]).To(ContainElement(version.MediaTypeDockerManifest))
+			resp.Header().Set("Content-Type", version.MediaTypeOCIManifest)
+			resp.Header().Set("Docker-Content-Digest", "sha256:abc")
+		})
+		manifest, err := version.ResolveManifest(context.Background(), http.DefaultClient, image, "1.15")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(manifest).To(Equal(&version.Manifest{
+			Digest:    "sha256:abc",
+			MediaType: version.MediaTypeOCIManifest,
+		}))
+	})
+	It("returns digest of manifest list without downloading it", func() {
+		server.RouteToHandler(http.MethodHead, "/v2/library/nginx/manifests/1.15", func(resp http.ResponseWriter, req *http.Request) {
+			resp.Header().Set("Content-Type", version.MediaTypeDockerManifestList)
+			resp.Header().Set("Docker-Content-Digest", "sha256:list")
+		})
+		manifest, err := version.ResolveManifest(context.Background(), http.DefaultClient, image, "1.15")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(manifest).To(Equal(&version.Manifest{
+			Digest:    "sha256:list",
+			MediaType: version.MediaTypeDockerManifestList,
+		}))
+		Expect(server.ReceivedRequests()).To(HaveLen(1))
+	})
+	It("returns platforms of manifest list by digest", func() {
+		server.RouteToHandler(http.MethodGet, "/v2/library/nginx/manifests/sha256:list", func(resp http.ResponseWriter, req *http.Request) {
+			resp.Header().Set("Content-Type", version.MediaTypeDockerManifestList)
+			fmt.Fprint(resp, manifestList)
+		})
+		platforms, err := version.ManifestPlatforms(context.Background(), http.DefaultClient, image, "sha256:list")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(platforms).To(Equal([]string{"linux/amd64", "linux/arm/v7"}))
+	})
+	It("returns error if manifest of platforms does not exist", func() {
+		_, err := version.ManifestPlatforms(context.Background(), http.DefaultClient, image, "sha256:banana")
+		Expect(err).To(HaveOccurred())
+	})
+	It("computes digest if registry sends none", func() {
+		server.RouteToHandler(http.MethodHead, "/v2/library/nginx/manifests/1.15", func(resp http.ResponseWriter, req *http.Request) {
+			resp.Header().Set("Content-Type", version.MediaTypeDockerManifest)
+		})
+		server.RouteToHandler(http.MethodGet, "/v2/library/nginx/manifests/1.15", func(resp http.ResponseWriter, req *http.Request) {
+			resp.Header().Set("Content-Type", version.MediaTypeDockerManifest+"; charset=utf-8")
+			fmt.Fprint(resp, `{"schemaVersion":2}`)
+		})
+		manifest, err := version.ResolveManifest(context.Background(), http.DefaultClient, image, "1.15")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(manifest.Digest).To(Equal(fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(`{"schemaVersion":2}`)))))
+		Expect(manifest.MediaType).To(Equal(version.MediaTypeDockerManifest))
+		Expect(manifest.Platforms).To(BeEmpty())
+	})
+	It("returns nil if tag does not exist", func() {
+		manifest, err := version.ResolveManifest(context.Background(), http.DefaultClient, image, "banana")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(manifest).To(BeNil())
+	})
+	It("returns error if registry fails", func() {
+		server.RouteToHandler(http.MethodHead, "/v2/library/nginx/manifests/1.15", func(resp http.ResponseWriter, req *http.Request) {
+			resp.WriteHeader(http.StatusInternalServerError)
+		})
+		_, err := version.ResolveManifest(context.Background(), http.DefaultClient, image, "1.15")
+		Expect(err).To(HaveOccurred())
+	})
+})
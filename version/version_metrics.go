@@ -0,0 +1,166 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import "github.com/prometheus/client_golang/prometheus"
+
+const metricsNamespace = "kafka_version_collector"
+
+var sourceSyncsCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "source",
+		Name:      "syncs_total",
+		Help:      "Number of syncs per source and result (success or failure).",
+	},
+	[]string{"source", "result"},
+)
+
+var lastSuccessfulSyncGauge = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "source",
+		Name:      "last_successful_sync_timestamp_seconds",
+		Help:      "Unix time of the last successful sync per source.",
+	},
+	[]string{"source"},
+)
+
+var fetchDurationHistogram = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "source",
+		Name:      "fetch_duration_seconds",
+		Help:      "Duration of fetching all versions of a source.",
+		Buckets:   []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+	},
+	[]string{"source"},
+)
+
+var tagsFetchedCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "tags_fetched_total",
+		Help:      "Number of tags fetched per source and app.",
+	},
+	[]string{"source", "app"},
+)
+
+var versionsRetaggedCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "versions_retagged_total",
+		Help:      "Number of published tags whose digest changed per app.",
+	},
+	[]string{"app"},
+)
+
+var deploymentsPublishedCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "deployments_published_total",
+		Help:      "Number of changed deployed versions published to Kafka per cluster.",
+	},
+	[]string{"cluster"},
+)
+
+var deploymentsDeletedCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "deployments_deleted_total",
+		Help:      "Number of tombstones published to Kafka for deployed versions gone per cluster.",
+	},
+	[]string{"cluster"},
+)
+
+var versionsPublishedCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "versions_published_total",
+		Help:      "Number of versions published to Kafka per app.",
+	},
+	[]string{"app"},
+)
+
+var versionsSkippedCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "versions_skipped_total",
+		Help:      "Number of versions skipped per app because they were published before.",
+	},
+	[]string{"app"},
+)
+
+var sendFailuresCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "send_failures_total",
+		Help:      "Number of versions failed to publish per app.",
+	},
+	[]string{"app"},
+)
+
+var schemaRegistryLookupsCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "schema_registry_lookups_total",
+		Help:      "Number of schema id lookups per result (success or failure).",
+	},
+	[]string{"result"},
+)
+
+var retriesCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "retries_total",
+		Help:      "Number of retried calls per target (registry host or kafka).",
+	},
+	[]string{"target"},
+)
+
+var rateLimitRemainingGauge = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "rate_limit_remaining",
+		Help:      "Remaining requests per host as announced by the RateLimit-Remaining header.",
+	},
+	[]string{"host"},
+)
+
+var webhookEventsCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "webhook_events_total",
+		Help:      "Number of received webhook events per webhook and result (published, ignored, invalid, unauthorized or failure).",
+	},
+	[]string{"webhook", "result"},
+)
+
+func init() {
+	prometheus.MustRegister(
+		sourceSyncsCounter,
+		lastSuccessfulSyncGauge,
+		fetchDurationHistogram,
+		tagsFetchedCounter,
+		versionsPublishedCounter,
+		versionsSkippedCounter,
+		sendFailuresCounter,
+		schemaRegistryLookupsCounter,
+		retriesCounter,
+		rateLimitRemainingGauge,
+		webhookEventsCounter,
+		deploymentsPublishedCounter,
+		deploymentsDeletedCounter,
+		versionsRetaggedCounter,
+	)
+}
+
+// resultLabel returns the result label value of the given error.
+func resultLabel(err error) string {
+	if err != nil {
+		return "failure"
+	}
+	return "success"
+}
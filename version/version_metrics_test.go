@@ -0,0 +1,112 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version_test
+
+import (
+	"context"
+
+	mocksmocks "github.com/Shopify/sarama/mocks"
+	"github.com/bborbe/kafka-k8s-version-collector/avro"
+	"github.com/bborbe/kafka-k8s-version-collector/mocks"
+	"github.com/bborbe/kafka-k8s-version-collector/version"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	gokafkamocks "github.com/seibert-media/go-kafka/mocks"
+)
+
+// metricValue returns the value of the counter or gauge with the given name and label.
+func metricValue(name string, labelName string, labelValue string) float64 {
+	families, err := prometheus.DefaultGatherer.Gather()
+	Expect(err).NotTo(HaveOccurred())
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == labelName && label.GetValue() == labelValue {
+					if metric.GetCounter() != nil {
+						return metric.GetCounter().GetValue()
+					}
+					return metric.GetGauge().GetValue()
+				}
+			}
+		}
+	}
+	return 0
+}
+
+var _ = Describe("Metrics", func() {
+	var sender version.Sender
+	var producer *mocksmocks.SyncProducer
+	var store *mocks.Store
+	BeforeEach(func() {
+		var t GinkgoTestReporter
+		producer = mocksmocks.NewSyncProducer(t, nil)
+		store = &mocks.Store{}
+		sender = version.NewSender(
+			producer,
+			&gokafkamocks.SchemaRegistry{},
+			"my-topic",
+			store,
+			false,
+		)
+	})
+	It("counts published versions", func() {
+		before := metricValue("kafka_version_collector_versions_published_total", "app", "MetricsPublished")
+		producer.ExpectSendMessageAndSucceed()
+		versions := make(chan avro.ApplicationVersionAvailable, 1)
+		versions <- version.NewApplicationVersionAvailable("MetricsPublished", "1.0.0")
+		close(versions)
+		_, err := sender.Send(context.Background(), versions)
+		Expect(err).To(BeNil())
+		Expect(metricValue("kafka_version_collector_versions_published_total", "app", "MetricsPublished")).To(Equal(before + 1))
+	})
+	It("counts skipped versions", func() {
+		before := metricValue("kafka_version_collector_versions_skipped_total", "app", "MetricsSkipped")
+		store.ContainsReturns(true, nil)
+		versions := make(chan avro.ApplicationVersionAvailable, 1)
+		versions <- version.NewApplicationVersionAvailable("MetricsSkipped", "1.0.0")
+		close(versions)
+		_, err := sender.Send(context.Background(), versions)
+		Expect(err).To(BeNil())
+		Expect(metricValue("kafka_version_collector_versions_skipped_total", "app", "MetricsSkipped")).To(Equal(before + 1))
+	})
+	It("counts fetched tags per source", func() {
+		before := metricValue("kafka_version_collector_tags_fetched_total", "source", "metrics-fetched")
+		filter, err := version.NewFilter(version.FilterConfig{})
+		Expect(err).NotTo(HaveOccurred())
+		fetcher := &mocks.Fetcher{}
+		fetcher.FetchStub = func(ctx context.Context, versions chan<- avro.ApplicationVersionAvailable) error {
+			versions <- version.NewApplicationVersionAvailable("MetricsFetched", "1.0.0")
+			versions <- version.NewApplicationVersionAvailable("MetricsFetched", "1.1.0")
+			return nil
+		}
+		sender := &mocks.Sender{}
+		sender.SendStub = func(ctx context.Context, versions <-chan avro.ApplicationVersionAvailable) (int, error) {
+			for range versions {
+			}
+			return 0, nil
+		}
+		syncer := version.NewSyncer(
+			sender,
+			version.Source{Name: "metrics-fetched", Fetcher: fetcher, Filter: filter},
+		)
+		Expect(syncer.Sync(context.Background())).To(BeNil())
+		Expect(metricValue("kafka_version_collector_tags_fetched_total", "source", "metrics-fetched")).To(Equal(before + 2))
+	})
+	It("sets last successful sync of source", func() {
+		filter, err := version.NewFilter(version.FilterConfig{})
+		Expect(err).NotTo(HaveOccurred())
+		syncer := version.NewSyncer(
+			&mocks.Sender{},
+			version.Source{Name: "metrics-source", Fetcher: &mocks.Fetcher{}, Filter: filter},
+		)
+		Expect(syncer.Sync(context.Background())).To(BeNil())
+		Expect(metricValue("kafka_version_collector_source_last_successful_sync_timestamp_seconds", "source", "metrics-source")).To(BeNumerically(">", 0))
+		Expect(metricValue("kafka_version_collector_source_syncs_total", "source", "metrics-source")).To(BeNumerically(">=", 1))
+	})
+})
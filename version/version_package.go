@@ -0,0 +1,332 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/bborbe/kafka-k8s-version-collector/avro"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+// Ecosystems of package versions, named like in the Open Source Vulnerabilities schema.
+const (
+	EcosystemGo     = "Go"
+	EcosystemNpm    = "npm"
+	EcosystemPyPI   = "PyPI"
+	EcosystemMaven  = "Maven"
+	EcosystemCrates = "crates.io"
+)
+
+// Default locations of the public package registries.
+const (
+	GoProxyURL      = "https://proxy.golang.org"
+	NpmRegistryURL  = "https://registry.npmjs.org"
+	PyPIURL         = "https://pypi.org"
+	MavenCentralURL = "https://repo1.maven.org/maven2"
+	CratesIndexURL  = "https://index.crates.io"
+)
+
+// Package describes a package of a language package registry.
+type Package struct {
+	// URL of the registry.
+	URL string
+	// Name of the package, the module path for Go and groupId:artifactId for Maven.
+	Name string
+	App  string
+}
+
+// NewGoProxyFetcher returns a Fetcher listing the versions of a module with the Go module proxy protocol.
+func NewGoProxyFetcher(httpClient *http.Client, pkg Package) Fetcher {
+	return &packageFetcher{
+		httpClient: httpClient,
+		pkg:        pkg,
+		ecosystem:  EcosystemGo,
+		path:       func(name string) (string, error) { return "/" + escapeModulePath(name) + "/@v/list", nil },
+		parse:      parseGoProxyList,
+	}
+}
+
+// NewNpmFetcher returns a Fetcher reading the versions of a package from the npm registry.
+// The dist-tags pointing to a version are published as channel.
+func NewNpmFetcher(httpClient *http.Client, pkg Package) Fetcher {
+	return &packageFetcher{
+		httpClient: httpClient,
+		pkg:        pkg,
+		ecosystem:  EcosystemNpm,
+		path:       func(name string) (string, error) { return "/" + url.PathEscape(name), nil },
+		parse:      parseNpmDocument,
+	}
+}
+
+// NewPyPIFetcher returns a Fetcher reading the releases of a project from the PyPI JSON API.
+// Releases with only yanked files are skipped.
+func NewPyPIFetcher(httpClient *http.Client, pkg Package) Fetcher {
+	return &packageFetcher{
+		httpClient: httpClient,
+		pkg:        pkg,
+		ecosystem:  EcosystemPyPI,
+		path:       func(name string) (string, error) { return "/pypi/" + url.PathEscape(name) + "/json", nil },
+		parse:      parsePyPIProject,
+	}
+}
+
+// NewMavenFetcher returns a Fetcher reading the versions of a artifact from the maven-metadata.xml of a Maven repository.
+// The versions marked as release or latest are published with that channel.
+func NewMavenFetcher(httpClient *http.Client, pkg Package) Fetcher {
+	return &packageFetcher{
+		httpClient: httpClient,
+		pkg:        pkg,
+		ecosystem:  EcosystemMaven,
+		path:       mavenMetadataPath,
+		parse:      parseMavenMetadata,
+	}
+}
+
+// NewCratesFetcher returns a Fetcher reading the versions of a crate from the sparse crates.io index.
+// Yanked versions are skipped.
+func NewCratesFetcher(httpClient *http.Client, pkg Package) Fetcher {
+	return &packageFetcher{
+		httpClient: httpClient,
+		pkg:        pkg,
+		ecosystem:  EcosystemCrates,
+		path:       func(name string) (string, error) { return "/" + CratesIndexPath(name), nil },
+		parse:      parseCratesIndex,
+	}
+}
+
+type packageFetcher struct {
+	httpClient *http.Client
+	pkg        Package
+	ecosystem  string
+	path       func(name string) (string, error)
+	parse      func(app string, body []byte) ([]avro.ApplicationVersionAvailable, error)
+}
+
+func (p *packageFetcher) Fetch(ctx context.Context, versions chan<- avro.ApplicationVersionAvailable) error {
+	path, err := p.path(p.pkg.Name)
+	if err != nil {
+		return err
+	}
+	body, err := p.get(ctx, strings.TrimSuffix(p.pkg.URL, "/")+path)
+	if err != nil {
+		return errors.Wrapf(err, "read %s package %s failed", p.ecosystem, p.pkg.Name)
+	}
+	list, err := p.parse(p.pkg.App, body)
+	if err != nil {
+		return errors.Wrapf(err, "parse %s package %s failed", p.ecosystem, p.pkg.Name)
+	}
+	SortVersions(list)
+	for _, version := range list {
+		version.Ecosystem = p.ecosystem
+		select {
+		case <-ctx.Done():
+			glog.Infof("context done => return")
+			return nil
+		case versions <- version:
+		}
+	}
+	return nil
+}
+
+func (p *packageFetcher) get(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "build request failed")
+	}
+	req = req.WithContext(ctx)
+	glog.V(1).Infof("%s %s", req.Method, req.URL.String())
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "request failed")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		return nil, errors.Errorf("package not found")
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, errors.Errorf("request status code %d != 2xx", resp.StatusCode)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "read body failed")
+	}
+	return body, nil
+}
+
+// escapeModulePath replaces upper case letters with ! followed by the lower case letter
+// like the Go module proxy protocol requires.
+func escapeModulePath(path string) string {
+	buf := &bytes.Buffer{}
+	for _, r := range path {
+		if unicode.IsUpper(r) {
+			buf.WriteByte('!')
+			r = unicode.ToLower(r)
+		}
+		buf.WriteRune(r)
+	}
+	return buf.String()
+}
+
+func parseGoProxyList(app string, body []byte) ([]avro.ApplicationVersionAvailable, error) {
+	var result []avro.ApplicationVersionAvailable
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		if version := strings.TrimSpace(scanner.Text()); version != "" {
+			result = append(result, NewApplicationVersionAvailable(app, version))
+		}
+	}
+	return result, errors.Wrap(scanner.Err(), "read list failed")
+}
+
+type npmDocument struct {
+	DistTags map[string]string          `json:"dist-tags"`
+	Versions map[string]json.RawMessage `json:"versions"`
+	Time     map[string]time.Time       `json:"time"`
+}
+
+func parseNpmDocument(app string, body []byte) ([]avro.ApplicationVersionAvailable, error) {
+	var document npmDocument
+	if err := json.Unmarshal(body, &document); err != nil {
+		return nil, errors.Wrap(err, "decode json failed")
+	}
+	channels := make(map[string][]string)
+	for tag, version := range document.DistTags {
+		channels[version] = append(channels[version], tag)
+	}
+	var result []avro.ApplicationVersionAvailable
+	for version := range document.Versions {
+		record := NewApplicationVersionAvailable(app, version)
+		if publishedAt, ok := document.Time[version]; ok {
+			record.PublishedAt = publishedAt.UnixNano() / int64(time.Millisecond)
+		}
+		sort.Strings(channels[version])
+		record.Channel = strings.Join(channels[version], ",")
+		result = append(result, record)
+	}
+	return result, nil
+}
+
+type pypiProject struct {
+	Releases map[string][]struct {
+		UploadTime time.Time `json:"upload_time_iso_8601"`
+		Yanked     bool      `json:"yanked"`
+	} `json:"releases"`
+}
+
+func parsePyPIProject(app string, body []byte) ([]avro.ApplicationVersionAvailable, error) {
+	var project pypiProject
+	if err := json.Unmarshal(body, &project); err != nil {
+		return nil, errors.Wrap(err, "decode json failed")
+	}
+	var result []avro.ApplicationVersionAvailable
+	for version, files := range project.Releases {
+		record := NewApplicationVersionAvailable(app, version)
+		yanked := len(files) > 0
+		for _, file := range files {
+			yanked = yanked && file.Yanked
+			if publishedAt := file.UploadTime.UnixNano() / int64(time.Millisecond); !file.UploadTime.IsZero() && (record.PublishedAt == 0 || publishedAt < record.PublishedAt) {
+				record.PublishedAt = publishedAt
+			}
+		}
+		if yanked {
+			glog.V(2).Infof("release %s of %s is yanked => skip", version, app)
+			continue
+		}
+		result = append(result, record)
+	}
+	return result, nil
+}
+
+// mavenMetadataPath returns the path of the maven-metadata.xml of a groupId:artifactId.
+func mavenMetadataPath(name string) (string, error) {
+	parts := strings.Split(name, ":")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", errors.Errorf("package %s must be groupId:artifactId", name)
+	}
+	return fmt.Sprintf("/%s/%s/maven-metadata.xml", strings.Replace(parts[0], ".", "/", -1), parts[1]), nil
+}
+
+type mavenMetadata struct {
+	Versioning struct {
+		Latest   string   `xml:"latest"`
+		Release  string   `xml:"release"`
+		Versions []string `xml:"versions>version"`
+	} `xml:"versioning"`
+}
+
+func parseMavenMetadata(app string, body []byte) ([]avro.ApplicationVersionAvailable, error) {
+	var metadata mavenMetadata
+	if err := xml.Unmarshal(body, &metadata); err != nil {
+		return nil, errors.Wrap(err, "decode xml failed")
+	}
+	var result []avro.ApplicationVersionAvailable
+	for _, version := range metadata.Versioning.Versions {
+		record := NewApplicationVersionAvailable(app, version)
+		var channels []string
+		if version == metadata.Versioning.Latest {
+			channels = append(channels, "latest")
+		}
+		if version == metadata.Versioning.Release {
+			channels = append(channels, "release")
+		}
+		record.Channel = strings.Join(channels, ",")
+		result = append(result, record)
+	}
+	return result, nil
+}
+
+// CratesIndexPath returns the path of a crate in the crates.io index, like se/rd/serde.
+func CratesIndexPath(name string) string {
+	name = strings.ToLower(name)
+	switch len(name) {
+	case 1:
+		return "1/" + name
+	case 2:
+		return "2/" + name
+	case 3:
+		return fmt.Sprintf("3/%s/%s", name[:1], name)
+	default:
+		return fmt.Sprintf("%s/%s/%s", name[:2], name[2:4], name)
+	}
+}
+
+func parseCratesIndex(app string, body []byte) ([]avro.ApplicationVersionAvailable, error) {
+	var result []avro.ApplicationVersionAvailable
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var entry struct {
+			Vers   string `json:"vers"`
+			Yanked bool   `json:"yanked"`
+		}
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return nil, errors.Wrap(err, "decode index line failed")
+		}
+		if entry.Yanked {
+			glog.V(2).Infof("version %s of %s is yanked => skip", entry.Vers, app)
+			continue
+		}
+		result = append(result, NewApplicationVersionAvailable(app, entry.Vers))
+	}
+	return result, errors.Wrap(scanner.Err(), "read index failed")
+}
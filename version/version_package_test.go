@@ -0,0 +1,164 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/bborbe/kafka-k8s-version-collector/avro"
+	"github.com/bborbe/kafka-k8s-version-collector/version"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+)
+
+var _ = Describe("Package", func() {
+	var server *ghttp.Server
+	BeforeEach(func() {
+		server = ghttp.NewServer()
+	})
+	AfterEach(func() {
+		server.Close()
+	})
+	serve := func(path string, body string) {
+		server.RouteToHandler(http.MethodGet, path, func(resp http.ResponseWriter, req *http.Request) {
+			fmt.Fprint(resp, body)
+		})
+	}
+	fetch := func(fetcher version.Fetcher) ([]avro.ApplicationVersionAvailable, error) {
+		versions := make(chan avro.ApplicationVersionAvailable, 100)
+		err := fetcher.Fetch(context.Background(), versions)
+		close(versions)
+		var list []avro.ApplicationVersionAvailable
+		for version := range versions {
+			list = append(list, version)
+		}
+		return list, err
+	}
+	versionsOf := func(list []avro.ApplicationVersionAvailable) []string {
+		var result []string
+		for _, v := range list {
+			result = append(result, v.Version)
+		}
+		return result
+	}
+	pkg := func(name string) version.Package {
+		return version.Package{
+			URL:  server.URL(),
+			Name: name,
+			App:  "Library",
+		}
+	}
+	Context("Go proxy", func() {
+		It("returns versions of escaped module path", func() {
+			serve("/github.com/!azure/azure-sdk-for-go/@v/list", "v1.0.0\nv1.1.0\n\nv2.0.0-beta.1\n")
+			list, err := fetch(version.NewGoProxyFetcher(http.DefaultClient, pkg("github.com/Azure/azure-sdk-for-go")))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versionsOf(list)).To(Equal([]string{"v2.0.0-beta.1", "v1.1.0", "v1.0.0"}))
+			Expect(list[0].App).To(Equal("Library"))
+			Expect(list[0].Ecosystem).To(Equal(version.EcosystemGo))
+			Expect(list[0].Stable).To(BeFalse())
+		})
+		It("returns error if module is not found", func() {
+			server.AllowUnhandledRequests = true
+			_, err := fetch(version.NewGoProxyFetcher(http.DefaultClient, pkg("github.com/bborbe/banana")))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Context("npm", func() {
+		It("returns versions with publish time and dist-tags", func() {
+			serve("/@bborbe/lib", `{
+				"name": "@bborbe/lib",
+				"dist-tags": {"latest": "1.1.0", "next": "2.0.0-rc.1"},
+				"versions": {"1.0.0": {}, "1.1.0": {}, "2.0.0-rc.1": {}},
+				"time": {"created": "2019-01-01T10:00:00.000Z", "1.0.0": "2019-01-01T10:00:00.000Z", "1.1.0": "2019-02-01T10:00:00.000Z"}
+			}`)
+			list, err := fetch(version.NewNpmFetcher(http.DefaultClient, pkg("@bborbe/lib")))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versionsOf(list)).To(Equal([]string{"2.0.0-rc.1", "1.1.0", "1.0.0"}))
+			Expect(list[0].Channel).To(Equal("next"))
+			Expect(list[1].Channel).To(Equal("latest"))
+			Expect(list[1].PublishedAt).To(Equal(time.Date(2019, 2, 1, 10, 0, 0, 0, time.UTC).UnixNano() / int64(time.Millisecond)))
+			Expect(list[1].Ecosystem).To(Equal(version.EcosystemNpm))
+			Expect(server.ReceivedRequests()[0].URL.EscapedPath()).To(Equal("/@bborbe%2Flib"))
+		})
+		It("returns error on invalid document", func() {
+			serve("/lib", `banana`)
+			_, err := fetch(version.NewNpmFetcher(http.DefaultClient, pkg("lib")))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Context("PyPI", func() {
+		It("returns releases with first upload time and skips yanked", func() {
+			serve("/pypi/requests/json", `{
+				"releases": {
+					"2.21.0": [
+						{"upload_time_iso_8601": "2018-12-10T15:40:00.000000Z", "yanked": false},
+						{"upload_time_iso_8601": "2018-12-10T15:30:00.000000Z", "yanked": false}
+					],
+					"2.22.0": [{"upload_time_iso_8601": "2019-05-16T14:22:00.000000Z", "yanked": true}],
+					"3.0.0rc1": []
+				}
+			}`)
+			list, err := fetch(version.NewPyPIFetcher(http.DefaultClient, pkg("requests")))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versionsOf(list)).To(Equal([]string{"2.21.0", "3.0.0rc1"}))
+			Expect(list[0].PublishedAt).To(Equal(time.Date(2018, 12, 10, 15, 30, 0, 0, time.UTC).UnixNano() / int64(time.Millisecond)))
+			Expect(list[0].Ecosystem).To(Equal(version.EcosystemPyPI))
+			Expect(list[1].Parsed).To(BeFalse())
+		})
+	})
+	Context("Maven", func() {
+		It("returns versions of metadata with channels", func() {
+			serve("/org/apache/kafka/kafka-clients/maven-metadata.xml", `<?xml version="1.0" encoding="UTF-8"?>
+<metadata>
+  <groupId>org.apache.kafka</groupId>
+  <artifactId>kafka-clients</artifactId>
+  <versioning>
+    <latest>2.2.0-SNAPSHOT</latest>
+    <release>2.1.1</release>
+    <versions>
+      <version>2.1.0</version>
+      <version>2.1.1</version>
+      <version>2.2.0-SNAPSHOT</version>
+    </versions>
+    <lastUpdated>20190215100000</lastUpdated>
+  </versioning>
+</metadata>`)
+			list, err := fetch(version.NewMavenFetcher(http.DefaultClient, pkg("org.apache.kafka:kafka-clients")))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versionsOf(list)).To(Equal([]string{"2.2.0-SNAPSHOT", "2.1.1", "2.1.0"}))
+			Expect(list[0].Channel).To(Equal("latest"))
+			Expect(list[1].Channel).To(Equal("release"))
+			Expect(list[2].Channel).To(BeEmpty())
+			Expect(list[0].Ecosystem).To(Equal(version.EcosystemMaven))
+		})
+		It("returns error if package is no groupId:artifactId", func() {
+			_, err := fetch(version.NewMavenFetcher(http.DefaultClient, pkg("kafka-clients")))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Context("crates.io", func() {
+		It("returns versions of index and skips yanked", func() {
+			serve("/se/rd/serde", `{"name":"serde","vers":"1.0.88","deps":[],"cksum":"a","features":{},"yanked":false}
+{"name":"serde","vers":"1.0.89","deps":[],"cksum":"b","features":{},"yanked":true}
+{"name":"serde","vers":"1.0.90","deps":[],"cksum":"c","features":{},"yanked":false}
+`)
+			list, err := fetch(version.NewCratesFetcher(http.DefaultClient, pkg("Serde")))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versionsOf(list)).To(Equal([]string{"1.0.90", "1.0.88"}))
+			Expect(list[0].Ecosystem).To(Equal(version.EcosystemCrates))
+		})
+		It("returns index path of crate", func() {
+			Expect(version.CratesIndexPath("a")).To(Equal("1/a"))
+			Expect(version.CratesIndexPath("ab")).To(Equal("2/ab"))
+			Expect(version.CratesIndexPath("abc")).To(Equal("3/a/abc"))
+			Expect(version.CratesIndexPath("cargo")).To(Equal("ca/rg/cargo"))
+		})
+	})
+})
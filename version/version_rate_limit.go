@@ -0,0 +1,137 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+// ErrRateLimited is returned for requests to a host with exhausted rate limit.
+var ErrRateLimited = errors.New("rate limited")
+
+// defaultRateLimitBackoff is used if a host responds too many requests without telling how long to wait.
+const defaultRateLimitBackoff = time.Minute
+
+// NewRateLimitRoundTripper returns a http.RoundTripper respecting the rate limit announced by a host
+// with RateLimit-Limit and RateLimit-Remaining headers like Docker Hub.
+// (see https://docs.docker.com/docker-hub/download-rate-limit/)
+// Once the limit is exhausted, requests to the host wait until a request is available again.
+// Requests that would have to wait longer than maxWait fail with ErrRateLimited.
+func NewRateLimitRoundTripper(
+	roundTripper http.RoundTripper,
+	maxWait time.Duration,
+) http.RoundTripper {
+	return &rateLimitRoundTripper{
+		roundTripper: roundTripper,
+		maxWait:      maxWait,
+		blocked:      make(map[string]time.Time),
+	}
+}
+
+type rateLimitRoundTripper struct {
+	roundTripper http.RoundTripper
+	maxWait      time.Duration
+
+	mux     sync.Mutex
+	blocked map[string]time.Time
+}
+
+func (r *rateLimitRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	if wait := r.wait(host, time.Now()); wait > 0 {
+		if wait > r.maxWait {
+			return nil, errors.Wrapf(ErrRateLimited, "rate limit of %s exhausted, next request in %v", host, wait)
+		}
+		glog.V(2).Infof("rate limit of %s exhausted => wait %v", host, wait)
+		if err := sleep(req.Context(), wait); err != nil {
+			return nil, err
+		}
+	}
+	resp, err := r.roundTripper.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	remaining, window, ok := ParseRateLimit(resp.Header.Get("RateLimit-Remaining"))
+	if ok {
+		rateLimitRemainingGauge.WithLabelValues(host).Set(float64(remaining))
+	}
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		backoff, ok := ParseRetryAfter(resp.Header.Get("Retry-After"), now)
+		if !ok {
+			backoff = r.replenish(resp, window)
+		}
+		r.block(host, now.Add(backoff))
+	case ok && remaining <= 0:
+		r.block(host, now.Add(r.replenish(resp, window)))
+	}
+	return resp, nil
+}
+
+// replenish returns the time until the window allows another request.
+func (r *rateLimitRoundTripper) replenish(resp *http.Response, window time.Duration) time.Duration {
+	limit, limitWindow, ok := ParseRateLimit(resp.Header.Get("RateLimit-Limit"))
+	if !ok || limit <= 0 {
+		return defaultRateLimitBackoff
+	}
+	if limitWindow > 0 {
+		window = limitWindow
+	}
+	if window <= 0 {
+		return defaultRateLimitBackoff
+	}
+	return window / time.Duration(limit)
+}
+
+func (r *rateLimitRoundTripper) wait(host string, now time.Time) time.Duration {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	until, ok := r.blocked[host]
+	if !ok {
+		return 0
+	}
+	if !until.After(now) {
+		delete(r.blocked, host)
+		return 0
+	}
+	return until.Sub(now)
+}
+
+func (r *rateLimitRoundTripper) block(host string, until time.Time) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	glog.V(1).Infof("rate limit of %s exhausted until %v", host, until.Format(time.RFC3339))
+	if until.After(r.blocked[host]) {
+		r.blocked[host] = until
+	}
+}
+
+// ParseRateLimit parses a rate limit header like "76;w=21600" into the value and the window.
+func ParseRateLimit(header string) (int, time.Duration, bool) {
+	parts := strings.Split(header, ";")
+	value, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return 0, 0, false
+	}
+	var window time.Duration
+	for _, part := range parts[1:] {
+		part = strings.TrimSpace(part)
+		if !strings.HasPrefix(part, "w=") {
+			continue
+		}
+		if seconds, err := strconv.Atoi(strings.TrimPrefix(part, "w=")); err == nil && seconds > 0 {
+			window = time.Duration(seconds) * time.Second
+		}
+	}
+	return value, window, true
+}
@@ -0,0 +1,113 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version_test
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/bborbe/kafka-k8s-version-collector/version"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+	"github.com/pkg/errors"
+)
+
+var _ = Describe("Rate Limit", func() {
+	It("parses rate limit header", func() {
+		value, window, ok := version.ParseRateLimit("76;w=21600")
+		Expect(ok).To(BeTrue())
+		Expect(value).To(Equal(76))
+		Expect(window).To(Equal(6 * time.Hour))
+	})
+	It("parses rate limit header without window", func() {
+		value, window, ok := version.ParseRateLimit("100")
+		Expect(ok).To(BeTrue())
+		Expect(value).To(Equal(100))
+		Expect(window).To(BeZero())
+	})
+	It("returns false for invalid header", func() {
+		_, _, ok := version.ParseRateLimit("banana")
+		Expect(ok).To(BeFalse())
+		_, _, ok = version.ParseRateLimit("")
+		Expect(ok).To(BeFalse())
+	})
+	Context("round tripper", func() {
+		var server *ghttp.Server
+		var client *http.Client
+		var header http.Header
+		var status int
+		BeforeEach(func() {
+			server = ghttp.NewServer()
+			header = http.Header{}
+			status = http.StatusOK
+			server.RouteToHandler(http.MethodGet, "/v2/library/nginx/tags/list", func(resp http.ResponseWriter, req *http.Request) {
+				for key, values := range header {
+					resp.Header()[key] = values
+				}
+				resp.WriteHeader(status)
+			})
+			client = &http.Client{
+				Transport: version.NewRateLimitRoundTripper(http.DefaultTransport, 50*time.Millisecond),
+			}
+		})
+		AfterEach(func() {
+			server.Close()
+		})
+		get := func() error {
+			resp, err := client.Get(server.URL() + "/v2/library/nginx/tags/list")
+			if err != nil {
+				return err
+			}
+			return resp.Body.Close()
+		}
+		It("passes requests while limit remains", func() {
+			header.Set("RateLimit-Limit", "100;w=21600")
+			header.Set("RateLimit-Remaining", "99;w=21600")
+			Expect(get()).To(BeNil())
+			Expect(get()).To(BeNil())
+			Expect(server.ReceivedRequests()).To(HaveLen(2))
+		})
+		It("fails without request if limit is exhausted", func() {
+			header.Set("RateLimit-Limit", "100;w=21600")
+			header.Set("RateLimit-Remaining", "0;w=21600")
+			Expect(get()).To(BeNil())
+			err := get()
+			Expect(err).To(HaveOccurred())
+			Expect(errors.Cause(err.(*url.Error).Err)).To(Equal(version.ErrRateLimited))
+			Expect(server.ReceivedRequests()).To(HaveLen(1))
+		})
+		It("waits until limit is available again", func() {
+			header.Set("RateLimit-Limit", "100;w=1")
+			header.Set("RateLimit-Remaining", "0;w=1")
+			Expect(get()).To(BeNil())
+			started := time.Now()
+			Expect(get()).To(BeNil())
+			Expect(time.Since(started)).To(BeNumerically(">=", 5*time.Millisecond))
+			Expect(server.ReceivedRequests()).To(HaveLen(2))
+		})
+		It("backs off after too many requests", func() {
+			status = http.StatusTooManyRequests
+			header.Set("Retry-After", "60")
+			Expect(get()).To(BeNil())
+			Expect(get()).To(HaveOccurred())
+			Expect(server.ReceivedRequests()).To(HaveLen(1))
+		})
+		It("stops retries once limit is exhausted", func() {
+			status = http.StatusTooManyRequests
+			header.Set("RateLimit-Limit", "1;w=3600")
+			client.Transport = version.NewRetryRoundTripper(client.Transport, version.RetryPolicy{
+				MaxAttempts:  3,
+				InitialDelay: time.Millisecond,
+				MaxDelay:     10 * time.Millisecond,
+			})
+			err := get()
+			Expect(err).To(HaveOccurred())
+			Expect(errors.Cause(err.(*url.Error).Err)).To(Equal(version.ErrRateLimited))
+			Expect(server.ReceivedRequests()).To(HaveLen(1))
+		})
+	})
+})
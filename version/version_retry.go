@@ -0,0 +1,253 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+// RetryPolicy defines how often and how long to wait before a failed call is retried.
+type RetryPolicy struct {
+	// MaxAttempts is the number of calls including the first one. 1 disables retries.
+	MaxAttempts int
+	// InitialDelay is the wait before the first retry, doubled for every further retry.
+	InitialDelay time.Duration
+	// MaxDelay caps the wait between two attempts.
+	MaxDelay time.Duration
+	// Jitter randomizes the wait by the given fraction (0.2 = ±20%).
+	Jitter float64
+}
+
+// Validate returns an error if the policy can not be used.
+func (r RetryPolicy) Validate() error {
+	if r.MaxAttempts < 1 {
+		return errors.Errorf("max attempts %d must be at least 1", r.MaxAttempts)
+	}
+	if r.InitialDelay < 0 {
+		return errors.Errorf("initial delay %v is negative", r.InitialDelay)
+	}
+	if r.MaxDelay < r.InitialDelay {
+		return errors.Errorf("max delay %v is lower than initial delay %v", r.MaxDelay, r.InitialDelay)
+	}
+	if r.Jitter < 0 || r.Jitter > 1 {
+		return errors.Errorf("jitter %v must be between 0 and 1", r.Jitter)
+	}
+	return nil
+}
+
+// Delay returns the wait before the given retry, starting with 1 for the first retry.
+func (r RetryPolicy) Delay(retry int) time.Duration {
+	delay := r.InitialDelay
+	for i := 1; i < retry && delay < r.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > r.MaxDelay {
+		delay = r.MaxDelay
+	}
+	if r.Jitter > 0 {
+		delay += time.Duration(float64(delay) * r.Jitter * (2*rand.Float64() - 1))
+	}
+	return delay
+}
+
+// Retry calls fn until it succeeds, the attempts are used up or the context is done.
+func (r RetryPolicy) Retry(ctx context.Context, target string, fn func(ctx context.Context) error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil || attempt >= r.MaxAttempts {
+			return err
+		}
+		delay := r.Delay(attempt)
+		glog.V(2).Infof("attempt %d of %s failed, retry in %v: %v", attempt, target, delay, err)
+		retriesCounter.WithLabelValues(target).Inc()
+		if err := sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+func sleep(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// IsRetryableStatus returns true for status codes signaling a temporary problem of the server.
+func IsRetryableStatus(statusCode int) bool {
+	switch statusCode {
+	case http.StatusRequestTimeout,
+		http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// ParseRetryAfter returns the wait of a Retry-After header given in seconds or as http date.
+func ParseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if date.Before(now) {
+		return 0, true
+	}
+	return date.Sub(now), true
+}
+
+// NewRetryRoundTripper returns a http.RoundTripper retrying connection errors and retryable status codes.
+// A Retry-After header is respected, the response is returned if it asks to wait longer than the max delay.
+// Requests failing with ErrRateLimited are not retried.
+// Requests with a body are only retried if the body can be recreated.
+func NewRetryRoundTripper(
+	roundTripper http.RoundTripper,
+	policy RetryPolicy,
+) http.RoundTripper {
+	return &retryRoundTripper{
+		roundTripper: roundTripper,
+		policy:       policy,
+	}
+}
+
+type retryRoundTripper struct {
+	roundTripper http.RoundTripper
+	policy       RetryPolicy
+}
+
+func (r *retryRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	for attempt := 1; ; attempt++ {
+		resp, err := r.roundTripper.RoundTrip(req)
+		if attempt >= r.policy.MaxAttempts || !r.canRetry(req) || errors.Cause(err) == ErrRateLimited {
+			return resp, err
+		}
+		delay := r.policy.Delay(attempt)
+		if err == nil {
+			if !IsRetryableStatus(resp.StatusCode) {
+				return resp, nil
+			}
+			if retryAfter, ok := ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+				if retryAfter > r.policy.MaxDelay {
+					glog.V(2).Infof("%s %s asks to retry after %v => give up", req.Method, req.URL.String(), retryAfter)
+					return resp, nil
+				}
+				if retryAfter > delay {
+					delay = retryAfter
+				}
+			}
+			glog.V(2).Infof("attempt %d of %s %s failed with status %d, retry in %v", attempt, req.Method, req.URL.String(), resp.StatusCode, delay)
+			resp.Body.Close()
+		} else {
+			glog.V(2).Infof("attempt %d of %s %s failed, retry in %v: %v", attempt, req.Method, req.URL.String(), delay, err)
+		}
+		retriesCounter.WithLabelValues(req.URL.Host).Inc()
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+		if req, err = rewind(req); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (r *retryRoundTripper) canRetry(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// rewind returns a copy of the request with a fresh body.
+func rewind(req *http.Request) (*http.Request, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return req, nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, errors.Wrap(err, "get body failed")
+	}
+	result := new(http.Request)
+	*result = *req
+	result.Body = body
+	return result, nil
+}
+
+// ContextSyncProducer is a sarama.SyncProducer whose sends can be cancelled with a context.
+type ContextSyncProducer interface {
+	sarama.SyncProducer
+	// SendMessageContext sends the message and returns once sent or the context is done.
+	SendMessageContext(ctx context.Context, msg *sarama.ProducerMessage) (int32, int64, error)
+}
+
+// NewRetrySyncProducer returns a sarama.SyncProducer retrying failed sends.
+// The senders wait for retries with SendMessageContext, so the backoff ends with their context.
+// Disable the retries of sarama (Producer.Retry.Max = 0), otherwise both retries multiply.
+func NewRetrySyncProducer(
+	producer sarama.SyncProducer,
+	policy RetryPolicy,
+) ContextSyncProducer {
+	return &retrySyncProducer{
+		SyncProducer: producer,
+		policy:       policy,
+	}
+}
+
+type retrySyncProducer struct {
+	sarama.SyncProducer
+	policy RetryPolicy
+}
+
+func (r *retrySyncProducer) SendMessage(msg *sarama.ProducerMessage) (int32, int64, error) {
+	return r.SendMessageContext(context.Background(), msg)
+}
+
+// SendMessageContext sends the message and stops retrying once the context is done.
+func (r *retrySyncProducer) SendMessageContext(ctx context.Context, msg *sarama.ProducerMessage) (int32, int64, error) {
+	var partition int32
+	var offset int64
+	err := r.policy.Retry(ctx, "kafka", func(ctx context.Context) error {
+		var err error
+		partition, offset, err = r.SyncProducer.SendMessage(msg)
+		return err
+	})
+	return partition, offset, err
+}
+
+func (r *retrySyncProducer) SendMessages(msgs []*sarama.ProducerMessage) error {
+	return r.policy.Retry(context.Background(), "kafka", func(ctx context.Context) error {
+		return r.SyncProducer.SendMessages(msgs)
+	})
+}
+
+// sendMessage sends the message with the context if the producer supports it.
+func sendMessage(ctx context.Context, producer sarama.SyncProducer, msg *sarama.ProducerMessage) (int32, int64, error) {
+	if p, ok := producer.(ContextSyncProducer); ok {
+		return p.SendMessageContext(ctx, msg)
+	}
+	return producer.SendMessage(msg)
+}
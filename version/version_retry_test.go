@@ -0,0 +1,226 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/Shopify/sarama"
+	mocksmocks "github.com/Shopify/sarama/mocks"
+	"github.com/bborbe/kafka-k8s-version-collector/version"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+)
+
+var _ = Describe("Retry", func() {
+	var policy version.RetryPolicy
+	BeforeEach(func() {
+		policy = version.RetryPolicy{
+			MaxAttempts:  3,
+			InitialDelay: time.Millisecond,
+			MaxDelay:     10 * time.Millisecond,
+		}
+	})
+	It("is valid", func() {
+		Expect(policy.Validate()).To(BeNil())
+	})
+	It("returns error if max attempts is zero", func() {
+		policy.MaxAttempts = 0
+		Expect(policy.Validate()).To(HaveOccurred())
+	})
+	It("returns error if max delay is lower than initial delay", func() {
+		policy.MaxDelay = 0
+		Expect(policy.Validate()).To(HaveOccurred())
+	})
+	It("returns error if jitter is greater than 1", func() {
+		policy.Jitter = 1.5
+		Expect(policy.Validate()).To(HaveOccurred())
+	})
+	It("doubles delay until max delay", func() {
+		Expect(policy.Delay(1)).To(Equal(time.Millisecond))
+		Expect(policy.Delay(2)).To(Equal(2 * time.Millisecond))
+		Expect(policy.Delay(4)).To(Equal(8 * time.Millisecond))
+		Expect(policy.Delay(5)).To(Equal(10 * time.Millisecond))
+		Expect(policy.Delay(100)).To(Equal(10 * time.Millisecond))
+	})
+	It("randomizes delay by jitter", func() {
+		policy.InitialDelay = time.Second
+		policy.MaxDelay = time.Second
+		policy.Jitter = 0.2
+		for i := 0; i < 100; i++ {
+			delay := policy.Delay(1)
+			Expect(delay).To(BeNumerically(">=", 800*time.Millisecond))
+			Expect(delay).To(BeNumerically("<=", 1200*time.Millisecond))
+		}
+	})
+	It("retries until success", func() {
+		counter := 0
+		err := policy.Retry(context.Background(), "test", func(ctx context.Context) error {
+			counter++
+			if counter < 3 {
+				return errors.New("banana")
+			}
+			return nil
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(counter).To(Equal(3))
+	})
+	It("returns last error after max attempts", func() {
+		counter := 0
+		err := policy.Retry(context.Background(), "test", func(ctx context.Context) error {
+			counter++
+			return errors.New("banana")
+		})
+		Expect(err).To(MatchError("banana"))
+		Expect(counter).To(Equal(3))
+	})
+	It("stops if context is canceled", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		counter := 0
+		err := policy.Retry(ctx, "test", func(ctx context.Context) error {
+			counter++
+			cancel()
+			return errors.New("banana")
+		})
+		Expect(err).To(HaveOccurred())
+		Expect(counter).To(Equal(1))
+	})
+	It("classifies retryable status codes", func() {
+		Expect(version.IsRetryableStatus(http.StatusTooManyRequests)).To(BeTrue())
+		Expect(version.IsRetryableStatus(http.StatusServiceUnavailable)).To(BeTrue())
+		Expect(version.IsRetryableStatus(http.StatusNotFound)).To(BeFalse())
+		Expect(version.IsRetryableStatus(http.StatusUnauthorized)).To(BeFalse())
+	})
+	It("parses retry after seconds", func() {
+		delay, ok := version.ParseRetryAfter("120", time.Now())
+		Expect(ok).To(BeTrue())
+		Expect(delay).To(Equal(2 * time.Minute))
+	})
+	It("parses retry after date", func() {
+		now := time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC)
+		delay, ok := version.ParseRetryAfter("Fri, 01 Mar 2019 12:00:30 GMT", now)
+		Expect(ok).To(BeTrue())
+		Expect(delay).To(Equal(30 * time.Second))
+	})
+	It("ignores invalid retry after", func() {
+		_, ok := version.ParseRetryAfter("banana", time.Now())
+		Expect(ok).To(BeFalse())
+	})
+
+	Context("RoundTripper", func() {
+		var server *ghttp.Server
+		var client *http.Client
+		BeforeEach(func() {
+			server = ghttp.NewServer()
+			client = &http.Client{
+				Transport: version.NewRetryRoundTripper(http.DefaultTransport, policy),
+			}
+		})
+		AfterEach(func() {
+			server.Close()
+		})
+		It("retries on service unavailable", func() {
+			server.AppendHandlers(
+				ghttp.RespondWith(http.StatusServiceUnavailable, ""),
+				ghttp.RespondWith(http.StatusOK, "ok"),
+			)
+			resp, err := client.Get(server.URL())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(server.ReceivedRequests()).To(HaveLen(2))
+		})
+		It("returns last response after max attempts", func() {
+			server.AppendHandlers(
+				ghttp.RespondWith(http.StatusBadGateway, ""),
+				ghttp.RespondWith(http.StatusBadGateway, ""),
+				ghttp.RespondWith(http.StatusBadGateway, ""),
+			)
+			resp, err := client.Get(server.URL())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusBadGateway))
+			Expect(server.ReceivedRequests()).To(HaveLen(3))
+		})
+		It("does not retry not found", func() {
+			server.AppendHandlers(
+				ghttp.RespondWith(http.StatusNotFound, ""),
+			)
+			resp, err := client.Get(server.URL())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+			Expect(server.ReceivedRequests()).To(HaveLen(1))
+		})
+		It("retries too many requests with retry after", func() {
+			server.AppendHandlers(
+				ghttp.RespondWith(http.StatusTooManyRequests, "", http.Header{"Retry-After": []string{"0"}}),
+				ghttp.RespondWith(http.StatusOK, "ok"),
+			)
+			resp, err := client.Get(server.URL())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(server.ReceivedRequests()).To(HaveLen(2))
+		})
+		It("gives up if retry after exceeds max delay", func() {
+			server.AppendHandlers(
+				ghttp.RespondWith(http.StatusTooManyRequests, "", http.Header{"Retry-After": []string{"3600"}}),
+			)
+			resp, err := client.Get(server.URL())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusTooManyRequests))
+			Expect(server.ReceivedRequests()).To(HaveLen(1))
+		})
+		It("sends body again on retry", func() {
+			var bodies []string
+			handler := func(resp http.ResponseWriter, req *http.Request) {
+				content, _ := ioutil.ReadAll(req.Body)
+				bodies = append(bodies, string(content))
+			}
+			server.AppendHandlers(
+				ghttp.CombineHandlers(handler, ghttp.RespondWith(http.StatusServiceUnavailable, "")),
+				ghttp.CombineHandlers(handler, ghttp.RespondWith(http.StatusOK, "")),
+			)
+			resp, err := client.Post(server.URL(), "application/json", bytes.NewBufferString(`{"schema":"banana"}`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(bodies).To(Equal([]string{`{"schema":"banana"}`, `{"schema":"banana"}`}))
+		})
+	})
+
+	Context("SyncProducer", func() {
+		var producer *mocksmocks.SyncProducer
+		BeforeEach(func() {
+			var t GinkgoTestReporter
+			producer = mocksmocks.NewSyncProducer(t, nil)
+		})
+		It("retries failed send", func() {
+			producer.ExpectSendMessageAndFail(sarama.ErrNotEnoughReplicas)
+			producer.ExpectSendMessageAndSucceed()
+			_, _, err := version.NewRetrySyncProducer(producer, policy).SendMessage(&sarama.ProducerMessage{
+				Topic: "my-topic",
+				Value: sarama.StringEncoder("banana"),
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(producer.Close()).To(BeNil())
+		})
+		It("stops retrying once context is done", func() {
+			policy.InitialDelay = time.Hour
+			policy.MaxDelay = time.Hour
+			producer.ExpectSendMessageAndFail(sarama.ErrNotEnoughReplicas)
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(10*time.Millisecond, cancel)
+			_, _, err := version.NewRetrySyncProducer(producer, policy).SendMessageContext(ctx, &sarama.ProducerMessage{
+				Topic: "my-topic",
+				Value: sarama.StringEncoder("banana"),
+			})
+			Expect(err).To(Equal(context.Canceled))
+			Expect(producer.Close()).To(BeNil())
+		})
+	})
+})
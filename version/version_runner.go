@@ -0,0 +1,242 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+// Triggers of a sync run.
+const (
+	SyncTriggerSchedule = "schedule"
+	SyncTriggerManual   = "manual"
+)
+
+// States of a sync run.
+const (
+	SyncStatusRunning = "running"
+	SyncStatusSuccess = "success"
+	SyncStatusFailure = "failure"
+)
+
+// ErrUnknownSource is the cause of errors for sources not configured.
+var ErrUnknownSource = errors.New("unknown source")
+
+// SyncRun describes a single sync of one or more sources.
+type SyncRun struct {
+	ID       string         `json:"id"`
+	Trigger  string         `json:"trigger"`
+	Sources  []string       `json:"sources"`
+	Started  time.Time      `json:"started"`
+	Finished time.Time      `json:"finished"`
+	Status   string         `json:"status"`
+	Error    string         `json:"error,omitempty"`
+	Results  []SourceResult `json:"results"`
+}
+
+// Err returns the error of a failed run.
+func (s SyncRun) Err() error {
+	if s.Status == SyncStatusFailure {
+		return errors.New(s.Error)
+	}
+	return nil
+}
+
+//go:generate counterfeiter -o ../mocks/runner.go --fake-name Runner . Runner
+type Runner interface {
+	// Run syncs the given sources, all sources if none given, and returns the finished run.
+	Run(ctx context.Context, trigger string, sources ...string) (SyncRun, error)
+}
+
+// NewRunner returns a Runner for the given sources.
+// A run for the same sources as a run in progress joins it instead of starting a new one.
+// A source is never synced by two runs at the same time, the later run shares the result of the earlier.
+func NewRunner(
+	sender Sender,
+	sources ...Source,
+) Runner {
+	return &runner{
+		sender:  sender,
+		sources: sources,
+		flight:  newFlight(),
+		running: make(map[string]*runningSync),
+	}
+}
+
+type runner struct {
+	sender  Sender
+	sources []Source
+	flight  *flight
+
+	mux     sync.Mutex
+	running map[string]*runningSync
+}
+
+type runningSync struct {
+	run  SyncRun
+	done chan struct{}
+}
+
+func (r *runner) Run(ctx context.Context, trigger string, names ...string) (SyncRun, error) {
+	sources, err := r.selectSources(names)
+	if err != nil {
+		return SyncRun{}, err
+	}
+	names = sourceNames(sources)
+	key := strings.Join(names, ",")
+
+	r.mux.Lock()
+	if current, ok := r.running[key]; ok {
+		r.mux.Unlock()
+		glog.V(1).Infof("%s sync of %s joins running sync %s", trigger, key, current.run.ID)
+		select {
+		case <-ctx.Done():
+			return SyncRun{}, ctx.Err()
+		case <-current.done:
+			return current.run, nil
+		}
+	}
+	current := &runningSync{
+		run: SyncRun{
+			ID:      newRunID(),
+			Trigger: trigger,
+			Sources: names,
+			Started: time.Now(),
+			Status:  SyncStatusRunning,
+		},
+		done: make(chan struct{}),
+	}
+	r.running[key] = current
+	r.mux.Unlock()
+
+	glog.V(1).Infof("sync %s of %s started by %s", current.run.ID, key, trigger)
+	results, err := newSyncer(r.sender, r.flight, sources...).sync(ctx, trigger)
+
+	r.mux.Lock()
+	delete(r.running, key)
+	current.run.Results = results
+	current.run.Finished = time.Now()
+	current.run.Status = SyncStatusSuccess
+	if err != nil {
+		current.run.Status = SyncStatusFailure
+		current.run.Error = err.Error()
+	}
+	r.mux.Unlock()
+	close(current.done)
+	glog.V(1).Infof("sync %s of %s finished with %s", current.run.ID, key, current.run.Status)
+	return current.run, nil
+}
+
+func (r *runner) selectSources(names []string) ([]Source, error) {
+	if len(names) == 0 {
+		return r.sources, nil
+	}
+	var result []Source
+	for _, name := range names {
+		source, ok := r.source(name)
+		if !ok {
+			return nil, errors.Wrapf(ErrUnknownSource, "source %s", name)
+		}
+		result = append(result, source)
+	}
+	return result, nil
+}
+
+func (r *runner) source(name string) (Source, bool) {
+	for _, source := range r.sources {
+		if source.Name == name {
+			return source, true
+		}
+	}
+	return Source{}, false
+}
+
+func sourceNames(sources []Source) []string {
+	var result []string
+	for _, source := range sources {
+		result = append(result, source.Name)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func newRunID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return time.Now().UTC().Format("20060102150405.000000000")
+	}
+	return hex.EncodeToString(buf)
+}
+
+// NewRunnerSyncer returns a Syncer running the given sources with the runner.
+func NewRunnerSyncer(runner Runner, trigger string, sources ...string) Syncer {
+	return syncerFunc(func(ctx context.Context) error {
+		run, err := runner.Run(ctx, trigger, sources...)
+		if err != nil {
+			return err
+		}
+		return run.Err()
+	})
+}
+
+type syncerFunc func(ctx context.Context) error
+
+func (s syncerFunc) Sync(ctx context.Context) error {
+	return s(ctx)
+}
+
+type runnerFunc func(ctx context.Context, trigger string, sources ...string) (SyncRun, error)
+
+func (r runnerFunc) Run(ctx context.Context, trigger string, sources ...string) (SyncRun, error) {
+	return r(ctx, trigger, sources...)
+}
+
+// flight executes a source sync once per source at a time. Callers of a source in progress wait for its result.
+type flight struct {
+	mux   sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	done chan struct{}
+	sync sourceSync
+}
+
+func newFlight() *flight {
+	return &flight{
+		calls: make(map[string]*flightCall),
+	}
+}
+
+func (f *flight) Do(key string, fn func() sourceSync) sourceSync {
+	f.mux.Lock()
+	if call, ok := f.calls[key]; ok {
+		f.mux.Unlock()
+		<-call.done
+		return call.sync
+	}
+	call := &flightCall{
+		done: make(chan struct{}),
+	}
+	f.calls[key] = call
+	f.mux.Unlock()
+
+	call.sync = fn()
+
+	f.mux.Lock()
+	delete(f.calls, key)
+	f.mux.Unlock()
+	close(call.done)
+	return call.sync
+}
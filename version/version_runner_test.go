@@ -0,0 +1,178 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/bborbe/kafka-k8s-version-collector/avro"
+	"github.com/bborbe/kafka-k8s-version-collector/mocks"
+	"github.com/bborbe/kafka-k8s-version-collector/version"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	pkgerrors "github.com/pkg/errors"
+)
+
+var _ = Describe("Runner", func() {
+	var runner version.Runner
+	var sender *mocks.Sender
+	var grafana *mocks.Fetcher
+	var nginx *mocks.Fetcher
+	BeforeEach(func() {
+		sender = &mocks.Sender{}
+		grafana = &mocks.Fetcher{}
+		nginx = &mocks.Fetcher{}
+		filter, err := version.NewFilter(version.FilterConfig{})
+		Expect(err).NotTo(HaveOccurred())
+		runner = version.NewRunner(
+			sender,
+			version.Source{Name: "nginx", Fetcher: nginx, Filter: filter},
+			version.Source{Name: "grafana", Fetcher: grafana, Filter: filter},
+		)
+	})
+	It("runs all sources", func() {
+		run, err := runner.Run(context.Background(), version.SyncTriggerManual)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(run.ID).NotTo(BeEmpty())
+		Expect(run.Trigger).To(Equal(version.SyncTriggerManual))
+		Expect(run.Sources).To(Equal([]string{"grafana", "nginx"}))
+		Expect(run.Status).To(Equal(version.SyncStatusSuccess))
+		Expect(run.Finished).NotTo(BeTemporally("<", run.Started))
+		Expect(run.Err()).To(BeNil())
+		Expect(grafana.FetchCallCount()).To(Equal(1))
+		Expect(nginx.FetchCallCount()).To(Equal(1))
+	})
+	It("records cancelled run as failed", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		grafana.FetchStub = func(ctx context.Context, versions chan<- avro.ApplicationVersionAvailable) error {
+			cancel()
+			<-ctx.Done()
+			return nil
+		}
+		run, err := runner.Run(ctx, version.SyncTriggerManual, "grafana")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(run.Status).To(Equal(version.SyncStatusFailure))
+		Expect(run.Error).To(ContainSubstring("sync cancelled"))
+	})
+	It("returns result of each source", func() {
+		grafana.FetchStub = func(ctx context.Context, versions chan<- avro.ApplicationVersionAvailable) error {
+			for _, v := range []string{"6.0.0", "6.0.1"} {
+				versions <- version.NewApplicationVersionAvailable("Grafana", v)
+			}
+			return nil
+		}
+		sender.SendStub = func(ctx context.Context, versions <-chan avro.ApplicationVersionAvailable) (int, error) {
+			var counter int
+			for range versions {
+				counter++
+			}
+			return counter, nil
+		}
+		run, err := runner.Run(context.Background(), version.SyncTriggerManual, "grafana")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(run.Results).To(HaveLen(1))
+		Expect(run.Results[0].Source).To(Equal("grafana"))
+		Expect(run.Results[0].Fetched).To(Equal(2))
+		Expect(run.Results[0].Published).To(Equal(2))
+		Expect(run.Results[0].Error).To(BeEmpty())
+	})
+	It("runs only the given source", func() {
+		run, err := runner.Run(context.Background(), version.SyncTriggerManual, "grafana")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(run.Sources).To(Equal([]string{"grafana"}))
+		Expect(grafana.FetchCallCount()).To(Equal(1))
+		Expect(nginx.FetchCallCount()).To(Equal(0))
+	})
+	It("returns error for unknown source", func() {
+		_, err := runner.Run(context.Background(), version.SyncTriggerManual, "banana")
+		Expect(pkgerrors.Cause(err)).To(Equal(version.ErrUnknownSource))
+	})
+	It("returns failed run", func() {
+		grafana.FetchReturns(errors.New("banana"))
+		run, err := runner.Run(context.Background(), version.SyncTriggerSchedule, "grafana")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(run.Status).To(Equal(version.SyncStatusFailure))
+		Expect(run.Error).To(ContainSubstring("banana"))
+		Expect(run.Err()).To(HaveOccurred())
+	})
+	Context("with sync in progress", func() {
+		var started chan struct{}
+		var release chan struct{}
+		BeforeEach(func() {
+			started = make(chan struct{}, 10)
+			release = make(chan struct{})
+			grafana.FetchStub = func(ctx context.Context, versions chan<- avro.ApplicationVersionAvailable) error {
+				started <- struct{}{}
+				<-release
+				return nil
+			}
+		})
+		It("joins run of the same sources", func() {
+			var wg sync.WaitGroup
+			var first version.SyncRun
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				var err error
+				first, err = runner.Run(context.Background(), version.SyncTriggerSchedule, "grafana")
+				Expect(err).NotTo(HaveOccurred())
+			}()
+			<-started
+			var second version.SyncRun
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				var err error
+				second, err = runner.Run(context.Background(), version.SyncTriggerManual, "grafana")
+				Expect(err).NotTo(HaveOccurred())
+			}()
+			Consistently(started).ShouldNot(Receive())
+			close(release)
+			wg.Wait()
+			Expect(second.ID).To(Equal(first.ID))
+			Expect(second.Trigger).To(Equal(version.SyncTriggerSchedule))
+			Expect(grafana.FetchCallCount()).To(Equal(1))
+		})
+		It("does not sync a source twice in overlapping runs", func() {
+			var wg sync.WaitGroup
+			var runs []version.SyncRun
+			var mux sync.Mutex
+			run := func(trigger string, sources ...string) {
+				defer GinkgoRecover()
+				defer wg.Done()
+				run, err := runner.Run(context.Background(), trigger, sources...)
+				Expect(err).NotTo(HaveOccurred())
+				mux.Lock()
+				runs = append(runs, run)
+				mux.Unlock()
+			}
+			wg.Add(1)
+			go run(version.SyncTriggerSchedule, "grafana")
+			<-started
+			wg.Add(1)
+			go run(version.SyncTriggerManual)
+			Eventually(nginx.FetchCallCount).Should(Equal(1))
+			Consistently(started).ShouldNot(Receive())
+			close(release)
+			wg.Wait()
+			Expect(runs).To(HaveLen(2))
+			Expect(runs[0].ID).NotTo(Equal(runs[1].ID))
+			Expect(grafana.FetchCallCount()).To(Equal(1))
+			for _, run := range runs {
+				for _, result := range run.Results {
+					if result.Source == "grafana" {
+						Expect(result.Trigger).To(Equal(version.SyncTriggerSchedule))
+					} else {
+						Expect(result.Trigger).To(Equal(version.SyncTriggerManual))
+					}
+				}
+			}
+		})
+	})
+})
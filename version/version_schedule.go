@@ -0,0 +1,95 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"context"
+	"time"
+
+	"github.com/bborbe/cron"
+	"github.com/bborbe/run"
+	"github.com/pkg/errors"
+	robfig_cron "github.com/robfig/cron"
+)
+
+// NewCron returns a cron.Cron that executes the action as defined by the schedule.
+// A failed action is logged and does not stop the schedule.
+func NewCron(
+	schedule ScheduleConfig,
+	action func(ctx context.Context) error,
+) cron.Cron {
+	if schedule.Cron != "" {
+		return cron.NewExpressionCron(
+			schedule.Cron,
+			run.SkipErrors(action),
+		)
+	}
+	return cron.NewWaitCron(
+		schedule.Wait,
+		run.SkipErrors(action),
+	)
+}
+
+// Validate returns an error if not exactly one of wait and cron is defined or the cron expression is invalid.
+func (s ScheduleConfig) Validate() error {
+	if s.Wait != 0 && s.Cron != "" {
+		return errors.New("wait and cron are exclusive")
+	}
+	if s.Cron != "" {
+		if _, err := robfig_cron.Parse(s.Cron); err != nil {
+			return errors.Wrapf(err, "parse cron '%s' failed", s.Cron)
+		}
+		return nil
+	}
+	if s.Wait <= 0 {
+		return errors.New("wait must be greater than 0")
+	}
+	return nil
+}
+
+// Next returns the time of the next scheduled run after now.
+// For a wait schedule it is the wait after the last scheduled run finished, zero if no scheduled run finished yet.
+func (s ScheduleConfig) Next(lastFinished time.Time, now time.Time) time.Time {
+	if s.Cron != "" {
+		schedule, err := robfig_cron.Parse(s.Cron)
+		if err != nil {
+			return time.Time{}
+		}
+		return schedule.Next(now)
+	}
+	if lastFinished.IsZero() {
+		return time.Time{}
+	}
+	return lastFinished.Add(s.Wait)
+}
+
+// scheduleIntervalSamples is the number of upcoming cron runs the interval is taken from.
+const scheduleIntervalSamples = 20
+
+// Interval returns the wait or the longest time between the upcoming runs of the cron expression,
+// e.g. the weekend for a cron running on weekdays.
+func (s ScheduleConfig) Interval(now time.Time) time.Duration {
+	if s.Cron == "" {
+		return s.Wait
+	}
+	var result time.Duration
+	next := s.Next(time.Time{}, now)
+	for i := 0; i < scheduleIntervalSamples && !next.IsZero(); i++ {
+		following := s.Next(time.Time{}, next)
+		if interval := following.Sub(next); interval > result {
+			result = interval
+		}
+		next = following
+	}
+	return result
+}
+
+// String returns the cron expression or the wait duration.
+func (s ScheduleConfig) String() string {
+	if s.Cron != "" {
+		return s.Cron
+	}
+	return "every " + s.Wait.String()
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/bborbe/kafka-k8s-version-collector/version"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Schedule", func() {
+	It("continues after failed action", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		var mux sync.Mutex
+		counter := 0
+		cron := version.NewCron(
+			version.ScheduleConfig{Wait: time.Millisecond},
+			func(ctx context.Context) error {
+				mux.Lock()
+				defer mux.Unlock()
+				counter++
+				if counter == 3 {
+					cancel()
+				}
+				return errors.New("banana")
+			},
+		)
+		Expect(cron.Run(ctx)).To(BeNil())
+		Expect(counter).To(Equal(3))
+	})
+	It("runs cron expression", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		cron := version.NewCron(
+			version.ScheduleConfig{Cron: "* * * * * *"},
+			func(ctx context.Context) error {
+				cancel()
+				return nil
+			},
+		)
+		Expect(cron.Run(ctx)).To(BeNil())
+	})
+	It("is valid with wait", func() {
+		Expect(version.ScheduleConfig{Wait: time.Hour}.Validate()).To(BeNil())
+	})
+	It("is valid with cron", func() {
+		Expect(version.ScheduleConfig{Cron: "0 */5 * * * *"}.Validate()).To(BeNil())
+	})
+	It("returns error if wait and cron", func() {
+		Expect(version.ScheduleConfig{Wait: time.Hour, Cron: "0 */5 * * * *"}.Validate()).To(HaveOccurred())
+	})
+	It("returns error if cron is invalid", func() {
+		Expect(version.ScheduleConfig{Cron: "banana"}.Validate()).To(HaveOccurred())
+	})
+	It("returns error if nothing defined", func() {
+		Expect(version.ScheduleConfig{}.Validate()).To(HaveOccurred())
+	})
+	It("returns next run of cron expression", func() {
+		now := time.Date(2019, 3, 1, 12, 3, 0, 0, time.UTC)
+		next := version.ScheduleConfig{Cron: "0 */5 * * * *"}.Next(time.Time{}, now)
+		Expect(next).To(Equal(time.Date(2019, 3, 1, 12, 5, 0, 0, time.UTC)))
+	})
+	It("returns next run of wait after last scheduled run", func() {
+		finished := time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC)
+		next := version.ScheduleConfig{Wait: time.Hour}.Next(finished, finished)
+		Expect(next).To(Equal(time.Date(2019, 3, 1, 13, 0, 0, 0, time.UTC)))
+	})
+	It("returns no next run of wait without scheduled run", func() {
+		next := version.ScheduleConfig{Wait: time.Hour}.Next(time.Time{}, time.Now())
+		Expect(next.IsZero()).To(BeTrue())
+	})
+	It("returns wait as interval", func() {
+		Expect(version.ScheduleConfig{Wait: time.Hour}.Interval(time.Now())).To(Equal(time.Hour))
+	})
+	It("returns longest interval of cron expression", func() {
+		now := time.Date(2019, 3, 1, 12, 3, 0, 0, time.UTC)
+		Expect(version.ScheduleConfig{Cron: "0 0 6 * * *"}.Interval(now)).To(Equal(24 * time.Hour))
+		Expect(version.ScheduleConfig{Cron: "0 0 6 * * MON-FRI"}.Interval(now)).To(Equal(72 * time.Hour))
+	})
+	It("describes schedule", func() {
+		Expect(version.ScheduleConfig{Wait: time.Hour}.String()).To(Equal("every 1h0m0s"))
+		Expect(version.ScheduleConfig{Cron: "0 0 6 * * *"}.String()).To(Equal("0 0 6 * * *"))
+	})
+})
@@ -0,0 +1,126 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// semVerRegexp matches semantic versions with optional v prefix.
+// Minor and patch are optional, because many images are tagged like 1.15.
+var semVerRegexp = regexp.MustCompile(`^[vV]?(0|[1-9][0-9]*)(?:\.(0|[1-9][0-9]*))?(?:\.(0|[1-9][0-9]*))?(?:-([0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*))?(?:\+([0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*))?$`)
+
+// SemVer is a parsed semantic version. (see https://semver.org)
+type SemVer struct {
+	Major      int
+	Minor      int
+	Patch      int
+	Prerelease string
+	Build      string
+}
+
+// ParseSemVer parses the given version like v1.13.4-beta.0+build.
+func ParseSemVer(value string) (*SemVer, error) {
+	matches := semVerRegexp.FindStringSubmatch(value)
+	if matches == nil {
+		return nil, errors.Errorf("parse semver '%s' failed", value)
+	}
+	result := &SemVer{
+		Prerelease: matches[4],
+		Build:      matches[5],
+	}
+	for i, target := range []*int{&result.Major, &result.Minor, &result.Patch} {
+		if matches[i+1] == "" {
+			continue
+		}
+		number, err := strconv.Atoi(matches[i+1])
+		if err != nil {
+			return nil, errors.Wrapf(err, "parse semver '%s' failed", value)
+		}
+		*target = number
+	}
+	return result, nil
+}
+
+// Stable returns true if the version is not a prerelease.
+func (s SemVer) Stable() bool {
+	return s.Prerelease == ""
+}
+
+// String returns the version without v prefix.
+func (s SemVer) String() string {
+	result := fmt.Sprintf("%d.%d.%d", s.Major, s.Minor, s.Patch)
+	if s.Prerelease != "" {
+		result += "-" + s.Prerelease
+	}
+	if s.Build != "" {
+		result += "+" + s.Build
+	}
+	return result
+}
+
+// Compare returns -1, 0 or 1 if the version has a lower, equal or higher precedence than the other.
+// Build metadata is ignored.
+func (s SemVer) Compare(other SemVer) int {
+	if result := compareInt(s.Major, other.Major); result != 0 {
+		return result
+	}
+	if result := compareInt(s.Minor, other.Minor); result != 0 {
+		return result
+	}
+	if result := compareInt(s.Patch, other.Patch); result != 0 {
+		return result
+	}
+	return comparePrerelease(s.Prerelease, other.Prerelease)
+}
+
+func comparePrerelease(a, b string) int {
+	if a == b {
+		return 0
+	}
+	if a == "" {
+		return 1
+	}
+	if b == "" {
+		return -1
+	}
+	as := strings.Split(a, ".")
+	bs := strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+		switch {
+		case aErr == nil && bErr == nil:
+			if result := compareInt(an, bn); result != 0 {
+				return result
+			}
+		case aErr == nil:
+			return -1
+		case bErr == nil:
+			return 1
+		default:
+			if result := strings.Compare(as[i], bs[i]); result != 0 {
+				return result
+			}
+		}
+	}
+	return compareInt(len(as), len(bs))
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
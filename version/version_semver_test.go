@@ -0,0 +1,99 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version_test
+
+import (
+	"github.com/bborbe/kafka-k8s-version-collector/version"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SemVer", func() {
+	for _, tc := range []struct {
+		value    string
+		expected version.SemVer
+	}{
+		{"1.2.3", version.SemVer{Major: 1, Minor: 2, Patch: 3}},
+		{"v1.13.4", version.SemVer{Major: 1, Minor: 13, Patch: 4}},
+		{"v1.13.4-beta.0", version.SemVer{Major: 1, Minor: 13, Patch: 4, Prerelease: "beta.0"}},
+		{"1.0.0+20190301", version.SemVer{Major: 1, Build: "20190301"}},
+		{"1.0.0-rc.1+exp.sha.5114f85", version.SemVer{Major: 1, Prerelease: "rc.1", Build: "exp.sha.5114f85"}},
+		{"1.15", version.SemVer{Major: 1, Minor: 15}},
+		{"5", version.SemVer{Major: 5}},
+	} {
+		tc := tc
+		It("parses "+tc.value, func() {
+			semVer, err := version.ParseSemVer(tc.value)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*semVer).To(Equal(tc.expected))
+		})
+	}
+	for _, value := range []string{"", "latest", "01.2.3", "1.2.3.4", "amd64-v1.2.3"} {
+		value := value
+		It("returns error for '"+value+"'", func() {
+			_, err := version.ParseSemVer(value)
+			Expect(err).To(HaveOccurred())
+		})
+	}
+	for _, tc := range []struct {
+		a        string
+		b        string
+		expected int
+	}{
+		{"1.2.3", "v1.2.3", 0},
+		{"2.0.0", "1.9.9", 1},
+		{"1.9.0", "1.10.0", -1},
+		{"1.2.3", "1.2.4", -1},
+		{"1.0.0", "1.0.0-rc.1", 1},
+		{"1.0.0-beta.2", "1.0.0-beta.11", -1},
+		{"1.0.0-1", "1.0.0-alpha", -1},
+		{"1.0.0-alpha.1", "1.0.0-alpha", 1},
+		{"1.0.0+a", "1.0.0+b", 0},
+	} {
+		tc := tc
+		It("compares "+tc.a+" with "+tc.b, func() {
+			semVerA, err := version.ParseSemVer(tc.a)
+			Expect(err).NotTo(HaveOccurred())
+			semVerB, err := version.ParseSemVer(tc.b)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(semVerA.Compare(*semVerB)).To(Equal(tc.expected))
+		})
+	}
+	It("is stable without prerelease", func() {
+		semVer, err := version.ParseSemVer("v1.13.4")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(semVer.Stable()).To(BeTrue())
+	})
+	It("is not stable with prerelease", func() {
+		semVer, err := version.ParseSemVer("v1.13.4-beta.0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(semVer.Stable()).To(BeFalse())
+	})
+})
+
+var _ = Describe("ApplicationVersionAvailable", func() {
+	It("fills semver fields", func() {
+		available := version.NewApplicationVersionAvailable("Kubernetes", "v1.13.4-beta.0")
+		Expect(available.App).To(Equal("Kubernetes"))
+		Expect(available.Version).To(Equal("v1.13.4-beta.0"))
+		Expect(available.Parsed).To(BeTrue())
+		Expect(available.Major).To(Equal(int32(1)))
+		Expect(available.Minor).To(Equal(int32(13)))
+		Expect(available.Patch).To(Equal(int32(4)))
+		Expect(available.Prerelease).To(Equal("beta.0"))
+		Expect(available.Stable).To(BeFalse())
+	})
+	It("flags unparsed versions", func() {
+		available := version.NewApplicationVersionAvailable("Kubernetes", "latest")
+		Expect(available.Version).To(Equal("latest"))
+		Expect(available.Parsed).To(BeFalse())
+		Expect(available.Stable).To(BeFalse())
+	})
+	It("flags versions exceeding int32 as unparsed", func() {
+		available := version.NewApplicationVersionAvailable("Nightly", "20190315123456.0.0")
+		Expect(available.Parsed).To(BeFalse())
+		Expect(available.Major).To(BeZero())
+	})
+})
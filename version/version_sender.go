@@ -18,18 +18,29 @@ import (
 
 //go:generate counterfeiter -o ../mocks/sender.go --fake-name Sender . Sender
 type Sender interface {
-	Send(ctx context.Context, versions <-chan avro.ApplicationVersionAvailable) error
+	// Send publishes the versions until the channel is closed and returns the number of published versions.
+	Send(ctx context.Context, versions <-chan avro.ApplicationVersionAvailable) (int, error)
 }
 
+// NewSender returns a Sender that publishes all versions not contained in the store.
+// Versions without app or version can not be stored and are skipped.
+// A version published before is published again if its channel or digest changed.
+// A tag published with digest whose digest changed is published as retagged with the digest published before as PreviousDigest,
+// a tag published without digest is not published again, its first digest is stored to detect later changes.
+// If force is true, all versions are published regardless of the store.
 func NewSender(
 	producer sarama.SyncProducer,
 	schemaRegistry schema.Registry,
 	kafkaTopic string,
+	store Store,
+	force bool,
 ) Sender {
 	return &sender{
 		producer:       producer,
 		schemaRegistry: schemaRegistry,
 		kafkaTopic:     kafkaTopic,
+		store:          store,
+		force:          force,
 	}
 }
 
@@ -37,36 +48,95 @@ type sender struct {
 	producer       sarama.SyncProducer
 	schemaRegistry schema.Registry
 	kafkaTopic     string
+	store          Store
+	force          bool
 }
 
-func (s *sender) Send(ctx context.Context, versions <-chan avro.ApplicationVersionAvailable) error {
+func (s *sender) Send(ctx context.Context, versions <-chan avro.ApplicationVersionAvailable) (int, error) {
+	var published int
 	for {
 		select {
 		case <-ctx.Done():
 			glog.V(3).Infof("context done => return")
-			return nil
+			return published, nil
 		case version, ok := <-versions:
 			if !ok {
 				glog.V(3).Infof("channel closed => return")
-				return nil
+				return published, nil
+			}
+			if version.App == "" || version.Version == "" {
+				glog.Warningf("version %q of app %q is empty => skip", version.Version, version.App)
+				continue
+			}
+			if !s.force {
+				contains, err := s.store.Contains(version.App, version.Version)
+				if err != nil {
+					return published, errors.Wrap(err, "check store failed")
+				}
+				if contains {
+					changed, err := s.changed(&version)
+					if err != nil {
+						return published, errors.Wrap(err, "get version from store failed")
+					}
+					if !changed {
+						versionsSkippedCounter.WithLabelValues(version.App).Inc()
+						glog.V(4).Infof("version %s of %s already published => skip", version.Version, version.App)
+						continue
+					}
+				}
 			}
 			schemaId, err := s.schemaRegistry.SchemaId(fmt.Sprintf("%s-value", s.kafkaTopic), version.Schema())
+			schemaRegistryLookupsCounter.WithLabelValues(resultLabel(err)).Inc()
 			if err != nil {
-				return errors.Wrap(err, "get schema id failed")
+				sendFailuresCounter.WithLabelValues(version.App).Inc()
+				return published, errors.Wrap(err, "get schema id failed")
 			}
 			buf := &bytes.Buffer{}
 			if err := version.Serialize(buf); err != nil {
-				return errors.Wrap(err, "serialize version failed")
+				return published, errors.Wrap(err, "serialize version failed")
 			}
-			partition, offset, err := s.producer.SendMessage(&sarama.ProducerMessage{
+			partition, offset, err := sendMessage(ctx, s.producer, &sarama.ProducerMessage{
 				Topic: s.kafkaTopic,
 				Key:   sarama.StringEncoder(fmt.Sprintf("%s-%s", version.App, version.Version)),
 				Value: &schema.AvroEncoder{SchemaId: schemaId, Content: buf.Bytes()},
 			})
 			if err != nil {
-				return errors.Wrap(err, "send message to kafka failed")
+				sendFailuresCounter.WithLabelValues(version.App).Inc()
+				return published, errors.Wrap(err, "send message to kafka failed")
 			}
+			versionsPublishedCounter.WithLabelValues(version.App).Inc()
+			published++
 			glog.V(3).Infof("send message successful to %s with partition %d offset %d", s.kafkaTopic, partition, offset)
+			if err := s.store.Add(version); err != nil {
+				return published, errors.Wrap(err, "add version to store failed")
+			}
+		}
+	}
+}
+
+// changed returns true if the version was published with another channel or digest.
+// A changed digest is recorded as PreviousDigest of the version.
+// The first digest of a version published without digest is stored without publishing the version again.
+func (s *sender) changed(version *avro.ApplicationVersionAvailable) (bool, error) {
+	stored, ok, err := s.store.Get(version.App, version.Version)
+	if err != nil || !ok {
+		return false, err
+	}
+	if version.Digest != "" && stored.Digest != "" && stored.Digest != version.Digest {
+		version.PreviousDigest = stored.Digest
+		versionsRetaggedCounter.WithLabelValues(version.App).Inc()
+		glog.V(1).Infof("tag %s of %s retagged from %s to %s => publish again", version.Version, version.App, stored.Digest, version.Digest)
+		return true, nil
+	}
+	if stored.Channel != version.Channel {
+		glog.V(3).Infof("channel of version %s of %s changed to %q => publish again", version.Version, version.App, version.Channel)
+		return true, nil
+	}
+	if version.Digest != "" && stored.Digest == "" {
+		glog.V(2).Infof("first digest %s of tag %s of %s => store", version.Digest, version.Version, version.App)
+		if err := s.store.Add(*version); err != nil {
+			return false, errors.Wrap(err, "add digest to store failed")
 		}
 	}
+	return false, nil
 }
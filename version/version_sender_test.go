@@ -10,32 +10,37 @@ import (
 
 	mocksmocks "github.com/Shopify/sarama/mocks"
 	"github.com/bborbe/kafka-k8s-version-collector/avro"
+	"github.com/bborbe/kafka-k8s-version-collector/mocks"
 	"github.com/bborbe/kafka-k8s-version-collector/version"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
-	"github.com/seibert-media/go-kafka/mocks"
+	gokafkamocks "github.com/seibert-media/go-kafka/mocks"
 )
 
 var _ = Describe("Version Sender", func() {
 	var sender version.Sender
 	var producer *mocksmocks.SyncProducer
 	var topic string
-	var schemaRegistry *mocks.SchemaRegistry
+	var schemaRegistry *gokafkamocks.SchemaRegistry
+	var store *mocks.Store
 	BeforeEach(func() {
 		var t GinkgoTestReporter
 		producer = mocksmocks.NewSyncProducer(t, nil)
 		topic = "my-topic"
-		schemaRegistry = &mocks.SchemaRegistry{}
+		schemaRegistry = &gokafkamocks.SchemaRegistry{}
+		store = &mocks.Store{}
 		sender = version.NewSender(
 			producer,
 			schemaRegistry,
 			topic,
+			store,
+			false,
 		)
 	})
 	It("send until channel is closed", func() {
 		versions := make(chan avro.ApplicationVersionAvailable)
 		close(versions)
-		err := sender.Send(context.Background(), versions)
+		_, err := sender.Send(context.Background(), versions)
 		Expect(err).NotTo(HaveOccurred())
 	})
 	It("send until channel is closed", func() {
@@ -43,7 +48,7 @@ var _ = Describe("Version Sender", func() {
 		cancel()
 		versions := make(chan avro.ApplicationVersionAvailable)
 		defer close(versions)
-		err := sender.Send(ctx, versions)
+		_, err := sender.Send(ctx, versions)
 		Expect(err).NotTo(HaveOccurred())
 	})
 	It("send version to producer", func() {
@@ -53,26 +58,147 @@ var _ = Describe("Version Sender", func() {
 			return nil
 		})
 		versions := make(chan avro.ApplicationVersionAvailable, 2)
-		versions <- *avro.NewApplicationVersionAvailable()
+		versions <- avro.ApplicationVersionAvailable{App: "Kubernetes", Version: "v1.13.4"}
 		close(versions)
-		err := sender.Send(context.Background(), versions)
+		published, err := sender.Send(context.Background(), versions)
 		Expect(err).To(BeNil())
+		Expect(published).To(Equal(1))
 		Expect(counter).To(Equal(1))
 	})
+	It("skips versions without app or version", func() {
+		producer.ExpectSendMessageAndSucceed()
+		versions := make(chan avro.ApplicationVersionAvailable, 3)
+		versions <- avro.ApplicationVersionAvailable{App: "", Version: "v1.13.4"}
+		versions <- avro.ApplicationVersionAvailable{App: "Kubernetes", Version: ""}
+		versions <- avro.ApplicationVersionAvailable{App: "Kubernetes", Version: "v1.13.4"}
+		close(versions)
+		published, err := sender.Send(context.Background(), versions)
+		Expect(err).To(BeNil())
+		Expect(published).To(Equal(1))
+		Expect(store.AddCallCount()).To(Equal(1))
+		Expect(store.ContainsCallCount()).To(Equal(1))
+	})
+	It("adds send version to store", func() {
+		producer.ExpectSendMessageAndSucceed()
+		versions := make(chan avro.ApplicationVersionAvailable, 2)
+		versions <- avro.ApplicationVersionAvailable{App: "Kubernetes", Version: "v1.13.4"}
+		close(versions)
+		_, err := sender.Send(context.Background(), versions)
+		Expect(err).To(BeNil())
+		Expect(store.AddCallCount()).To(Equal(1))
+		Expect(store.AddArgsForCall(0).Version).To(Equal("v1.13.4"))
+	})
+	It("skips versions already in store", func() {
+		store.ContainsReturns(true, nil)
+		versions := make(chan avro.ApplicationVersionAvailable, 2)
+		versions <- avro.ApplicationVersionAvailable{App: "Kubernetes", Version: "v1.13.4"}
+		close(versions)
+		published, err := sender.Send(context.Background(), versions)
+		Expect(err).To(BeNil())
+		Expect(published).To(Equal(0))
+		Expect(schemaRegistry.SchemaIdCallCount()).To(Equal(0))
+		Expect(store.AddCallCount()).To(Equal(0))
+	})
+	It("sends versions already in store again if channel changed", func() {
+		store.ContainsReturns(true, nil)
+		store.GetReturns(avro.ApplicationVersionAvailable{App: "Kubernetes", Version: "v1.13.4", Channel: "stable"}, true, nil)
+		producer.ExpectSendMessageAndSucceed()
+		versions := make(chan avro.ApplicationVersionAvailable, 2)
+		versions <- avro.ApplicationVersionAvailable{App: "Kubernetes", Version: "v1.13.4", Channel: "stable-1.13"}
+		close(versions)
+		published, err := sender.Send(context.Background(), versions)
+		Expect(err).To(BeNil())
+		Expect(published).To(Equal(1))
+		Expect(store.AddArgsForCall(0).Channel).To(Equal("stable-1.13"))
+	})
+	It("skips versions already in store with same channel", func() {
+		store.ContainsReturns(true, nil)
+		store.GetReturns(avro.ApplicationVersionAvailable{App: "Kubernetes", Version: "v1.13.4", Channel: "stable"}, true, nil)
+		versions := make(chan avro.ApplicationVersionAvailable, 2)
+		versions <- avro.ApplicationVersionAvailable{App: "Kubernetes", Version: "v1.13.4", Channel: "stable"}
+		close(versions)
+		published, err := sender.Send(context.Background(), versions)
+		Expect(err).To(BeNil())
+		Expect(published).To(Equal(0))
+	})
+	It("sends retagged version with previous digest", func() {
+		store.ContainsReturns(true, nil)
+		store.GetReturns(avro.ApplicationVersionAvailable{App: "Kubernetes", Version: "v1.13.4", Digest: "sha256:old"}, true, nil)
+		producer.ExpectSendMessageAndSucceed()
+		versions := make(chan avro.ApplicationVersionAvailable, 2)
+		versions <- avro.ApplicationVersionAvailable{App: "Kubernetes", Version: "v1.13.4", Digest: "sha256:new"}
+		close(versions)
+		published, err := sender.Send(context.Background(), versions)
+		Expect(err).To(BeNil())
+		Expect(published).To(Equal(1))
+		Expect(store.AddArgsForCall(0).Digest).To(Equal("sha256:new"))
+		Expect(store.AddArgsForCall(0).PreviousDigest).To(Equal("sha256:old"))
+	})
+	It("stores first digest of versions already in store without digest", func() {
+		store.ContainsReturns(true, nil)
+		store.GetReturns(avro.ApplicationVersionAvailable{App: "Kubernetes", Version: "v1.13.4"}, true, nil)
+		versions := make(chan avro.ApplicationVersionAvailable, 2)
+		versions <- avro.ApplicationVersionAvailable{App: "Kubernetes", Version: "v1.13.4", Digest: "sha256:new"}
+		close(versions)
+		published, err := sender.Send(context.Background(), versions)
+		Expect(err).To(BeNil())
+		Expect(published).To(Equal(0))
+		Expect(store.AddCallCount()).To(Equal(1))
+		Expect(store.AddArgsForCall(0).Digest).To(Equal("sha256:new"))
+		Expect(store.AddArgsForCall(0).PreviousDigest).To(Equal(""))
+	})
+	It("skips versions already in store with same or unknown digest", func() {
+		store.ContainsReturns(true, nil)
+		store.GetReturns(avro.ApplicationVersionAvailable{App: "Kubernetes", Version: "v1.13.4", Digest: "sha256:old"}, true, nil)
+		versions := make(chan avro.ApplicationVersionAvailable, 2)
+		versions <- avro.ApplicationVersionAvailable{App: "Kubernetes", Version: "v1.13.4", Digest: "sha256:old"}
+		versions <- avro.ApplicationVersionAvailable{App: "Kubernetes", Version: "v1.13.4"}
+		close(versions)
+		published, err := sender.Send(context.Background(), versions)
+		Expect(err).To(BeNil())
+		Expect(published).To(Equal(0))
+		Expect(store.AddCallCount()).To(Equal(0))
+	})
+	It("sends versions already in store if forced", func() {
+		sender = version.NewSender(
+			producer,
+			schemaRegistry,
+			topic,
+			store,
+			true,
+		)
+		store.ContainsReturns(true, nil)
+		producer.ExpectSendMessageAndSucceed()
+		versions := make(chan avro.ApplicationVersionAvailable, 2)
+		versions <- avro.ApplicationVersionAvailable{App: "Kubernetes", Version: "v1.13.4"}
+		close(versions)
+		_, err := sender.Send(context.Background(), versions)
+		Expect(err).To(BeNil())
+		Expect(store.AddCallCount()).To(Equal(1))
+	})
+	It("returns error if store fails", func() {
+		store.ContainsReturns(false, errors.New("banana"))
+		versions := make(chan avro.ApplicationVersionAvailable, 2)
+		versions <- avro.ApplicationVersionAvailable{App: "Kubernetes", Version: "v1.13.4"}
+		close(versions)
+		_, err := sender.Send(context.Background(), versions)
+		Expect(err).To(HaveOccurred())
+	})
 	It("returns error if get schemaId fails", func() {
 		schemaRegistry.SchemaIdReturns(0, errors.New("banana"))
 		versions := make(chan avro.ApplicationVersionAvailable, 2)
-		versions <- *avro.NewApplicationVersionAvailable()
+		versions <- avro.ApplicationVersionAvailable{App: "Kubernetes", Version: "v1.13.4"}
 		close(versions)
-		err := sender.Send(context.Background(), versions)
+		_, err := sender.Send(context.Background(), versions)
 		Expect(err).To(HaveOccurred())
 	})
 	It("returns error if send message fails", func() {
 		producer.ExpectSendMessageAndFail(errors.New("banana"))
 		versions := make(chan avro.ApplicationVersionAvailable, 2)
-		versions <- *avro.NewApplicationVersionAvailable()
+		versions <- avro.ApplicationVersionAvailable{App: "Kubernetes", Version: "v1.13.4"}
 		close(versions)
-		err := sender.Send(context.Background(), versions)
+		_, err := sender.Send(context.Background(), versions)
 		Expect(err).To(HaveOccurred())
+		Expect(store.AddCallCount()).To(Equal(0))
 	})
 })
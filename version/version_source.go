@@ -0,0 +1,252 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// DockerHubRegistry is the registry of Docker Hub images.
+const DockerHubRegistry = "https://registry-1.docker.io"
+
+// DockerHubRepository returns the repository of a Docker Hub image.
+// Official images like nginx are located in the library namespace.
+func DockerHubRepository(name string) string {
+	if strings.Contains(name, "/") {
+		return name
+	}
+	return "library/" + name
+}
+
+// sourceImage returns the image of a registry or dockerhub source.
+func sourceImage(source SourceConfig) Image {
+	if source.Type != SourceTypeDockerHub {
+		return Image{
+			Registry:   source.Registry,
+			Repository: source.Repository,
+			App:        source.App,
+		}
+	}
+	registry := source.Registry
+	if registry == "" {
+		registry = DockerHubRegistry
+	}
+	return Image{
+		Registry:   registry,
+		Repository: DockerHubRepository(source.Repository),
+		App:        source.App,
+	}
+}
+
+// NewSourceFilter returns the Filter for the given source.
+// Tags of sources with ResolveDigests passing the filter are resolved with NewDigestResolver.
+func NewSourceFilter(
+	transport http.RoundTripper,
+	config *Config,
+	source SourceConfig,
+	store Store,
+) (Filter, error) {
+	filter, err := NewFilter(source.Filter)
+	if err != nil {
+		return nil, err
+	}
+	if !source.ResolveDigests {
+		return filter, nil
+	}
+	username, password := config.CredentialsFor(source)
+	image := sourceImage(source)
+	return NewDigestResolver(
+		filter,
+		&http.Client{
+			Transport: NewBearerTokenRoundTripper(transport, image.Registry, username, password),
+		},
+		image,
+		store,
+	), nil
+}
+
+// NewSourceFetcher returns the Fetcher for the given source.
+func NewSourceFetcher(
+	transport http.RoundTripper,
+	config *Config,
+	source SourceConfig,
+) (Fetcher, error) {
+	username, password := config.CredentialsFor(source)
+	switch source.Type {
+	case SourceTypeRegistry, SourceTypeDockerHub:
+		image := sourceImage(source)
+		return NewFetcher(
+			&http.Client{
+				Transport: NewBearerTokenRoundTripper(transport, image.Registry, username, password),
+			},
+			source.PageSize,
+			source.MaxPages,
+			image,
+		), nil
+	case SourceTypeGitHub, SourceTypeGitHubTags:
+		api := source.URL
+		if api == "" {
+			api = GitHubAPI
+		}
+		var authorization string
+		if password != "" {
+			authorization = "Bearer " + password
+		}
+		httpClient := &http.Client{
+			Transport: NewAuthorizationRoundTripper(transport, api, authorization),
+		}
+		repository := GitHubRepository{
+			API:        api,
+			Repository: source.Repository,
+			App:        source.App,
+		}
+		if source.Type == SourceTypeGitHubTags {
+			return NewGitHubTagsFetcher(httpClient, source.PageSize, source.MaxPages, repository), nil
+		}
+		return NewGitHubReleasesFetcher(httpClient, source.PageSize, source.MaxPages, source.IncludeDrafts, repository), nil
+	case SourceTypeHelm:
+		return NewHelmFetcher(
+			&http.Client{
+				Transport: NewAuthorizationRoundTripper(transport, source.URL, basicAuthorization(username, password)),
+			},
+			HelmChart{
+				Repository:    source.URL,
+				Chart:         source.Chart,
+				App:           source.App,
+				AppVersionApp: source.AppVersionApp,
+			},
+		), nil
+	case SourceTypeKubernetes:
+		release := source.URL
+		if release == "" {
+			release = KubernetesReleaseURL
+		}
+		return NewKubernetesFetcher(
+			&http.Client{
+				Transport: transport,
+			},
+			KubernetesRelease{
+				URL:     release,
+				Listing: source.Listing,
+				Minors:  source.Minors,
+				App:     source.App,
+			},
+		), nil
+	case SourceTypeGit:
+		return NewGitTagsFetcher(
+			&http.Client{
+				Transport: NewAuthorizationRoundTripper(transport, source.URL, basicAuthorization(username, password)),
+			},
+			GitRepository{
+				URL: source.URL,
+				App: source.App,
+			},
+		), nil
+	case SourceTypeGoProxy, SourceTypeNpm, SourceTypePyPI, SourceTypeMaven, SourceTypeCrates:
+		return newPackageFetcher(transport, source, basicAuthorization(username, password)), nil
+	case SourceTypeApt, SourceTypeRPM, SourceTypeApk:
+		httpClient := &http.Client{
+			Transport: NewAuthorizationRoundTripper(transport, source.URL, basicAuthorization(username, password)),
+		}
+		pkg := DistroPackage{
+			URL:  source.URL,
+			Name: source.Package,
+			App:  source.App,
+		}
+		switch source.Type {
+		case SourceTypeApt:
+			return NewAptFetcher(httpClient, pkg), nil
+		case SourceTypeRPM:
+			return NewRPMFetcher(httpClient, pkg), nil
+		default:
+			return NewApkFetcher(httpClient, pkg), nil
+		}
+	case SourceTypeCluster:
+		return nil, errors.Errorf("source type %s is created with NewClusterSourceFetcher", source.Type)
+	default:
+		return nil, errors.Errorf("unknown source type %s", source.Type)
+	}
+}
+
+// NewClusterSourceFetcher returns the Fetcher of a cluster source publishing the deployed versions with the sender.
+// The cluster is read with the kubeconfig of the source or the service account of the pod if none is given.
+func NewClusterSourceFetcher(
+	config *Config,
+	source SourceConfig,
+	sender DeploymentSender,
+) (Fetcher, error) {
+	var clusterConfig *ClusterConfig
+	var err error
+	if source.Kubeconfig != "" {
+		clusterConfig, err = LoadKubeconfig(source.Kubeconfig, source.Context)
+	} else {
+		clusterConfig, err = InClusterConfig(ServiceAccountDir)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "load cluster config failed")
+	}
+	httpClient, err := clusterConfig.HTTPClient()
+	if err != nil {
+		return nil, errors.Wrap(err, "create cluster client failed")
+	}
+	apps, err := NewImageApps(config)
+	if err != nil {
+		return nil, err
+	}
+	return NewClusterFetcher(
+		NewClusterInventory(httpClient, Cluster{
+			Name:       source.Name,
+			Server:     clusterConfig.Server,
+			Namespaces: source.Namespaces,
+			App:        source.App,
+			Apps:       apps,
+		}),
+		sender,
+	), nil
+}
+
+// newPackageFetcher returns the Fetcher of the package registry source with the public registry as default url.
+func newPackageFetcher(transport http.RoundTripper, source SourceConfig, authorization string) Fetcher {
+	pkg := Package{
+		URL:  source.URL,
+		Name: source.Package,
+		App:  source.App,
+	}
+	var defaultURL string
+	var newFetcher func(*http.Client, Package) Fetcher
+	switch source.Type {
+	case SourceTypeGoProxy:
+		defaultURL, newFetcher = GoProxyURL, NewGoProxyFetcher
+	case SourceTypeNpm:
+		defaultURL, newFetcher = NpmRegistryURL, NewNpmFetcher
+	case SourceTypePyPI:
+		defaultURL, newFetcher = PyPIURL, NewPyPIFetcher
+	case SourceTypeMaven:
+		defaultURL, newFetcher = MavenCentralURL, NewMavenFetcher
+	default:
+		defaultURL, newFetcher = CratesIndexURL, NewCratesFetcher
+	}
+	if pkg.URL == "" {
+		pkg.URL = defaultURL
+	}
+	return newFetcher(
+		&http.Client{
+			Transport: NewAuthorizationRoundTripper(transport, pkg.URL, authorization),
+		},
+		pkg,
+	)
+}
+
+// basicAuthorization returns the Authorization header of the credentials or an empty string without username.
+func basicAuthorization(username string, password string) string {
+	if username == "" {
+		return ""
+	}
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+}
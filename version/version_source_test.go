@@ -0,0 +1,156 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/bborbe/kafka-k8s-version-collector/avro"
+	"github.com/bborbe/kafka-k8s-version-collector/version"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+)
+
+var _ = Describe("Source Fetcher", func() {
+	var server *ghttp.Server
+	var config *version.Config
+	BeforeEach(func() {
+		server = ghttp.NewServer()
+		config = &version.Config{}
+	})
+	AfterEach(func() {
+		server.Close()
+	})
+	It("returns registry fetcher", func() {
+		server.RouteToHandler(http.MethodGet, "/v2/grafana/grafana/tags/list", func(resp http.ResponseWriter, req *http.Request) {
+			fmt.Fprint(resp, `{"tags":["6.0.0"]}`)
+		})
+		fetcher, err := version.NewSourceFetcher(http.DefaultTransport, config, version.SourceConfig{
+			Name:       "grafana",
+			Type:       version.SourceTypeRegistry,
+			Registry:   server.URL(),
+			Repository: "grafana/grafana",
+			App:        "Grafana",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		versions := make(chan avro.ApplicationVersionAvailable, 1)
+		Expect(fetcher.Fetch(context.Background(), versions)).To(BeNil())
+		Expect((<-versions).App).To(Equal("Grafana"))
+	})
+	It("returns error for unknown type", func() {
+		_, err := version.NewSourceFetcher(http.DefaultTransport, config, version.SourceConfig{
+			Type: "banana",
+		})
+		Expect(err).To(HaveOccurred())
+	})
+	It("returns dockerhub fetcher for official image", func() {
+		server.RouteToHandler(http.MethodGet, "/v2/library/nginx/tags/list", func(resp http.ResponseWriter, req *http.Request) {
+			fmt.Fprint(resp, `{"tags":["1.15.8"]}`)
+		})
+		fetcher, err := version.NewSourceFetcher(http.DefaultTransport, config, version.SourceConfig{
+			Name:       "nginx",
+			Type:       version.SourceTypeDockerHub,
+			Registry:   server.URL(),
+			Repository: "nginx",
+			App:        "Nginx",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		versions := make(chan avro.ApplicationVersionAvailable, 1)
+		Expect(fetcher.Fetch(context.Background(), versions)).To(BeNil())
+		v := <-versions
+		Expect(v.App).To(Equal("Nginx"))
+		Expect(v.Version).To(Equal("1.15.8"))
+	})
+	It("returns repository of dockerhub image", func() {
+		Expect(version.DockerHubRepository("nginx")).To(Equal("library/nginx"))
+		Expect(version.DockerHubRepository("grafana/grafana")).To(Equal("grafana/grafana"))
+	})
+	It("returns helm fetcher with basic auth", func() {
+		server.RouteToHandler(http.MethodGet, "/index.yaml", func(resp http.ResponseWriter, req *http.Request) {
+			fmt.Fprint(resp, "entries:\n  nginx-ingress:\n  - version: 1.3.1\n")
+		})
+		config.Credentials = map[string]version.CredentialsConfig{
+			"charts": {Username: "bborbe", Password: "secret"},
+		}
+		fetcher, err := version.NewSourceFetcher(http.DefaultTransport, config, version.SourceConfig{
+			Name:        "nginx-ingress",
+			Type:        version.SourceTypeHelm,
+			URL:         server.URL(),
+			Chart:       "nginx-ingress",
+			App:         "Nginx Ingress",
+			Credentials: "charts",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		versions := make(chan avro.ApplicationVersionAvailable, 1)
+		Expect(fetcher.Fetch(context.Background(), versions)).To(BeNil())
+		Expect((<-versions).Version).To(Equal("1.3.1"))
+		username, password, ok := server.ReceivedRequests()[0].BasicAuth()
+		Expect(ok).To(BeTrue())
+		Expect(username).To(Equal("bborbe"))
+		Expect(password).To(Equal("secret"))
+	})
+	It("returns git fetcher with basic auth", func() {
+		server.RouteToHandler(http.MethodGet, "/project.git/info/refs", func(resp http.ResponseWriter, req *http.Request) {
+			resp.Header().Set("Content-Type", "application/x-git-upload-pack-advertisement")
+			fmt.Fprint(resp, "0000003d1111111111111111111111111111111111111111 refs/tags/1.0.0\n0000")
+		})
+		config.Credentials = map[string]version.CredentialsConfig{
+			"git": {Username: "bborbe", Password: "secret"},
+		}
+		fetcher, err := version.NewSourceFetcher(http.DefaultTransport, config, version.SourceConfig{
+			Name:        "project",
+			Type:        version.SourceTypeGit,
+			URL:         server.URL() + "/project.git",
+			App:         "Project",
+			Credentials: "git",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		versions := make(chan avro.ApplicationVersionAvailable, 1)
+		Expect(fetcher.Fetch(context.Background(), versions)).To(BeNil())
+		Expect((<-versions).Version).To(Equal("1.0.0"))
+		username, _, ok := server.ReceivedRequests()[0].BasicAuth()
+		Expect(ok).To(BeTrue())
+		Expect(username).To(Equal("bborbe"))
+	})
+	It("returns package fetcher of ecosystem", func() {
+		server.RouteToHandler(http.MethodGet, "/pypi/requests/json", func(resp http.ResponseWriter, req *http.Request) {
+			fmt.Fprint(resp, `{"releases":{"2.21.0":[]}}`)
+		})
+		fetcher, err := version.NewSourceFetcher(http.DefaultTransport, config, version.SourceConfig{
+			Name:    "requests",
+			Type:    version.SourceTypePyPI,
+			URL:     server.URL(),
+			Package: "requests",
+			App:     "Requests",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		versions := make(chan avro.ApplicationVersionAvailable, 1)
+		Expect(fetcher.Fetch(context.Background(), versions)).To(BeNil())
+		result := <-versions
+		Expect(result.Version).To(Equal("2.21.0"))
+		Expect(result.Ecosystem).To(Equal(version.EcosystemPyPI))
+	})
+	It("returns distribution package fetcher", func() {
+		server.RouteToHandler(http.MethodGet, "/debian/Packages.gz", func(resp http.ResponseWriter, req *http.Request) {
+			fmt.Fprint(resp, "Package: openssl\nVersion: 1.1.0j-1~deb9u1\n")
+		})
+		fetcher, err := version.NewSourceFetcher(http.DefaultTransport, config, version.SourceConfig{
+			Name:    "openssl",
+			Type:    version.SourceTypeApt,
+			URL:     server.URL() + "/debian",
+			Package: "openssl",
+			App:     "OpenSSL",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		versions := make(chan avro.ApplicationVersionAvailable, 1)
+		Expect(fetcher.Fetch(context.Background(), versions)).To(BeNil())
+		result := <-versions
+		Expect(result.Version).To(Equal("1.1.0j-1~deb9u1"))
+		Expect(result.Ecosystem).To(Equal(version.EcosystemDebian))
+	})
+})
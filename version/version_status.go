@@ -0,0 +1,134 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// Status describes the configured sources and the recent runs.
+type Status struct {
+	Sources []SourceStatus `json:"sources"`
+	Runs    []SyncRun      `json:"runs"`
+}
+
+// SourceStatus describes the last and next run of a source.
+type SourceStatus struct {
+	Name     string     `json:"name"`
+	Schedule string     `json:"schedule"`
+	LastRun  *SourceRun `json:"lastRun,omitempty"`
+	NextRun  *time.Time `json:"nextRun,omitempty"`
+}
+
+// Status returns the status of the given sources at the given time.
+func (h *History) Status(sources []SourceConfig, now time.Time) Status {
+	status := Status{
+		Sources: []SourceStatus{},
+		Runs:    h.Runs(),
+	}
+	for _, source := range sources {
+		sourceStatus := SourceStatus{
+			Name:     source.Name,
+			Schedule: source.Schedule.String(),
+		}
+		if lastRun, ok := h.LastResult(source.Name); ok {
+			sourceStatus.LastRun = &lastRun
+		}
+		if next := source.Schedule.Next(h.LastScheduled(source.Name), now); !next.IsZero() {
+			sourceStatus.NextRun = &next
+		}
+		status.Sources = append(status.Sources, sourceStatus)
+	}
+	return status
+}
+
+// NewStatusHandler returns a http.Handler responding the status as json.
+func NewStatusHandler(history *History, sources []SourceConfig) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		resp.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(resp).Encode(history.Status(sources, time.Now())); err != nil {
+			glog.Warningf("encode status failed: %v", err)
+		}
+	})
+}
+
+// NewStatusPageHandler returns a http.Handler responding the status as html page.
+func NewStatusPageHandler(history *History, sources []SourceConfig) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/" {
+			http.NotFound(resp, req)
+			return
+		}
+		resp.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := statusPageTemplate.Execute(resp, history.Status(sources, time.Now())); err != nil {
+			glog.Warningf("render status page failed: %v", err)
+		}
+	})
+}
+
+func formatTime(value interface{}) string {
+	switch t := value.(type) {
+	case time.Time:
+		if t.IsZero() {
+			return "-"
+		}
+		return t.Format(time.RFC3339)
+	case *time.Time:
+		if t == nil || t.IsZero() {
+			return "-"
+		}
+		return t.Format(time.RFC3339)
+	default:
+		return "-"
+	}
+}
+
+var statusPageTemplate = template.Must(template.New("status").Funcs(template.FuncMap{
+	"time": formatTime,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<title>Kafka Version Collector</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; }
+.failure { color: #c00; }
+</style>
+</head>
+<body>
+<h1>Kafka Version Collector</h1>
+<h2>Sources</h2>
+<table>
+<tr><th>Source</th><th>Schedule</th><th>Last start</th><th>Last end</th><th>Duration</th><th>Fetched</th><th>Published</th><th>Last error</th><th>Next run</th></tr>
+{{range .Sources}}<tr>
+<td>{{.Name}}</td>
+<td>{{.Schedule}}</td>
+{{with .LastRun}}<td>{{time .Started}}</td><td>{{time .Finished}}</td><td>{{.Duration}}</td><td>{{.Fetched}}</td><td>{{.Published}}</td><td class="failure">{{.Error}}</td>{{else}}<td>-</td><td>-</td><td>-</td><td>-</td><td>-</td><td></td>{{end}}
+<td>{{time .NextRun}}</td>
+</tr>
+{{end}}</table>
+<h2>Runs</h2>
+<table>
+<tr><th>ID</th><th>Trigger</th><th>Sources</th><th>Started</th><th>Finished</th><th>Status</th><th>Error</th></tr>
+{{range .Runs}}<tr>
+<td>{{.ID}}</td>
+<td>{{.Trigger}}</td>
+<td>{{range $i, $source := .Sources}}{{if $i}}, {{end}}{{$source}}{{end}}</td>
+<td>{{time .Started}}</td>
+<td>{{time .Finished}}</td>
+<td class="{{.Status}}">{{.Status}}</td>
+<td>{{.Error}}</td>
+</tr>
+{{end}}</table>
+<p><a href="status">json</a> <a href="metrics">metrics</a></p>
+</body>
+</html>
+`))
@@ -0,0 +1,64 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/bborbe/kafka-k8s-version-collector/version"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Status", func() {
+	var history *version.History
+	var sources []version.SourceConfig
+	var recorder *httptest.ResponseRecorder
+	BeforeEach(func() {
+		history = version.NewHistory(10)
+		history.Add(version.SyncRun{
+			ID:      "1234",
+			Trigger: version.SyncTriggerSchedule,
+			Sources: []string{"grafana"},
+			Status:  version.SyncStatusFailure,
+			Error:   "banana",
+			Results: []version.SourceResult{
+				{Source: "grafana", Fetched: 42, Error: "banana"},
+			},
+		})
+		sources = []version.SourceConfig{
+			{Name: "grafana", Schedule: version.ScheduleConfig{Cron: "0 0 6 * * *"}},
+		}
+		recorder = httptest.NewRecorder()
+	})
+	It("returns status as json", func() {
+		version.NewStatusHandler(history, sources).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/status", nil))
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		var status version.Status
+		Expect(json.NewDecoder(recorder.Body).Decode(&status)).To(BeNil())
+		Expect(status.Sources).To(HaveLen(1))
+		Expect(status.Sources[0].Name).To(Equal("grafana"))
+		Expect(status.Sources[0].Schedule).To(Equal("0 0 6 * * *"))
+		Expect(status.Sources[0].LastRun.RunID).To(Equal("1234"))
+		Expect(status.Sources[0].LastRun.Fetched).To(Equal(42))
+		Expect(status.Sources[0].LastRun.Error).To(Equal("banana"))
+		Expect(*status.Sources[0].NextRun).To(BeTemporally(">", time.Now()))
+		Expect(status.Runs).To(HaveLen(1))
+	})
+	It("returns status page", func() {
+		version.NewStatusPageHandler(history, sources).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Body.String()).To(ContainSubstring("<td>grafana</td>"))
+		Expect(recorder.Body.String()).To(ContainSubstring("banana"))
+		Expect(recorder.Body.String()).To(ContainSubstring("1234"))
+	})
+	It("returns not found for other pages", func() {
+		version.NewStatusPageHandler(history, sources).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/banana", nil))
+		Expect(recorder.Code).To(Equal(http.StatusNotFound))
+	})
+})
@@ -0,0 +1,162 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"bytes"
+
+	"github.com/bborbe/kafka-k8s-version-collector/avro"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+var versionsBucketName = []byte("versions")
+
+//go:generate counterfeiter -o ../mocks/store.go --fake-name Store . Store
+type Store interface {
+	// Contains returns true if the version of the app was already published.
+	Contains(app string, version string) (bool, error)
+	// Get returns the published version of the app and false if it was not published.
+	Get(app string, version string) (avro.ApplicationVersionAvailable, bool, error)
+	// Add marks the version as published.
+	Add(version avro.ApplicationVersionAvailable) error
+	// Apps returns the names of all apps with published versions.
+	Apps() ([]string, error)
+	// Versions returns all published versions of the app.
+	Versions(app string) ([]avro.ApplicationVersionAvailable, error)
+}
+
+// NewStore returns a Store that saves published versions in the given Bolt database.
+func NewStore(db *bolt.DB) Store {
+	return &store{
+		db: db,
+	}
+}
+
+type store struct {
+	db *bolt.DB
+}
+
+func (s *store) Contains(app string, version string) (bool, error) {
+	var result bool
+	err := s.db.View(func(tx *bolt.Tx) error {
+		versions := tx.Bucket(versionsBucketName)
+		if versions == nil {
+			return nil
+		}
+		bucket := versions.Bucket([]byte(app))
+		if bucket == nil {
+			return nil
+		}
+		result = bucket.Get([]byte(version)) != nil
+		return nil
+	})
+	if err != nil {
+		return false, errors.Wrap(err, "view failed")
+	}
+	return result, nil
+}
+
+// Get returns the published version of the app.
+// A record written with an older schema is returned with the fields parsed from the version.
+func (s *store) Get(app string, version string) (avro.ApplicationVersionAvailable, bool, error) {
+	var result avro.ApplicationVersionAvailable
+	var found bool
+	err := s.db.View(func(tx *bolt.Tx) error {
+		versions := tx.Bucket(versionsBucketName)
+		if versions == nil {
+			return nil
+		}
+		bucket := versions.Bucket([]byte(app))
+		if bucket == nil {
+			return nil
+		}
+		value := bucket.Get([]byte(version))
+		if value == nil {
+			return nil
+		}
+		found = true
+		result = deserializeVersion(app, version, value)
+		return nil
+	})
+	if err != nil {
+		return result, false, errors.Wrap(err, "view failed")
+	}
+	return result, found, nil
+}
+
+func (s *store) Add(version avro.ApplicationVersionAvailable) error {
+	buf := &bytes.Buffer{}
+	if err := version.Serialize(buf); err != nil {
+		return errors.Wrap(err, "serialize version failed")
+	}
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		versions, err := tx.CreateBucketIfNotExists(versionsBucketName)
+		if err != nil {
+			return errors.Wrap(err, "create versions bucket failed")
+		}
+		bucket, err := versions.CreateBucketIfNotExists([]byte(version.App))
+		if err != nil {
+			return errors.Wrapf(err, "create bucket for app %s failed", version.App)
+		}
+		return bucket.Put([]byte(version.Version), buf.Bytes())
+	})
+	return errors.Wrap(err, "update failed")
+}
+
+func (s *store) Apps() ([]string, error) {
+	var result []string
+	err := s.db.View(func(tx *bolt.Tx) error {
+		versions := tx.Bucket(versionsBucketName)
+		if versions == nil {
+			return nil
+		}
+		return versions.ForEach(func(key, value []byte) error {
+			if value == nil {
+				result = append(result, string(key))
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "view failed")
+	}
+	return result, nil
+}
+
+// Versions returns the published versions of the app.
+// Records written with an older schema are returned with the fields parsed from the version.
+func (s *store) Versions(app string) ([]avro.ApplicationVersionAvailable, error) {
+	var result []avro.ApplicationVersionAvailable
+	err := s.db.View(func(tx *bolt.Tx) error {
+		versions := tx.Bucket(versionsBucketName)
+		if versions == nil {
+			return nil
+		}
+		bucket := versions.Bucket([]byte(app))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(key, value []byte) error {
+			result = append(result, deserializeVersion(app, string(key), value))
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "view failed")
+	}
+	return result, nil
+}
+
+// deserializeVersion returns the stored record or the version parsed from the key if the record can not be read.
+func deserializeVersion(app string, key string, value []byte) avro.ApplicationVersionAvailable {
+	version, err := avro.DeserializeApplicationVersionAvailable(bytes.NewReader(value))
+	if err != nil {
+		glog.V(4).Infof("deserialize version %s of %s failed => parse version: %v", key, app, err)
+		return NewApplicationVersionAvailable(app, key)
+	}
+	return *version
+}
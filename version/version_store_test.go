@@ -0,0 +1,114 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/bborbe/kafka-k8s-version-collector/avro"
+	"github.com/bborbe/kafka-k8s-version-collector/version"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	bolt "go.etcd.io/bbolt"
+)
+
+var _ = Describe("Version Store", func() {
+	var store version.Store
+	var db *bolt.DB
+	var dir string
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "store")
+		Expect(err).NotTo(HaveOccurred())
+		db, err = bolt.Open(path.Join(dir, "state.db"), 0600, nil)
+		Expect(err).NotTo(HaveOccurred())
+		store = version.NewStore(db)
+	})
+	AfterEach(func() {
+		db.Close()
+		os.RemoveAll(dir)
+	})
+	It("contains no version if empty", func() {
+		contains, err := store.Contains("Kubernetes", "v1.13.4")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(contains).To(BeFalse())
+	})
+	It("contains added version", func() {
+		err := store.Add(avro.ApplicationVersionAvailable{App: "Kubernetes", Version: "v1.13.4"})
+		Expect(err).NotTo(HaveOccurred())
+		contains, err := store.Contains("Kubernetes", "v1.13.4")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(contains).To(BeTrue())
+	})
+	It("separates versions by app", func() {
+		err := store.Add(avro.ApplicationVersionAvailable{App: "Kubernetes", Version: "v1.13.4"})
+		Expect(err).NotTo(HaveOccurred())
+		contains, err := store.Contains("Grafana", "v1.13.4")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(contains).To(BeFalse())
+	})
+	It("returns no apps if empty", func() {
+		apps, err := store.Apps()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(apps).To(BeEmpty())
+	})
+	It("returns apps of added versions", func() {
+		Expect(store.Add(version.NewApplicationVersionAvailable("Kubernetes", "v1.13.4"))).To(BeNil())
+		Expect(store.Add(version.NewApplicationVersionAvailable("Grafana", "6.0.0"))).To(BeNil())
+		apps, err := store.Apps()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(apps).To(Equal([]string{"Grafana", "Kubernetes"}))
+	})
+	It("returns added versions of app", func() {
+		Expect(store.Add(version.NewApplicationVersionAvailable("Kubernetes", "v1.13.4"))).To(BeNil())
+		Expect(store.Add(version.NewApplicationVersionAvailable("Grafana", "6.0.0"))).To(BeNil())
+		versions, err := store.Versions("Kubernetes")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(versions).To(Equal([]avro.ApplicationVersionAvailable{
+			version.NewApplicationVersionAvailable("Kubernetes", "v1.13.4"),
+		}))
+	})
+	It("returns added version", func() {
+		added := version.NewApplicationVersionAvailable("Kubernetes", "v1.13.4")
+		added.Channel = "stable"
+		added.Platforms = []string{"linux/amd64", "linux/arm/v7"}
+		Expect(store.Add(added)).To(BeNil())
+		result, ok, err := store.Get("Kubernetes", "v1.13.4")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(result).To(Equal(added))
+	})
+	It("returns false for unknown version", func() {
+		_, ok, err := store.Get("Kubernetes", "v1.13.4")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse())
+	})
+	It("returns no versions of unknown app", func() {
+		versions, err := store.Versions("Kubernetes")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(versions).To(BeEmpty())
+	})
+	It("parses versions stored with an older schema", func() {
+		err := db.Update(func(tx *bolt.Tx) error {
+			versions, err := tx.CreateBucketIfNotExists([]byte("versions"))
+			if err != nil {
+				return err
+			}
+			bucket, err := versions.CreateBucketIfNotExists([]byte("Kubernetes"))
+			if err != nil {
+				return err
+			}
+			return bucket.Put([]byte("v1.13.4"), []byte{0x14})
+		})
+		Expect(err).NotTo(HaveOccurred())
+		versions, err := store.Versions("Kubernetes")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(versions).To(HaveLen(1))
+		Expect(versions[0].Version).To(Equal("v1.13.4"))
+		Expect(versions[0].Major).To(Equal(int32(1)))
+	})
+})
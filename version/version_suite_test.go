@@ -22,6 +22,18 @@ func (g GinkgoTestReporter) Errorf(format string, args ...interface{}) {
 	Fail(fmt.Sprintf(format, args...))
 }
 
+func (g GinkgoTestReporter) Error(args ...interface{}) {
+	Fail(fmt.Sprint(args...))
+}
+
+func (g GinkgoTestReporter) Fatal(args ...interface{}) {
+	Fail(fmt.Sprint(args...))
+}
+
+func (g GinkgoTestReporter) Fatalf(format string, args ...interface{}) {
+	Fail(fmt.Sprintf(format, args...))
+}
+
 type ErrorRoundTripper struct{}
 
 func (e *ErrorRoundTripper) RoundTrip(*http.Request) (*http.Response, error) {
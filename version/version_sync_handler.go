@@ -0,0 +1,47 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+// NewSyncHandler returns a http.Handler that triggers a sync on POST and responds with the finished run as json.
+// The query parameter source limits the sync to the given source.
+// The sync runs with the given context, a client disconnecting does not cancel it.
+func NewSyncHandler(ctx context.Context, runner Runner) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			resp.Header().Set("Allow", http.MethodPost)
+			http.Error(resp, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var sources []string
+		if source := req.URL.Query().Get("source"); source != "" {
+			sources = append(sources, source)
+		}
+		run, err := runner.Run(ctx, SyncTriggerManual, sources...)
+		if err != nil {
+			if errors.Cause(err) == ErrUnknownSource {
+				http.Error(resp, err.Error(), http.StatusNotFound)
+				return
+			}
+			http.Error(resp, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		resp.Header().Set("Content-Type", "application/json")
+		if run.Status != SyncStatusSuccess {
+			resp.WriteHeader(http.StatusInternalServerError)
+		}
+		if err := json.NewEncoder(resp).Encode(run); err != nil {
+			glog.Warningf("encode run %s failed: %v", run.ID, err)
+		}
+	})
+}
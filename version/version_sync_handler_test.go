@@ -0,0 +1,72 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/bborbe/kafka-k8s-version-collector/mocks"
+	"github.com/bborbe/kafka-k8s-version-collector/version"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+)
+
+var _ = Describe("Sync Handler", func() {
+	var handler http.Handler
+	var runner *mocks.Runner
+	var recorder *httptest.ResponseRecorder
+	BeforeEach(func() {
+		runner = &mocks.Runner{}
+		runner.RunReturns(version.SyncRun{ID: "1234", Status: version.SyncStatusSuccess}, nil)
+		handler = version.NewSyncHandler(context.Background(), runner)
+		recorder = httptest.NewRecorder()
+	})
+	It("returns method not allowed for get", func() {
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/sync", nil))
+		Expect(recorder.Code).To(Equal(http.StatusMethodNotAllowed))
+		Expect(runner.RunCallCount()).To(Equal(0))
+	})
+	It("runs all sources", func() {
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/sync", nil))
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		_, trigger, sources := runner.RunArgsForCall(0)
+		Expect(trigger).To(Equal(version.SyncTriggerManual))
+		Expect(sources).To(BeEmpty())
+		var run version.SyncRun
+		Expect(json.NewDecoder(recorder.Body).Decode(&run)).To(BeNil())
+		Expect(run.ID).To(Equal("1234"))
+		Expect(run.Status).To(Equal(version.SyncStatusSuccess))
+	})
+	It("runs sync not cancelled by the request", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/sync", nil).WithContext(ctx))
+		runCtx, _, _ := runner.RunArgsForCall(0)
+		Expect(runCtx.Err()).To(BeNil())
+	})
+	It("runs given source", func() {
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/sync?source=grafana", nil))
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		_, _, sources := runner.RunArgsForCall(0)
+		Expect(sources).To(Equal([]string{"grafana"}))
+	})
+	It("returns not found for unknown source", func() {
+		runner.RunReturns(version.SyncRun{}, errors.Wrap(version.ErrUnknownSource, "source banana"))
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/sync?source=banana", nil))
+		Expect(recorder.Code).To(Equal(http.StatusNotFound))
+	})
+	It("returns internal server error for failed run", func() {
+		runner.RunReturns(version.SyncRun{ID: "1234", Status: version.SyncStatusFailure, Error: "banana"}, nil)
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/sync", nil))
+		Expect(recorder.Code).To(Equal(http.StatusInternalServerError))
+		var run version.SyncRun
+		Expect(json.NewDecoder(recorder.Body).Decode(&run)).To(BeNil())
+		Expect(run.Error).To(Equal("banana"))
+	})
+})
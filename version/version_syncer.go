@@ -7,10 +7,15 @@ package version
 import (
 	"context"
 	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/bborbe/kafka-k8s-version-collector/avro"
 	"github.com/bborbe/run"
 	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 //go:generate counterfeiter -o ../mocks/syncer.go --fake-name Syncer . Syncer
@@ -18,33 +23,187 @@ type Syncer interface {
 	Sync(ctx context.Context) error
 }
 
+// Source combines the fetcher and filter of a configured source.
+type Source struct {
+	Name    string
+	Fetcher Fetcher
+	Filter  Filter
+}
+
+// NewSyncer returns a Syncer that sends all fetched versions passing the filter of each source.
+// Sources are synced in isolation, a failed source does not stop the others.
 func NewSyncer(
-	fetcher Fetcher,
 	sender Sender,
+	sources ...Source,
 ) Syncer {
+	return newSyncer(sender, newFlight(), sources...)
+}
+
+func newSyncer(
+	sender Sender,
+	flight *flight,
+	sources ...Source,
+) *syncer {
 	return &syncer{
-		fetcher: fetcher,
 		sender:  sender,
+		flight:  flight,
+		sources: sources,
 	}
 }
 
 type syncer struct {
-	fetcher Fetcher
 	sender  Sender
+	flight  *flight
+	sources []Source
+}
+
+// sinkError marks errors of the sender.
+type sinkError struct {
+	err error
+}
+
+func (s sinkError) Error() string {
+	return s.err.Error()
+}
+
+// SourceResult describes the sync of a single source.
+// Trigger is the trigger of the run executing the sync, a run joining the sync of another run shares its result.
+type SourceResult struct {
+	Source    string    `json:"source"`
+	Trigger   string    `json:"trigger,omitempty"`
+	Started   time.Time `json:"started"`
+	Finished  time.Time `json:"finished"`
+	Fetched   int       `json:"fetched"`
+	Published int       `json:"published"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// sourceSync contains the result and error of a source sync.
+type sourceSync struct {
+	result SourceResult
+	err    error
 }
 
+// Sync returns an error if all sources failed or the sink failed.
 func (s *syncer) Sync(ctx context.Context) error {
+	_, err := s.sync(ctx, "")
+	return err
+}
+
+// sync returns the result of each source and an error if all sources failed or the sink failed.
+// The trigger is recorded in the results of the sources synced by this call.
+func (s *syncer) sync(ctx context.Context, trigger string) ([]SourceResult, error) {
 	glog.V(1).Infof("sync started")
 	defer glog.V(1).Infof("sync finished")
-	versions := make(chan avro.ApplicationVersionAvailable, runtime.NumCPU())
+	syncs := make([]sourceSync, len(s.sources))
+	var wg sync.WaitGroup
+	for i, source := range s.sources {
+		wg.Add(1)
+		go func(i int, source Source) {
+			defer wg.Done()
+			syncs[i] = s.flight.Do(source.Name, func() sourceSync {
+				return s.syncSourceAndRecord(ctx, source, trigger)
+			})
+		}(i, source)
+	}
+	wg.Wait()
+	var results []SourceResult
+	var failed run.ErrorList
+	var sinkFailed bool
+	for i, synced := range syncs {
+		results = append(results, synced.result)
+		if synced.err == nil {
+			continue
+		}
+		if _, ok := synced.err.(sinkError); ok {
+			sinkFailed = true
+		}
+		failed = append(failed, errors.Wrapf(synced.err, "sync source %s failed", s.sources[i].Name))
+	}
+	if len(failed) > 0 && (sinkFailed || len(failed) == len(s.sources)) {
+		return results, failed
+	}
+	return results, nil
+}
+
+// syncSourceAndRecord syncs the source and records the result in logs and metrics.
+// Fetchers and senders return without error once the context is done, a cancelled sync is recorded as failed.
+func (s *syncer) syncSourceAndRecord(ctx context.Context, source Source, trigger string) sourceSync {
+	result := SourceResult{
+		Source:  source.Name,
+		Trigger: trigger,
+		Started: time.Now(),
+	}
+	var fetched, published int64
+	err := s.syncSource(ctx, source, &fetched, &published)
+	if err == nil && ctx.Err() != nil {
+		err = errors.Wrap(ctx.Err(), "sync cancelled")
+	}
+	result.Finished = time.Now()
+	result.Fetched = int(atomic.LoadInt64(&fetched))
+	result.Published = int(atomic.LoadInt64(&published))
+	sourceSyncsCounter.WithLabelValues(source.Name, resultLabel(err)).Inc()
+	if err != nil {
+		glog.Warningf("sync source %s failed: %v", source.Name, err)
+		result.Error = err.Error()
+		return sourceSync{result: result, err: err}
+	}
+	lastSuccessfulSyncGauge.WithLabelValues(source.Name).Set(float64(result.Finished.Unix()))
+	glog.V(2).Infof("sync source %s completed, %d fetched, %d published", source.Name, result.Fetched, result.Published)
+	return sourceSync{result: result}
+}
+
+func (s *syncer) syncSource(ctx context.Context, source Source, fetchedCounter *int64, publishedCounter *int64) error {
+	fetched := make(chan avro.ApplicationVersionAvailable, runtime.NumCPU())
+	counted := make(chan avro.ApplicationVersionAvailable, runtime.NumCPU())
+	filtered := make(chan avro.ApplicationVersionAvailable, runtime.NumCPU())
 	return run.CancelOnFirstError(
 		ctx,
 		func(ctx context.Context) error {
-			defer close(versions)
-			return s.fetcher.Fetch(ctx, versions)
+			defer close(fetched)
+			timer := prometheus.NewTimer(fetchDurationHistogram.WithLabelValues(source.Name))
+			defer timer.ObserveDuration()
+			return source.Fetcher.Fetch(ctx, fetched)
+		},
+		func(ctx context.Context) error {
+			defer close(counted)
+			return count(ctx, source.Name, fetched, counted, fetchedCounter)
+		},
+		func(ctx context.Context) error {
+			defer close(filtered)
+			return source.Filter.Filter(ctx, counted, filtered)
 		},
 		func(ctx context.Context) error {
-			return s.sender.Send(ctx, versions)
+			published, err := s.sender.Send(ctx, filtered)
+			atomic.StoreInt64(publishedCounter, int64(published))
+			if err != nil {
+				return sinkError{err: err}
+			}
+			return nil
 		},
 	)
 }
+
+// count passes all versions from in to out and counts them, in the metrics per source and app.
+func count(ctx context.Context, source string, in <-chan avro.ApplicationVersionAvailable, out chan<- avro.ApplicationVersionAvailable, counter *int64) error {
+	for {
+		select {
+		case <-ctx.Done():
+			glog.V(3).Infof("context done => return")
+			return nil
+		case version, ok := <-in:
+			if !ok {
+				glog.V(3).Infof("channel closed => return")
+				return nil
+			}
+			atomic.AddInt64(counter, 1)
+			tagsFetchedCounter.WithLabelValues(source, version.App).Inc()
+			select {
+			case <-ctx.Done():
+				glog.V(3).Infof("context done => return")
+				return nil
+			case out <- version:
+			}
+		}
+	}
+}
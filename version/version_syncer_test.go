@@ -6,6 +6,8 @@ package version_test
 
 import (
 	"context"
+	"errors"
+	"sync"
 
 	"github.com/bborbe/kafka-k8s-version-collector/avro"
 	"github.com/bborbe/kafka-k8s-version-collector/mocks"
@@ -14,31 +16,36 @@ import (
 	. "github.com/onsi/gomega"
 )
 
-var _ = Describe("Version Sender", func() {
+var _ = Describe("Version Syncer", func() {
 	var syncer version.Syncer
 	var sender *mocks.Sender
 	var fetcher *mocks.Fetcher
 	var sendCounter int
+	var mux sync.Mutex
 	BeforeEach(func() {
 		sendCounter = 0
 		sender = &mocks.Sender{}
-		sender.SendStub = func(ctx context.Context, availables <-chan avro.ApplicationVersionAvailable) error {
+		sender.SendStub = func(ctx context.Context, availables <-chan avro.ApplicationVersionAvailable) (int, error) {
 			for {
 				select {
 				case <-ctx.Done():
-					return nil
+					return 0, nil
 				case _, ok := <-availables:
 					if !ok {
-						return nil
+						return 0, nil
 					}
+					mux.Lock()
 					sendCounter++
+					mux.Unlock()
 				}
 			}
 		}
 		fetcher = &mocks.Fetcher{}
+		filter, err := version.NewFilter(version.FilterConfig{})
+		Expect(err).NotTo(HaveOccurred())
 		syncer = version.NewSyncer(
-			fetcher,
 			sender,
+			version.Source{Name: "test", Fetcher: fetcher, Filter: filter},
 		)
 	})
 	It("returns without error", func() {
@@ -74,4 +81,85 @@ var _ = Describe("Version Sender", func() {
 		Expect(err).NotTo(HaveOccurred())
 		Expect(sendCounter).To(Equal(counter))
 	})
+	It("sends only filtered versions", func() {
+		fetcher.FetchStub = func(ctx context.Context, availables chan<- avro.ApplicationVersionAvailable) error {
+			for _, v := range []string{"latest", "v1.13.4", "v1.14.0-beta.0"} {
+				select {
+				case <-ctx.Done():
+					return nil
+				case availables <- version.NewApplicationVersionAvailable("Kubernetes", v):
+				}
+			}
+			return nil
+		}
+		filter, err := version.NewFilter(version.FilterConfig{StableOnly: true})
+		Expect(err).NotTo(HaveOccurred())
+		syncer = version.NewSyncer(
+			sender,
+			version.Source{Name: "test", Fetcher: fetcher, Filter: filter},
+		)
+		err = syncer.Sync(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sendCounter).To(Equal(1))
+	})
+	It("returns error if filter fails", func() {
+		filter := &mocks.Filter{}
+		filter.FilterReturns(errors.New("banana"))
+		syncer = version.NewSyncer(
+			sender,
+			version.Source{Name: "test", Fetcher: fetcher, Filter: filter},
+		)
+		err := syncer.Sync(context.Background())
+		Expect(err).To(HaveOccurred())
+	})
+	Context("with multiple sources", func() {
+		var failing *mocks.Fetcher
+		BeforeEach(func() {
+			fetcher.FetchStub = func(ctx context.Context, availables chan<- avro.ApplicationVersionAvailable) error {
+				select {
+				case <-ctx.Done():
+					return nil
+				case availables <- version.NewApplicationVersionAvailable("Grafana", "6.0.0"):
+					return nil
+				}
+			}
+			failing = &mocks.Fetcher{}
+			failing.FetchReturns(errors.New("banana"))
+			filter, err := version.NewFilter(version.FilterConfig{})
+			Expect(err).NotTo(HaveOccurred())
+			syncer = version.NewSyncer(
+				sender,
+				version.Source{Name: "failing", Fetcher: failing, Filter: filter},
+				version.Source{Name: "grafana", Fetcher: fetcher, Filter: filter},
+			)
+		})
+		It("returns without error if only some sources fail", func() {
+			err := syncer.Sync(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+		})
+		It("sends versions of successful sources", func() {
+			err := syncer.Sync(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sendCounter).To(Equal(1))
+			Expect(failing.FetchCallCount()).To(Equal(1))
+		})
+		It("returns error if all sources fail", func() {
+			fetcher.FetchReturns(errors.New("banana"))
+			err := syncer.Sync(context.Background())
+			Expect(err).To(HaveOccurred())
+		})
+		It("returns error if sink fails while other sources succeed", func() {
+			failing.FetchReturns(nil)
+			sender.SendStub = func(ctx context.Context, availables <-chan avro.ApplicationVersionAvailable) (int, error) {
+				for range availables {
+					return 0, errors.New("kafka down")
+				}
+				return 0, nil
+			}
+			err := syncer.Sync(context.Background())
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("sync source grafana failed: kafka down"))
+			Expect(err.Error()).NotTo(ContainSubstring("sync source failing"))
+		})
+	})
 })
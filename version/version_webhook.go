@@ -0,0 +1,529 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/bborbe/kafka-k8s-version-collector/avro"
+	"github.com/bborbe/run"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+// Names of the webhook receivers, served at /webhooks/{name}.
+const (
+	WebhookDistribution = "distribution"
+	WebhookDockerHub    = "dockerhub"
+	WebhookHarbor       = "harbor"
+	WebhookGitHub       = "github"
+)
+
+var webhookNames = []string{
+	WebhookDistribution,
+	WebhookDockerHub,
+	WebhookHarbor,
+	WebhookGitHub,
+}
+
+// maxWebhookBodySize limits the size of accepted notifications.
+const maxWebhookBodySize = 1 << 20
+
+var dockerHubHosts = map[string]bool{
+	"docker.io":               true,
+	"index.docker.io":         true,
+	"registry-1.docker.io":    true,
+	"registry.hub.docker.com": true,
+}
+
+// webhookPush is a tag pushed to a repository.
+type webhookPush struct {
+	// Host of the registry, empty if unknown.
+	Host       string
+	Repository string
+	Tag        string
+	// Release is set for pushes of GitHub releases.
+	Release *GitHubRelease
+}
+
+// record returns the version of the push for the given app.
+func (w webhookPush) record(app string) avro.ApplicationVersionAvailable {
+	if w.Release != nil {
+		return NewGitHubReleaseVersion(app, *w.Release)
+	}
+	return NewApplicationVersionAvailable(app, w.Tag)
+}
+
+// webhookTarget is an app pushed tags of a repository are published for.
+type webhookTarget struct {
+	App    string
+	Filter Filter
+}
+
+// webhook authenticates a notification and returns the pushed tags.
+type webhook struct {
+	verify func(req *http.Request, body []byte, secret string) bool
+	parse  func(req *http.Request, body []byte) ([]webhookPush, error)
+	// sourceTypes are the types of sources pushed repositories are matched against.
+	sourceTypes []string
+}
+
+var registrySourceTypes = []string{SourceTypeRegistry, SourceTypeDockerHub}
+
+var webhooks = map[string]webhook{
+	WebhookDistribution: {
+		verify:      verifyBearerSecret,
+		parse:       parseDistributionNotification,
+		sourceTypes: registrySourceTypes,
+	},
+	WebhookDockerHub: {
+		verify:      verifyQuerySecret,
+		parse:       parseDockerHubWebhook,
+		sourceTypes: registrySourceTypes,
+	},
+	WebhookHarbor: {
+		verify:      verifyHarborSecret,
+		parse:       parseHarborWebhook,
+		sourceTypes: registrySourceTypes,
+	},
+	WebhookGitHub: {
+		verify:      verifyGitHubSignature,
+		parse:       parseGitHubWebhook,
+		sourceTypes: []string{SourceTypeGitHub, SourceTypeGitHubTags},
+	},
+}
+
+// NewWebhookHandlers returns the http.Handler of each configured webhook by name.
+// Pushed tags of repositories matching a source are published with the app and filter of the source,
+// except keepNewest that needs all tags of the app and is only applied by the sync.
+// Versions are published with the given context, a client disconnecting does not cancel them.
+func NewWebhookHandlers(ctx context.Context, config *Config, sender Sender) (map[string]http.Handler, error) {
+	result := make(map[string]http.Handler)
+	for name, webhookConfig := range config.Webhooks.enabled() {
+		targets, err := newWebhookTargets(config, webhookConfig, webhooks[name].sourceTypes)
+		if err != nil {
+			return nil, errors.Wrapf(err, "create targets of webhook %s failed", name)
+		}
+		result[name] = &webhookHandler{
+			ctx:     ctx,
+			name:    name,
+			webhook: webhooks[name],
+			secret:  webhookConfig.SecretValue(),
+			targets: targets,
+			sender:  sender,
+		}
+	}
+	return result, nil
+}
+
+// webhookTargets returns the targets of a pushed repository.
+type webhookTargets func(push webhookPush) []webhookTarget
+
+func newWebhookTargets(config *Config, webhookConfig WebhookConfig, sourceTypes []string) (webhookTargets, error) {
+	type source struct {
+		host       string
+		repository string
+		target     webhookTarget
+	}
+	var sources []source
+	for _, sourceConfig := range config.Sources {
+		if !containsString(sourceTypes, sourceConfig.Type) {
+			continue
+		}
+		host, repository, err := webhookSourceRepository(sourceConfig)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parse repository of source %s failed", sourceConfig.Name)
+		}
+		filter, err := NewFilter(sourceConfig.Filter.stateless())
+		if err != nil {
+			return nil, errors.Wrapf(err, "create filter for source %s failed", sourceConfig.Name)
+		}
+		sources = append(sources, source{
+			host:       host,
+			repository: repository,
+			target:     webhookTarget{App: sourceConfig.App, Filter: filter},
+		})
+	}
+	appsFilter, err := NewFilter(webhookConfig.Filter.stateless())
+	if err != nil {
+		return nil, errors.Wrap(err, "create filter for apps failed")
+	}
+	return func(push webhookPush) []webhookTarget {
+		var result []webhookTarget
+		for _, source := range sources {
+			if source.repository == push.Repository && sameRegistryHost(source.host, push.Host) {
+				result = append(result, source.target)
+			}
+		}
+		if len(result) == 0 {
+			if app, ok := webhookConfig.Apps[push.Repository]; ok {
+				result = append(result, webhookTarget{App: app, Filter: appsFilter})
+			}
+		}
+		return result
+	}, nil
+}
+
+// webhookSourceRepository returns the host and repository pushes to the source are notified with.
+func webhookSourceRepository(source SourceConfig) (string, string, error) {
+	switch source.Type {
+	case SourceTypeDockerHub:
+		registry := source.Registry
+		if registry == "" {
+			registry = DockerHubRegistry
+		}
+		u, err := url.Parse(registry)
+		if err != nil {
+			return "", "", err
+		}
+		return u.Host, DockerHubRepository(source.Repository), nil
+	case SourceTypeGitHub, SourceTypeGitHubTags:
+		api := source.URL
+		if api == "" {
+			api = GitHubAPI
+		}
+		u, err := url.Parse(api)
+		if err != nil {
+			return "", "", err
+		}
+		return strings.TrimPrefix(u.Host, "api."), source.Repository, nil
+	default:
+		u, err := url.Parse(source.Registry)
+		if err != nil {
+			return "", "", err
+		}
+		return u.Host, source.Repository, nil
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// sameRegistryHost returns true if the hosts are equal, both are Docker Hub or the pushed host is unknown.
+func sameRegistryHost(sourceHost string, pushHost string) bool {
+	if pushHost == "" || sourceHost == pushHost {
+		return true
+	}
+	return dockerHubHosts[sourceHost] && dockerHubHosts[pushHost]
+}
+
+type webhookHandler struct {
+	ctx     context.Context
+	name    string
+	webhook webhook
+	secret  string
+	targets webhookTargets
+	sender  Sender
+}
+
+func (w *webhookHandler) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		resp.Header().Set("Allow", http.MethodPost)
+		http.Error(resp, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(resp, req.Body, maxWebhookBodySize))
+	if err != nil {
+		w.count("invalid")
+		http.Error(resp, "read body failed", http.StatusBadRequest)
+		return
+	}
+	if !w.webhook.verify(req, body, w.secret) {
+		glog.Warningf("webhook %s from %s rejected: invalid secret", w.name, req.RemoteAddr)
+		w.count("unauthorized")
+		http.Error(resp, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	pushes, err := w.webhook.parse(req, body)
+	if err != nil {
+		glog.Warningf("webhook %s invalid: %v", w.name, err)
+		w.count("invalid")
+		http.Error(resp, "invalid notification", http.StatusBadRequest)
+		return
+	}
+	var published int
+	var failed bool
+	var results []webhookResult
+	for _, push := range pushes {
+		targets := w.targets(push)
+		if len(targets) == 0 {
+			glog.V(2).Infof("webhook %s: no source for repository %s => ignore", w.name, push.Repository)
+			w.count("ignored")
+			continue
+		}
+		for _, target := range targets {
+			result := webhookResult{
+				Repository: push.Repository,
+				Tag:        push.Tag,
+				App:        target.App,
+			}
+			count, err := w.publish(w.ctx, target, push.record(target.App))
+			if err != nil {
+				glog.Warningf("webhook %s: publish %s of %s failed: %v", w.name, push.Tag, target.App, err)
+				w.count("failure")
+				failed = true
+				result.Error = err.Error()
+			} else {
+				w.count("published")
+				result.Published = count
+				published += count
+			}
+			results = append(results, result)
+		}
+	}
+	glog.V(1).Infof("webhook %s: %d versions published", w.name, published)
+	if failed && published == 0 {
+		resp.Header().Set("Content-Type", "application/json")
+		resp.WriteHeader(http.StatusInternalServerError)
+	}
+	writeJSON(resp, webhookResponse{
+		Published: published,
+		Results:   results,
+	})
+}
+
+// webhookResponse reports the published versions of each pushed tag and target app.
+type webhookResponse struct {
+	Published int             `json:"published"`
+	Results   []webhookResult `json:"results,omitempty"`
+}
+
+type webhookResult struct {
+	Repository string `json:"repository"`
+	Tag        string `json:"tag"`
+	App        string `json:"app"`
+	Published  int    `json:"published"`
+	Error      string `json:"error,omitempty"`
+}
+
+func (w *webhookHandler) count(result string) {
+	webhookEventsCounter.WithLabelValues(w.name, result).Inc()
+}
+
+// publish sends the version through the filter of the target and returns the number of published versions.
+func (w *webhookHandler) publish(ctx context.Context, target webhookTarget, version avro.ApplicationVersionAvailable) (int, error) {
+	versions := make(chan avro.ApplicationVersionAvailable, 1)
+	versions <- version
+	close(versions)
+	published, err := w.filterAndSend(ctx, target.Filter, versions)
+	if err == nil && ctx.Err() != nil {
+		return published, errors.Wrap(ctx.Err(), "publish cancelled")
+	}
+	return published, err
+}
+
+func (w *webhookHandler) filterAndSend(ctx context.Context, filter Filter, versions <-chan avro.ApplicationVersionAvailable) (int, error) {
+	var published int
+	filtered := make(chan avro.ApplicationVersionAvailable, 1)
+	err := run.CancelOnFirstError(
+		ctx,
+		func(ctx context.Context) error {
+			defer close(filtered)
+			return filter.Filter(ctx, versions, filtered)
+		},
+		func(ctx context.Context) error {
+			var err error
+			published, err = w.sender.Send(ctx, filtered)
+			return err
+		},
+	)
+	return published, err
+}
+
+func equalSecret(value string, secret string) bool {
+	return secret != "" && subtle.ConstantTimeCompare([]byte(value), []byte(secret)) == 1
+}
+
+// verifyBearerSecret expects the secret as bearer token, configured as header of the notification endpoint.
+func verifyBearerSecret(req *http.Request, body []byte, secret string) bool {
+	return secret != "" && equalSecret(req.Header.Get("Authorization"), "Bearer "+secret)
+}
+
+// verifyQuerySecret expects the secret as query parameter, Docker Hub does not sign webhooks.
+func verifyQuerySecret(req *http.Request, body []byte, secret string) bool {
+	return equalSecret(req.URL.Query().Get("secret"), secret)
+}
+
+// verifyHarborSecret expects the secret as auth header configured in the Harbor webhook policy.
+func verifyHarborSecret(req *http.Request, body []byte, secret string) bool {
+	value := req.Header.Get("Authorization")
+	return equalSecret(value, secret) || (secret != "" && equalSecret(value, "Bearer "+secret))
+}
+
+// verifyGitHubSignature checks the HMAC signature of the body in X-Hub-Signature-256 or X-Hub-Signature.
+func verifyGitHubSignature(req *http.Request, body []byte, secret string) bool {
+	if secret == "" {
+		return false
+	}
+	if signature := req.Header.Get("X-Hub-Signature-256"); signature != "" {
+		return equalSecret(signature, "sha256="+hmacHex(sha256.New, secret, body))
+	}
+	if signature := req.Header.Get("X-Hub-Signature"); signature != "" {
+		return equalSecret(signature, "sha1="+hmacHex(sha1.New, secret, body))
+	}
+	return false
+}
+
+func hmacHex(hashFunc func() hash.Hash, secret string, body []byte) string {
+	mac := hmac.New(hashFunc, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// parseDistributionNotification returns the tags of push events in a notification envelope.
+// (see https://docs.docker.com/registry/notifications/)
+func parseDistributionNotification(req *http.Request, body []byte) ([]webhookPush, error) {
+	var envelope struct {
+		Events []struct {
+			Action string `json:"action"`
+			Target struct {
+				Repository string `json:"repository"`
+				Tag        string `json:"tag"`
+			} `json:"target"`
+			Request struct {
+				Host string `json:"host"`
+			} `json:"request"`
+		} `json:"events"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, errors.Wrap(err, "unmarshal envelope failed")
+	}
+	var result []webhookPush
+	for _, event := range envelope.Events {
+		if event.Action != "push" || event.Target.Tag == "" {
+			continue
+		}
+		result = append(result, webhookPush{
+			Host:       event.Request.Host,
+			Repository: event.Target.Repository,
+			Tag:        event.Target.Tag,
+		})
+	}
+	return result, nil
+}
+
+// parseDockerHubWebhook returns the pushed tag of a Docker Hub webhook.
+// Official images are named without namespace and returned as library/name.
+func parseDockerHubWebhook(req *http.Request, body []byte) ([]webhookPush, error) {
+	var data struct {
+		PushData struct {
+			Tag string `json:"tag"`
+		} `json:"push_data"`
+		Repository struct {
+			RepoName string `json:"repo_name"`
+		} `json:"repository"`
+	}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, errors.Wrap(err, "unmarshal webhook failed")
+	}
+	if data.Repository.RepoName == "" || data.PushData.Tag == "" {
+		return nil, errors.New("repository or tag missing")
+	}
+	return []webhookPush{
+		{
+			Host:       "docker.io",
+			Repository: DockerHubRepository(data.Repository.RepoName),
+			Tag:        data.PushData.Tag,
+		},
+	}, nil
+}
+
+// parseHarborWebhook returns the pushed tags of a Harbor push event.
+func parseHarborWebhook(req *http.Request, body []byte) ([]webhookPush, error) {
+	var data struct {
+		Type      string `json:"type"`
+		EventData struct {
+			Resources []struct {
+				Tag         string `json:"tag"`
+				ResourceURL string `json:"resource_url"`
+			} `json:"resources"`
+			Repository struct {
+				RepoFullName string `json:"repo_full_name"`
+			} `json:"repository"`
+		} `json:"event_data"`
+	}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, errors.Wrap(err, "unmarshal webhook failed")
+	}
+	if data.Type != "PUSH_ARTIFACT" && data.Type != "pushImage" {
+		return nil, nil
+	}
+	var result []webhookPush
+	for _, resource := range data.EventData.Resources {
+		if resource.Tag == "" {
+			continue
+		}
+		var host string
+		if pos := strings.Index(resource.ResourceURL, "/"); pos > 0 {
+			host = resource.ResourceURL[:pos]
+		}
+		result = append(result, webhookPush{
+			Host:       host,
+			Repository: data.EventData.Repository.RepoFullName,
+			Tag:        resource.Tag,
+		})
+	}
+	return result, nil
+}
+
+// parseGitHubWebhook returns the tag of a published release. Other events and drafts are ignored.
+func parseGitHubWebhook(req *http.Request, body []byte) ([]webhookPush, error) {
+	if event := req.Header.Get("X-GitHub-Event"); event != "release" {
+		glog.V(2).Infof("ignore github event %s", event)
+		return nil, nil
+	}
+	var data struct {
+		Action     string        `json:"action"`
+		Release    GitHubRelease `json:"release"`
+		Repository struct {
+			FullName string `json:"full_name"`
+			HTMLURL  string `json:"html_url"`
+		} `json:"repository"`
+	}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, errors.Wrap(err, "unmarshal webhook failed")
+	}
+	if data.Action != "published" && data.Action != "released" || data.Release.Draft {
+		return nil, nil
+	}
+	if data.Repository.FullName == "" || data.Release.TagName == "" {
+		return nil, errors.New("repository or tag missing")
+	}
+	var host string
+	if u, err := url.Parse(data.Repository.HTMLURL); err == nil {
+		host = u.Host
+	}
+	return []webhookPush{
+		{
+			Host:       host,
+			Repository: data.Repository.FullName,
+			Tag:        data.Release.TagName,
+			Release:    &data.Release,
+		},
+	}, nil
+}
+
+// WebhookPath returns the path the webhook with the given name is served at.
+func WebhookPath(name string) string {
+	return fmt.Sprintf("/webhooks/%s", name)
+}
@@ -0,0 +1,312 @@
+// Copyright (c) 2019 Benjamin Borbe All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/bborbe/kafka-k8s-version-collector/avro"
+	"github.com/bborbe/kafka-k8s-version-collector/mocks"
+	"github.com/bborbe/kafka-k8s-version-collector/version"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Webhook", func() {
+	var config *version.Config
+	var sender *mocks.Sender
+	var handlers map[string]http.Handler
+	var recorder *httptest.ResponseRecorder
+	var published []avro.ApplicationVersionAvailable
+	var mux sync.Mutex
+	BeforeEach(func() {
+		published = nil
+		sender = &mocks.Sender{}
+		sender.SendStub = func(ctx context.Context, versions <-chan avro.ApplicationVersionAvailable) (int, error) {
+			var counter int
+			for v := range versions {
+				mux.Lock()
+				published = append(published, v)
+				mux.Unlock()
+				counter++
+			}
+			return counter, nil
+		}
+		config = &version.Config{
+			Sources: []version.SourceConfig{
+				{
+					Name:       "grafana",
+					Type:       version.SourceTypeRegistry,
+					Registry:   "https://registry-1.docker.io",
+					Repository: "grafana/grafana",
+					App:        "Grafana",
+					Filter:     version.FilterConfig{Exclude: []string{"^master"}, KeepNewest: 1},
+				},
+				{
+					Name:       "nginx",
+					Type:       version.SourceTypeDockerHub,
+					Repository: "nginx",
+					App:        "Nginx",
+				},
+				{
+					Name:       "collector",
+					Type:       version.SourceTypeRegistry,
+					Registry:   "https://registry.example.com",
+					Repository: "bborbe/collector",
+					App:        "Collector",
+				},
+			},
+			Webhooks: version.WebhooksConfig{
+				Distribution: &version.WebhookConfig{Secret: "secret"},
+				DockerHub:    &version.WebhookConfig{Secret: "secret", Apps: map[string]string{"bborbe/banana": "Banana"}, Filter: version.FilterConfig{Exclude: []string{"^master"}}},
+				Harbor:       &version.WebhookConfig{Secret: "secret"},
+				GitHub:       &version.WebhookConfig{Secret: "secret", Apps: map[string]string{"grafana/grafana": "Grafana"}},
+			},
+		}
+		recorder = httptest.NewRecorder()
+	})
+	JustBeforeEach(func() {
+		var err error
+		handlers, err = version.NewWebhookHandlers(context.Background(), config, sender)
+		Expect(err).NotTo(HaveOccurred())
+	})
+	It("returns handler of each configured webhook", func() {
+		Expect(handlers).To(HaveLen(4))
+		Expect(version.WebhookPath(version.WebhookDockerHub)).To(Equal("/webhooks/dockerhub"))
+	})
+	Context("without webhooks", func() {
+		BeforeEach(func() {
+			config.Webhooks = version.WebhooksConfig{}
+		})
+		It("returns no handler", func() {
+			Expect(handlers).To(BeEmpty())
+		})
+	})
+	Context("dockerhub", func() {
+		var body string
+		BeforeEach(func() {
+			body = `{"push_data":{"tag":"6.0.0"},"repository":{"repo_name":"grafana/grafana"}}`
+		})
+		post := func(url string) {
+			handlers[version.WebhookDockerHub].ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, url, strings.NewReader(body)))
+		}
+		It("publishes pushed tag with app of source", func() {
+			post("/webhooks/dockerhub?secret=secret")
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(recorder.Body.String()).To(MatchJSON(`{"published":1,"results":[{"repository":"grafana/grafana","tag":"6.0.0","app":"Grafana","published":1}]}`))
+			Expect(published).To(HaveLen(1))
+			Expect(published[0].App).To(Equal("Grafana"))
+			Expect(published[0].Version).To(Equal("6.0.0"))
+		})
+		It("returns unauthorized for wrong secret", func() {
+			post("/webhooks/dockerhub?secret=banana")
+			Expect(recorder.Code).To(Equal(http.StatusUnauthorized))
+			Expect(sender.SendCallCount()).To(Equal(0))
+		})
+		It("returns method not allowed for get", func() {
+			handlers[version.WebhookDockerHub].ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/webhooks/dockerhub?secret=secret", nil))
+			Expect(recorder.Code).To(Equal(http.StatusMethodNotAllowed))
+		})
+		It("returns bad request for invalid payload", func() {
+			body = `banana`
+			post("/webhooks/dockerhub?secret=secret")
+			Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+		})
+		It("applies filter of source", func() {
+			body = `{"push_data":{"tag":"master-1234"},"repository":{"repo_name":"grafana/grafana"}}`
+			post("/webhooks/dockerhub?secret=secret")
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(recorder.Body.String()).To(MatchJSON(`{"published":0,"results":[{"repository":"grafana/grafana","tag":"master-1234","app":"Grafana","published":0}]}`))
+			Expect(published).To(BeEmpty())
+		})
+		It("does not apply keepNewest of source to pushed tags", func() {
+			body = `{"push_data":{"tag":"5.4.3"},"repository":{"repo_name":"grafana/grafana"}}`
+			post("/webhooks/dockerhub?secret=secret")
+			Expect(published).To(HaveLen(1))
+			Expect(published[0].Version).To(Equal("5.4.3"))
+		})
+		It("matches official images", func() {
+			body = `{"push_data":{"tag":"1.15.8"},"repository":{"repo_name":"nginx"}}`
+			post("/webhooks/dockerhub?secret=secret")
+			Expect(published).To(HaveLen(1))
+			Expect(published[0].App).To(Equal("Nginx"))
+		})
+		It("uses configured app of repository without source", func() {
+			body = `{"push_data":{"tag":"1.0.0"},"repository":{"repo_name":"bborbe/banana"}}`
+			post("/webhooks/dockerhub?secret=secret")
+			Expect(published).To(HaveLen(1))
+			Expect(published[0].App).To(Equal("Banana"))
+		})
+		It("applies filter of webhook to repository without source", func() {
+			body = `{"push_data":{"tag":"master-1234"},"repository":{"repo_name":"bborbe/banana"}}`
+			post("/webhooks/dockerhub?secret=secret")
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(published).To(BeEmpty())
+		})
+		It("ignores unknown repository", func() {
+			body = `{"push_data":{"tag":"1.0.0"},"repository":{"repo_name":"bborbe/apple"}}`
+			post("/webhooks/dockerhub?secret=secret")
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(sender.SendCallCount()).To(Equal(0))
+		})
+		It("returns internal server error if send fails", func() {
+			sender.SendStub = nil
+			sender.SendReturns(0, errors.New("banana"))
+			post("/webhooks/dockerhub?secret=secret")
+			Expect(recorder.Code).To(Equal(http.StatusInternalServerError))
+			Expect(recorder.Body.String()).To(MatchJSON(`{"published":0,"results":[{"repository":"grafana/grafana","tag":"6.0.0","app":"Grafana","published":0,"error":"banana"}]}`))
+		})
+		It("publishes with context not cancelled by the request", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			post := httptest.NewRequest(http.MethodPost, "/webhooks/dockerhub?secret=secret", strings.NewReader(body))
+			handlers[version.WebhookDockerHub].ServeHTTP(recorder, post.WithContext(ctx))
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(published).To(HaveLen(1))
+		})
+		It("returns error of publish cancelled by shutdown", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			var err error
+			handlers, err = version.NewWebhookHandlers(ctx, config, sender)
+			Expect(err).NotTo(HaveOccurred())
+			sender.SendStub = nil
+			sender.SendReturns(0, nil)
+			post("/webhooks/dockerhub?secret=secret")
+			Expect(recorder.Code).To(Equal(http.StatusInternalServerError))
+			Expect(recorder.Body.String()).To(ContainSubstring("publish cancelled"))
+		})
+	})
+	Context("distribution", func() {
+		post := func(authorization string, body string) {
+			req := httptest.NewRequest(http.MethodPost, "/webhooks/distribution", strings.NewReader(body))
+			req.Header.Set("Authorization", authorization)
+			handlers[version.WebhookDistribution].ServeHTTP(recorder, req)
+		}
+		It("publishes tags of push events", func() {
+			post("Bearer secret", `{"events":[
+				{"action":"push","target":{"repository":"bborbe/collector","tag":"1.0.0"},"request":{"host":"registry.example.com"}},
+				{"action":"pull","target":{"repository":"bborbe/collector","tag":"0.9.0"},"request":{"host":"registry.example.com"}},
+				{"action":"push","target":{"repository":"bborbe/collector","digest":"sha256:1234"},"request":{"host":"registry.example.com"}}
+			]}`)
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(published).To(HaveLen(1))
+			Expect(published[0].App).To(Equal("Collector"))
+			Expect(published[0].Version).To(Equal("1.0.0"))
+		})
+		It("publishes remaining tags if a push fails", func() {
+			sender.SendStub = func(ctx context.Context, versions <-chan avro.ApplicationVersionAvailable) (int, error) {
+				var counter int
+				for v := range versions {
+					if v.Version == "1.0.0" {
+						return counter, errors.New("banana")
+					}
+					mux.Lock()
+					published = append(published, v)
+					mux.Unlock()
+					counter++
+				}
+				return counter, nil
+			}
+			post("Bearer secret", `{"events":[
+				{"action":"push","target":{"repository":"bborbe/collector","tag":"1.0.0"},"request":{"host":"registry.example.com"}},
+				{"action":"push","target":{"repository":"bborbe/collector","tag":"1.1.0"},"request":{"host":"registry.example.com"}}
+			]}`)
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(published).To(HaveLen(1))
+			Expect(published[0].Version).To(Equal("1.1.0"))
+			Expect(recorder.Body.String()).To(MatchJSON(`{"published":1,"results":[
+				{"repository":"bborbe/collector","tag":"1.0.0","app":"Collector","published":0,"error":"banana"},
+				{"repository":"bborbe/collector","tag":"1.1.0","app":"Collector","published":1}
+			]}`))
+		})
+		It("ignores push to other registry", func() {
+			post("Bearer secret", `{"events":[{"action":"push","target":{"repository":"bborbe/collector","tag":"1.0.0"},"request":{"host":"other.example.com"}}]}`)
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(published).To(BeEmpty())
+		})
+		It("returns unauthorized without bearer token", func() {
+			post("secret", `{"events":[]}`)
+			Expect(recorder.Code).To(Equal(http.StatusUnauthorized))
+		})
+	})
+	Context("harbor", func() {
+		It("publishes pushed tags", func() {
+			req := httptest.NewRequest(http.MethodPost, "/webhooks/harbor", strings.NewReader(`{
+				"type":"PUSH_ARTIFACT",
+				"event_data":{
+					"resources":[{"tag":"1.1.0","resource_url":"registry.example.com/bborbe/collector:1.1.0"}],
+					"repository":{"repo_full_name":"bborbe/collector"}
+				}
+			}`))
+			req.Header.Set("Authorization", "secret")
+			handlers[version.WebhookHarbor].ServeHTTP(recorder, req)
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(published).To(HaveLen(1))
+			Expect(published[0].App).To(Equal("Collector"))
+			Expect(published[0].Version).To(Equal("1.1.0"))
+		})
+	})
+	Context("github", func() {
+		post := func(event string, body string, secret string) {
+			mac := hmac.New(sha256.New, []byte(secret))
+			mac.Write([]byte(body))
+			req := httptest.NewRequest(http.MethodPost, "/webhooks/github", strings.NewReader(body))
+			req.Header.Set("X-GitHub-Event", event)
+			req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+			handlers[version.WebhookGitHub].ServeHTTP(recorder, req)
+		}
+		It("publishes tag of published release", func() {
+			post("release", `{"action":"published","release":{"tag_name":"v6.1.0"},"repository":{"full_name":"grafana/grafana"}}`, "secret")
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(published).To(HaveLen(1))
+			Expect(published[0].App).To(Equal("Grafana"))
+			Expect(published[0].Version).To(Equal("v6.1.0"))
+		})
+		It("publishes release with app of github source", func() {
+			config.Sources = append(config.Sources, version.SourceConfig{
+				Name:       "grafana-releases",
+				Type:       version.SourceTypeGitHub,
+				Repository: "grafana/grafana",
+				App:        "Grafana Release",
+			})
+			handlers, _ = version.NewWebhookHandlers(context.Background(), config, sender)
+			post("release", `{
+				"action":"published",
+				"release":{"tag_name":"v6.1.0-beta1","prerelease":true,"html_url":"https://github.com/grafana/grafana/releases/tag/v6.1.0-beta1","published_at":"2019-03-20T12:00:00Z"},
+				"repository":{"full_name":"grafana/grafana","html_url":"https://github.com/grafana/grafana"}
+			}`, "secret")
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(published).To(HaveLen(1))
+			Expect(published[0].App).To(Equal("Grafana Release"))
+			Expect(published[0].Stable).To(BeFalse())
+			Expect(published[0].Url).To(Equal("https://github.com/grafana/grafana/releases/tag/v6.1.0-beta1"))
+			Expect(published[0].PublishedAt).NotTo(BeZero())
+		})
+		It("ignores drafts", func() {
+			post("release", `{"action":"published","release":{"tag_name":"v6.1.0","draft":true},"repository":{"full_name":"grafana/grafana"}}`, "secret")
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(published).To(BeEmpty())
+		})
+		It("answers ping", func() {
+			post("ping", `{"zen":"Keep it logically awesome."}`, "secret")
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(sender.SendCallCount()).To(Equal(0))
+		})
+		It("returns unauthorized for invalid signature", func() {
+			post("release", `{"action":"published","release":{"tag_name":"v6.1.0"},"repository":{"full_name":"grafana/grafana"}}`, "banana")
+			Expect(recorder.Code).To(Equal(http.StatusUnauthorized))
+			Expect(sender.SendCallCount()).To(Equal(0))
+		})
+	})
+})